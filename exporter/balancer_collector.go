@@ -0,0 +1,145 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const balancerLockLocked = 2
+
+type balancerCollector struct {
+	ctx  context.Context
+	base *baseCollector
+}
+
+// newBalancerCollector creates a collector for the balancer state and chunk migrations of a sharded cluster.
+func newBalancerCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *balancerCollector {
+	return &balancerCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "balancer"})),
+	}
+}
+
+func (d *balancerCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *balancerCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *balancerCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "balancer")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// The config database is only meaningful for the whole cluster through mongos.
+	nodeType, err := getNodeType(d.ctx, client)
+	if err != nil {
+		logger.Errorf("cannot get node type: %s", err)
+		return
+	}
+	if nodeType != typeMongos {
+		return
+	}
+
+	if enabled, err := balancerStateEnabled(d.ctx, client); err != nil {
+		logger.Warnf("cannot get balancer settings: %s", err)
+	} else {
+		ch <- newBalancerMetric("enabled", "Whether the balancer is enabled in config.settings", boolToFloat(enabled))
+	}
+
+	if running, err := balancerStateRunning(d.ctx, client); err != nil {
+		logger.Warnf("cannot get balancer status: %s", err)
+	} else {
+		ch <- newBalancerMetric("running", "Whether the balancer is in a balancing round", boolToFloat(running))
+	}
+
+	if n, err := client.Database("config").Collection("migrations").CountDocuments(d.ctx, bson.M{}); err != nil {
+		logger.Warnf("cannot count in-flight migrations: %s", err)
+	} else {
+		ch <- newBalancerMetric("migrations_in_progress", "Number of chunk migrations in progress", float64(n))
+	}
+}
+
+func newBalancerMetric(name, help string, value float64) prometheus.Metric { //nolint:ireturn
+	d := prometheus.NewDesc("mongodb_sharding_balancer_"+name, help, nil, nil)
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, value)
+}
+
+// balancerStateEnabled reads the balancer document in config.settings. The document doesn't
+// exist until the balancer is stopped or started for the first time, so a missing document
+// means the balancer is enabled.
+func balancerStateEnabled(ctx context.Context, client *mongo.Client) (bool, error) {
+	var settings struct {
+		Stopped bool   `bson:"stopped"`
+		Mode    string `bson:"mode"`
+	}
+
+	err := client.Database("config").Collection("settings").FindOne(ctx, bson.M{"_id": "balancer"}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !settings.Stopped && settings.Mode != "off", nil
+}
+
+// balancerStateRunning uses balancerStatus and falls back to the balancer lock in config.locks
+// for servers not supporting the command.
+func balancerStateRunning(ctx context.Context, client *mongo.Client) (bool, error) {
+	var status struct {
+		InBalancerRound bool `bson:"inBalancerRound"`
+	}
+
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "balancerStatus", Value: 1}}).Decode(&status)
+	if err == nil {
+		return status.InBalancerRound, nil
+	}
+
+	var lock struct {
+		State int `bson:"state"`
+	}
+	lerr := client.Database("config").Collection("locks").FindOne(ctx, bson.M{"_id": "balancer"}).Decode(&lock)
+	if errors.Is(lerr, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if lerr != nil {
+		return false, errors.Wrapf(lerr, "balancerStatus failed (%s), cannot read config.locks", err)
+	}
+
+	return lock.State == balancerLockLocked, nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+var _ prometheus.Collector = (*balancerCollector)(nil)
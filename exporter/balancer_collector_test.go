@@ -0,0 +1,64 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestBalancerCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClientMongoS(ctx, t)
+	c := newBalancerCollector(ctx, client, logrus.New())
+
+	expected := strings.NewReader(`
+	# HELP mongodb_sharding_balancer_enabled Whether the balancer is enabled in config.settings
+	# TYPE mongodb_sharding_balancer_enabled gauge
+	mongodb_sharding_balancer_enabled 1
+	# HELP mongodb_sharding_balancer_migrations_in_progress Number of chunk migrations in progress
+	# TYPE mongodb_sharding_balancer_migrations_in_progress gauge
+	mongodb_sharding_balancer_migrations_in_progress 0` + "\n")
+
+	filter := []string{
+		"mongodb_sharding_balancer_enabled",
+		"mongodb_sharding_balancer_migrations_in_progress",
+	}
+	err := testutil.CollectAndCompare(c, expected, filter...)
+	assert.NoError(t, err)
+}
+
+func TestBalancerCollectorNotMongos(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+	c := newBalancerCollector(ctx, client, logrus.New())
+
+	// Only the collector scrape time, success and errors metrics are expected.
+	count := testutil.CollectAndCount(c)
+	assert.Equal(t, 3, count)
+}
@@ -18,6 +18,7 @@ package exporter
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -28,16 +29,83 @@ type baseCollector struct {
 	client *mongo.Client
 	logger *logrus.Entry
 
+	// name is the collector field of the logger, errors counts the errors it logged.
+	name   string
+	errors *errorCounter
+
 	lock         sync.Mutex
 	metricsCache []prometheus.Metric
 }
 
 // newBaseCollector creates a skeletal collector, which is used to create other collectors.
 func newBaseCollector(client *mongo.Client, logger *logrus.Entry) *baseCollector {
+	name, _ := logger.Data["collector"].(string)
+	errors := new(errorCounter)
+
 	return &baseCollector{
 		client: client,
-		logger: logger,
+		logger: withHook(logger, errors),
+		name:   name,
+		errors: errors,
+	}
+}
+
+// errorCounter is a logrus hook counting the logged errors.
+type errorCounter struct {
+	count int32
+}
+
+func (h *errorCounter) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (h *errorCounter) Fire(*logrus.Entry) error {
+	atomic.AddInt32(&h.count, 1)
+	return nil
+}
+
+// withHook returns an entry with the same fields logging to a copy of the entry logger,
+// with the hook added to the copy only. The hook gets the entries of its levels even if the
+// logger level doesn't log them, e.g. the errors are counted with the fatal level: the copy
+// only writes, and passes to the other hooks, the entries of the logger level.
+func withHook(entry *logrus.Entry, hook logrus.Hook) *logrus.Entry {
+	l := entry.Logger
+	level := l.GetLevel()
+	logger := &logrus.Logger{
+		Out:          l.Out,
+		Hooks:        make(logrus.LevelHooks),
+		Formatter:    &levelFormatter{Formatter: l.Formatter, level: level},
+		ReportCaller: l.ReportCaller,
+		Level:        level,
+		ExitFunc:     l.ExitFunc,
+	}
+	for lvl, hooks := range l.Hooks {
+		if l.IsLevelEnabled(lvl) {
+			logger.Hooks[lvl] = append([]logrus.Hook(nil), hooks...)
+		}
+	}
+	for _, lvl := range hook.Levels() {
+		if lvl > logger.Level {
+			logger.Level = lvl
+		}
+	}
+	logger.AddHook(hook)
+
+	return logger.WithFields(entry.Data)
+}
+
+// levelFormatter formats the entries up to level, the other entries are not written.
+type levelFormatter struct {
+	logrus.Formatter
+	level logrus.Level
+}
+
+func (f *levelFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > f.level {
+		return nil, nil
 	}
+
+	return f.Formatter.Format(entry)
 }
 
 func (d *baseCollector) Describe(ctx context.Context, ch chan<- *prometheus.Desc, collect func(mCh chan<- prometheus.Metric)) {
@@ -55,6 +123,10 @@ func (d *baseCollector) Describe(ctx context.Context, ch chan<- *prometheus.Desc
 
 	d.metricsCache = make([]prometheus.Metric, 0, defaultCacheSize)
 
+	// The scrape failed if the collector logged an error. They are only counted if the logger
+	// level is error or lower.
+	errorsBefore := atomic.LoadInt32(&d.errors.count)
+
 	// This is a copy/paste of prometheus.DescribeByCollect(d, ch) with the aggreated functionality
 	// to populate the metrics cache. Since on each scrape Prometheus will call Describe and inmediatelly
 	// after it will call Collect, it is safe to populate the cache here.
@@ -68,6 +140,16 @@ func (d *baseCollector) Describe(ctx context.Context, ch chan<- *prometheus.Desc
 		d.metricsCache = append(d.metricsCache, m) // populate the cache
 		ch <- m.Desc()
 	}
+
+	if d.name == "" {
+		return
+	}
+
+	failed := atomic.LoadInt32(&d.errors.count) > errorsBefore
+	for _, m := range collectorStatusFromContext(ctx).metrics(d.name, failed) {
+		d.metricsCache = append(d.metricsCache, m)
+		ch <- m.Desc()
+	}
 }
 
 func (d *baseCollector) Collect(ch chan<- prometheus.Metric) {
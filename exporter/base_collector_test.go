@@ -0,0 +1,181 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingCollector is a collector logging an error in every scrape while fail is set.
+type failingCollector struct {
+	ctx  context.Context
+	base *baseCollector
+	fail bool
+}
+
+func (d *failingCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *failingCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *failingCollector) collect(chan<- prometheus.Metric) {
+	if d.fail {
+		d.base.logger.Error("cannot run the command")
+	}
+}
+
+func TestCollectorStatusMetrics(t *testing.T) {
+	t.Parallel()
+
+	logger, hook := logrustest.NewNullLogger()
+	entry := logger.WithFields(logrus.Fields{"collector": "test_status"})
+	status := newCollectorStatus()
+	c := &failingCollector{ctx: withCollectorStatus(context.Background(), status), base: newBaseCollector(nil, entry)}
+
+	expected := func(success, errors string) *strings.Reader {
+		return strings.NewReader(`
+# HELP mongodb_collector_scrape_errors_total Number of scrapes of the collector that logged an error
+# TYPE mongodb_collector_scrape_errors_total counter
+mongodb_collector_scrape_errors_total{collector="test_status"} ` + errors + `
+# HELP mongodb_collector_success Whether the last scrape of the collector succeeded, without logging an error
+# TYPE mongodb_collector_success gauge
+mongodb_collector_success{collector="test_status"} ` + success + "\n")
+	}
+	filter := []string{"mongodb_collector_success", "mongodb_collector_scrape_errors_total"}
+
+	assert.NoError(t, testutil.CollectAndCompare(c, expected("1", "0"), filter...))
+
+	c.fail = true
+	assert.NoError(t, testutil.CollectAndCompare(c, expected("0", "1"), filter...))
+	assert.NoError(t, testutil.CollectAndCompare(c, expected("0", "2"), filter...))
+
+	c.fail = false
+	assert.NoError(t, testutil.CollectAndCompare(c, expected("1", "2"), filter...))
+
+	// The collectors are created for every scrape, the errors are counted by the status.
+	c = &failingCollector{ctx: withCollectorStatus(context.Background(), status), base: newBaseCollector(nil, entry)}
+	assert.NoError(t, testutil.CollectAndCompare(c, expected("1", "2"), filter...))
+
+	// Another exporter has its own counters.
+	c = &failingCollector{ctx: withCollectorStatus(context.Background(), newCollectorStatus()), base: newBaseCollector(nil, entry)}
+	assert.NoError(t, testutil.CollectAndCompare(c, expected("1", "0"), filter...))
+
+	// The errors still reach the hooks of the original logger.
+	assert.Len(t, hook.Entries, 2)
+}
+
+func TestCollectorStatusFatalLogLevel(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	logger, hook := logrustest.NewNullLogger()
+	logger.Out = &out
+	logger.SetLevel(logrus.FatalLevel)
+
+	c := &failingCollector{
+		ctx:  withCollectorStatus(context.Background(), newCollectorStatus()),
+		base: newBaseCollector(nil, logger.WithFields(logrus.Fields{"collector": "test_fatal"})),
+		fail: true,
+	}
+
+	// The errors are counted even if they are not logged.
+	expected := strings.NewReader(`
+# HELP mongodb_collector_success Whether the last scrape of the collector succeeded, without logging an error
+# TYPE mongodb_collector_success gauge
+mongodb_collector_success{collector="test_fatal"} 0` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(c, expected, "mongodb_collector_success"))
+	assert.Empty(t, out.String())
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestCollectorLastScrapeTimestamp(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := logrustest.NewNullLogger()
+	c := &failingCollector{
+		ctx:  withCollectorStatus(context.Background(), newCollectorStatus()),
+		base: newBaseCollector(nil, logger.WithFields(logrus.Fields{"collector": "test_last_scrape"})),
+		fail: true,
+	}
+
+	lastScrape := func() float64 {
+		t.Helper()
+
+		registry := prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(c))
+		families, err := registry.Gather()
+		require.NoError(t, err)
+
+		for _, family := range families {
+			if family.GetName() == "mongodb_collector_last_scrape_timestamp_seconds" {
+				return family.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+
+		return 0
+	}
+
+	// Not reported before the first successful scrape.
+	assert.Zero(t, lastScrape())
+
+	c.fail = false
+	first := lastScrape()
+	assert.InDelta(t, float64(time.Now().UnixNano())/1e9, first, 5)
+
+	time.Sleep(10 * time.Millisecond)
+	second := lastScrape()
+	assert.Greater(t, second, first)
+
+	// A failed scrape keeps the time of the last successful one.
+	c.fail = true
+	assert.Equal(t, second, lastScrape())
+
+	// The collectors are created for every scrape, the time is kept by the status.
+	c = &failingCollector{ctx: c.ctx, base: newBaseCollector(nil, logger.WithFields(logrus.Fields{"collector": "test_last_scrape"})), fail: true}
+	assert.Equal(t, second, lastScrape())
+
+	// Another exporter has its own.
+	c = &failingCollector{
+		ctx:  withCollectorStatus(context.Background(), newCollectorStatus()),
+		base: newBaseCollector(nil, logger.WithFields(logrus.Fields{"collector": "test_last_scrape"})),
+		fail: true,
+	}
+	assert.Zero(t, lastScrape())
+
+	// The failed scrapes are known even if the errors are not logged.
+	fatal, _ := logrustest.NewNullLogger()
+	fatal.SetLevel(logrus.FatalLevel)
+	c = &failingCollector{
+		ctx:  withCollectorStatus(context.Background(), newCollectorStatus()),
+		base: newBaseCollector(nil, fatal.WithFields(logrus.Fields{"collector": "test_last_scrape"})),
+		fail: true,
+	}
+	assert.Zero(t, lastScrape())
+}
@@ -0,0 +1,209 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
+	"github.com/percona/mongodb_exporter/internal/util"
+)
+
+// Values of the mongodb_cluster_health metric.
+const (
+	clusterHealthCritical = 0
+	clusterHealthDegraded = 1
+	clusterHealthHealthy  = 2
+)
+
+// clusterHealthThresholds are the replication lag limits used to grade the cluster health.
+type clusterHealthThresholds struct {
+	lagDegraded time.Duration
+	lagCritical time.Duration
+}
+
+type clusterHealthCollector struct {
+	ctx  context.Context
+	base *baseCollector
+
+	topologyInfo labelsGetter
+	cache        *scrapeCache
+	thresholds   clusterHealthThresholds
+	collectors   []string
+}
+
+// newClusterHealthCollector creates a collector for a summary of the replica set health. The
+// health is degraded if the last scrape of one of the collectors, named as in collect[], failed.
+func newClusterHealthCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger,
+	topology labelsGetter, cache *scrapeCache, thresholds clusterHealthThresholds, collectors []string,
+) *clusterHealthCollector {
+	return &clusterHealthCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "clusterhealth"})),
+
+		topologyInfo: topology,
+		cache:        cache,
+		thresholds:   thresholds,
+		collectors:   collectors,
+	}
+}
+
+func (d *clusterHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *clusterHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *clusterHealthCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "cluster_health")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	status, err := d.cache.replSetStatus(d.ctx)
+	if err != nil {
+		if e, ok := err.(mongo.CommandError); ok && util.IsReplicationNotEnabledError(e) { //nolint:errorlint
+			// There is no cluster to evaluate on standalone instances.
+			return
+		}
+		logger.Errorf("cannot get replSetGetStatus: %s", err)
+	}
+
+	var votes map[string]int32
+	if status != nil {
+		ctx, cancel := util.WithTimeout(d.ctx, util.DefaultCommandTimeout)
+		defer cancel()
+
+		if rs, err := util.ReplicasetConfig(ctx, client); err == nil {
+			votes = make(map[string]int32, len(rs.Config.Members))
+			for _, m := range rs.Config.Members {
+				votes[m.Host] = m.Votes
+			}
+		} else {
+			logger.Warnf("cannot get replSetGetConfig, assuming one vote per member: %s", err)
+		}
+	}
+
+	// The collectors which run after this one report their previous scrape.
+	collectorsFailed := collectorStatusFromContext(d.ctx).anyFailed(d.collectors)
+
+	desc := prometheus.NewDesc("mongodb_cluster_health",
+		"Replica set health summary: 0=critical, 1=degraded, 2=healthy",
+		nil, d.topologyInfo.baseLabels())
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue,
+		clusterHealth(status, votes, d.thresholds, collectorsFailed))
+
+	if status != nil {
+		ch <- voteDeficitMetric(status, votes, d.topologyInfo.baseLabels())
+	}
+}
+
+// voteDeficitMetric returns the votes missing to the healthy members to reach the majority. A
+// positive value means the majority is lost and no primary can be elected, a negative value is
+// the number of votes which can still be lost.
+func voteDeficitMetric(status *proto.ReplicaSetStatus, votes map[string]int32, labels map[string]string) prometheus.Metric {
+	majority, healthyVotes := replSetVotes(status, votes)
+
+	desc := prometheus.NewDesc("mongodb_rs_vote_deficit",
+		"Votes needed for a majority minus the votes of the healthy members, positive when the majority is lost",
+		nil, labels)
+
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, majority-healthyVotes)
+}
+
+// replSetVotes returns the votes needed for a majority and the votes of the healthy members.
+// votes maps member hosts to their votes. When nil, each member is assumed to have one vote.
+func replSetVotes(status *proto.ReplicaSetStatus, votes map[string]int32) (float64, float64) {
+	var totalVotes, healthyVotes float64
+
+	for _, m := range status.Members {
+		v := float64(1)
+		if votes != nil {
+			v = float64(votes[m.Name])
+		}
+		totalVotes += v
+
+		if m.Health == 1 {
+			healthyVotes += v
+		}
+	}
+
+	majority := status.MajorityVoteCount
+	if majority == 0 {
+		majority = float64(int(totalVotes)/2 + 1) //nolint:mnd
+	}
+
+	return majority, healthyVotes
+}
+
+// clusterHealth grades the replica set:
+//   - critical: replSetGetStatus could not be scraped, there is no primary, the healthy members
+//     don't have the majority of votes, or a member lags behind the critical threshold.
+//   - degraded: a member is unhealthy, lags behind the degraded threshold, or the last scrape of
+//     another collector failed.
+//   - healthy: none of the above.
+//
+// votes maps member hosts to their votes. When nil, each member is assumed to have one vote.
+func clusterHealth(status *proto.ReplicaSetStatus, votes map[string]int32, t clusterHealthThresholds, collectorsFailed bool) float64 {
+	if status == nil {
+		return clusterHealthCritical
+	}
+
+	hasPrimary := false
+	allHealthy := true
+
+	for _, m := range status.Members {
+		if m.Health != 1 {
+			allHealthy = false
+		}
+
+		if m.StateStr == "PRIMARY" {
+			hasPrimary = true
+		}
+	}
+
+	majority, healthyVotes := replSetVotes(status, votes)
+	if !hasPrimary || healthyVotes < majority {
+		return clusterHealthCritical
+	}
+
+	var maxLag time.Duration
+	for _, l := range replicationLags(*status) {
+		if l.lag > maxLag {
+			maxLag = l.lag
+		}
+	}
+
+	switch {
+	case t.lagCritical > 0 && maxLag >= t.lagCritical:
+		return clusterHealthCritical
+	case t.lagDegraded > 0 && maxLag >= t.lagDegraded:
+		return clusterHealthDegraded
+	case !allHealthy, collectorsFailed:
+		return clusterHealthDegraded
+	}
+
+	return clusterHealthHealthy
+}
+
+var _ prometheus.Collector = (*clusterHealthCollector)(nil)
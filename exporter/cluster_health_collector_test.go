@@ -0,0 +1,223 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
+)
+
+func TestClusterHealth(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	optime := func(lag time.Duration) primitive.DateTime {
+		return primitive.NewDateTimeFromTime(now.Add(-lag))
+	}
+	member := func(name, state string, health float64, lag time.Duration) proto.Members {
+		return proto.Members{Name: name, StateStr: state, Health: health, OptimeDate: optime(lag)}
+	}
+	thresholds := clusterHealthThresholds{lagDegraded: 10 * time.Second, lagCritical: time.Minute}
+
+	tests := []struct {
+		name   string
+		status *proto.ReplicaSetStatus
+		votes  map[string]int32
+		failed bool
+		want   float64
+	}{
+		{
+			name:   "scrape failed",
+			status: nil,
+			want:   clusterHealthCritical,
+		},
+		{
+			name: "healthy",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "PRIMARY", 1, 0),
+				member("m2", "SECONDARY", 1, time.Second),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			want: clusterHealthHealthy,
+		},
+		{
+			name: "no primary",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "SECONDARY", 1, 0),
+				member("m2", "SECONDARY", 1, 0),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			want: clusterHealthCritical,
+		},
+		{
+			name: "majority not satisfiable",
+			status: &proto.ReplicaSetStatus{MajorityVoteCount: 2, Members: []proto.Members{
+				member("m1", "PRIMARY", 1, 0),
+				member("m2", "(not reachable/healthy)", 0, 0),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			votes: map[string]int32{"m1": 1, "m2": 1, "m3": 0},
+			want:  clusterHealthCritical,
+		},
+		{
+			name: "one member down",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "PRIMARY", 1, 0),
+				member("m2", "(not reachable/healthy)", 0, 0),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			want: clusterHealthDegraded,
+		},
+		{
+			name: "lag above degraded threshold",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "PRIMARY", 1, 0),
+				member("m2", "SECONDARY", 1, 20*time.Second),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			want: clusterHealthDegraded,
+		},
+		{
+			name: "lag above critical threshold",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "PRIMARY", 1, 0),
+				member("m2", "SECONDARY", 1, 2*time.Minute),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			want: clusterHealthCritical,
+		},
+		{
+			name: "collector failed",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "PRIMARY", 1, 0),
+				member("m2", "SECONDARY", 1, 0),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			failed: true,
+			want:   clusterHealthDegraded,
+		},
+		{
+			name: "collector failed without primary",
+			status: &proto.ReplicaSetStatus{Members: []proto.Members{
+				member("m1", "SECONDARY", 1, 0),
+				member("m2", "SECONDARY", 1, 0),
+				member("m3", "SECONDARY", 1, 0),
+			}},
+			failed: true,
+			want:   clusterHealthCritical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, clusterHealth(tt.status, tt.votes, thresholds, tt.failed))
+		})
+	}
+}
+
+func TestVoteDeficitMetric(t *testing.T) {
+	t.Parallel()
+
+	member := func(name string, health float64) proto.Members {
+		return proto.Members{Name: name, Health: health}
+	}
+	labels := map[string]string{"rs_nm": "rs1"}
+
+	expected := func(deficit string) *strings.Reader {
+		return strings.NewReader(`
+# HELP mongodb_rs_vote_deficit Votes needed for a majority minus the votes of the healthy members, positive when the majority is lost
+# TYPE mongodb_rs_vote_deficit gauge
+mongodb_rs_vote_deficit{rs_nm="rs1"} ` + deficit + "\n")
+	}
+
+	// 3 voting members and a non voting one, one vote can still be lost.
+	votes := map[string]int32{"a:27017": 1, "b:27017": 1, "c:27017": 1, "d:27017": 0}
+	status := &proto.ReplicaSetStatus{
+		MajorityVoteCount: 2,
+		Members: []proto.Members{
+			member("a:27017", 1), member("b:27017", 1), member("c:27017", 1), member("d:27017", 0),
+		},
+	}
+	metric := voteDeficitMetric(status, votes, labels)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected("-1")))
+
+	// Two voting members are lost: the majority is lost even if the non voting one is back.
+	status.Members = []proto.Members{
+		member("a:27017", 1), member("b:27017", 0), member("c:27017", 0), member("d:27017", 1),
+	}
+	metric = voteDeficitMetric(status, votes, labels)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected("1")))
+
+	// Before MongoDB 4.4 the majority is computed from the votes, one per member without config.
+	status.MajorityVoteCount = 0
+	metric = voteDeficitMetric(status, nil, labels)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected("1")))
+}
+
+func TestClusterHealthCollectorFailures(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	logger, _ := logrustest.NewNullLogger()
+	now := primitive.NewDateTimeFromTime(time.Now())
+	cache := &scrapeCache{
+		run: func(context.Context, string) (bson.M, error) {
+			return bson.M{"set": "rs1", "members": bson.A{
+				bson.M{"name": "m1", "stateStr": "PRIMARY", "health": float64(1), "optimeDate": now},
+				bson.M{"name": "m2", "stateStr": "SECONDARY", "health": float64(1), "optimeDate": now},
+			}}, nil
+		},
+		results: make(map[string]*cachedCommand),
+	}
+
+	// The last scrape of diagnosticdata failed.
+	status := newCollectorStatus()
+	status.metrics("diagnosticdata", true)
+	ctx = withCollectorStatus(ctx, status)
+
+	health := func(value string) *strings.Reader {
+		return strings.NewReader(`
+# HELP mongodb_cluster_health Replica set health summary: 0=critical, 1=degraded, 2=healthy
+# TYPE mongodb_cluster_health gauge
+mongodb_cluster_health ` + value + "\n")
+	}
+
+	c := newClusterHealthCollector(ctx, client, logger, labelsGetterMock{}, cache, clusterHealthThresholds{}, []string{"diagnosticdata"})
+	assert.NoError(t, testutil.CollectAndCompare(c, health("1"), "mongodb_cluster_health"))
+
+	// The collectors which are not enabled are ignored.
+	c = newClusterHealthCollector(ctx, client, logger, labelsGetterMock{}, cache, clusterHealthThresholds{}, []string{"dbstats"})
+	assert.NoError(t, testutil.CollectAndCompare(c, health("2"), "mongodb_cluster_health"))
+}
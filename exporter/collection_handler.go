@@ -0,0 +1,95 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CollectionHandler returns an http.Handler serving the collstats and indexstats metrics of
+// the namespace in the ns query parameter, for ad hoc investigations of collections which are
+// not in the configured lists. Namespaces which don't exist are not found.
+func (e *Exporter) CollectionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := r.URL.Query().Get("ns")
+		database, collection := splitNamespace(ns)
+		if database == "" || collection == "" {
+			http.Error(w, fmt.Sprintf("invalid namespace %q, the ns parameter must be database.collection", ns), http.StatusBadRequest)
+			return
+		}
+
+		seconds, err := strconv.Atoi(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"))
+		if err != nil {
+			seconds = 10
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(seconds-e.opts.TimeoutOffset)*time.Second)
+		defer cancel()
+
+		client, err := e.getClient(ctx)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("cannot connect to MongoDB: %s", err), http.StatusServiceUnavailable)
+			return
+		}
+
+		if !e.opts.GlobalConnPool {
+			defer func() {
+				if err := client.Disconnect(ctx); err != nil {
+					e.logger.Errorf("Cannot disconnect client: %v", err)
+				}
+			}()
+		}
+
+		names, err := client.Database(database).ListCollectionNames(ctx, bson.D{{Key: "name", Value: collection}})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("cannot list the collections of %s: %s", database, err), http.StatusInternalServerError)
+			return
+		}
+		if len(names) == 0 {
+			http.Error(w, fmt.Sprintf("namespace %s not found", ns), http.StatusNotFound)
+			return
+		}
+
+		ti := newTopologyInfo(ctx, client, e.logger, newScrapeCache(client, e.opts.CommandRetries))
+
+		// The collectors scrape while they are registered, before the client is disconnected.
+		registry := prometheus.NewRegistry()
+		err = registerAll(prometheus.WrapRegistererWith(e.opts.ConstantLabels, registry),
+			newCollectionStatsCollector(ctx, client, e.collectorLogger("collstats"),
+				false, e.opts.IncludeViews, ti, []string{ns}, e.opts.CommandRetries, e.opts.CollStatsWiredTiger),
+			newIndexStatsCollector(ctx, client, e.collectorLogger("indexstats"),
+				false, e.opts.IncludeViews, e.opts.EnableOverrideDescendingIndex, ti, []string{ns}),
+		)
+		if err != nil {
+			e.logger.Errorf("Cannot register the collection collectors: %s", err)
+		}
+
+		h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorHandling:     promhttp.ContinueOnError,
+			ErrorLog:          e.logger,
+			EnableOpenMetrics: e.opts.EnableOpenMetrics,
+		})
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,96 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestCollectionHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	database := client.Database("testdb_collection_handler")
+	database.Drop(ctx) //nolint
+
+	defer func() {
+		err := database.Drop(ctx)
+		assert.NoError(t, err)
+	}()
+
+	_, err := database.Collection("orders").InsertOne(ctx, bson.M{"f1": 1})
+	require.NoError(t, err)
+
+	e, err := New(&Opts{
+		URI:    fmt.Sprintf("mongodb://127.0.0.1:%s/admin", tu.GetenvDefault("TEST_MONGODB_STANDALONE_PORT", "27017")),
+		Logger: logrus.New(),
+	})
+	require.NoError(t, err)
+
+	// The namespace doesn't have to be in the configured collections.
+	rr := httptest.NewRecorder()
+	e.CollectionHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/collection?ns=testdb_collection_handler.orders", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `mongodb_collstats_storageStats_count{collection="orders",database="testdb_collection_handler"`)
+	assert.Contains(t, string(body), `mongodb_indexstats_accesses_ops{collection="orders",database="testdb_collection_handler"`)
+
+	rr = httptest.NewRecorder()
+	e.CollectionHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/collection?ns=testdb_collection_handler.missing", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestCollectionHandlerErrors(t *testing.T) {
+	t.Parallel()
+
+	e := &Exporter{
+		logger: logrus.New(),
+		opts:   &Opts{Logger: logrus.New()},
+		connectFn: func(context.Context, *Opts, *event.PoolMonitor) (*mongo.Client, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	for _, ns := range []string{"", "shop", ".orders"} {
+		rr := httptest.NewRecorder()
+		e.CollectionHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/collection?ns="+ns, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, ns)
+	}
+
+	rr := httptest.NewRecorder()
+	e.CollectionHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/collection?ns=shop.orders", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
@@ -0,0 +1,80 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// collectorQueue holds the collectors of a scrape until they are registered, which runs them,
+// so they run in the order of the priority list.
+type collectorQueue struct {
+	priority []string
+	entries  []queuedCollectors
+}
+
+type queuedCollectors struct {
+	name       string
+	collectors []prometheus.Collector
+}
+
+func newCollectorQueue(priority []string) *collectorQueue {
+	return &collectorQueue{priority: priority}
+}
+
+// add queues the collectors, named as in collect[].
+func (q *collectorQueue) add(name string, collectors ...prometheus.Collector) {
+	q.entries = append(q.entries, queuedCollectors{name: name, collectors: collectors})
+}
+
+// names returns the names of the queued collectors.
+func (q *collectorQueue) names() []string {
+	names := make([]string, 0, len(q.entries))
+	for _, e := range q.entries {
+		names = append(names, e.name)
+	}
+
+	return names
+}
+
+// register registers the queued collectors through the sampler, one after the other: first the
+// ones in the priority list, in its order, then the others in the order they were added.
+// Collectors registered after the scrape deadline don't collect, so the ones listed first are
+// the last to be cut off.
+func (q *collectorQueue) register(registry prometheus.Registerer, sampler *collectorSampler, logger *logrus.Logger) {
+	rank := func(name string) int {
+		for i, n := range q.priority {
+			if n == name {
+				return i
+			}
+		}
+
+		return len(q.priority)
+	}
+
+	sort.SliceStable(q.entries, func(i, j int) bool {
+		return rank(q.entries[i].name) < rank(q.entries[j].name)
+	})
+
+	for _, e := range q.entries {
+		if err := sampler.register(registry, e.name, e.collectors...); err != nil {
+			logger.Errorf("Cannot register the %s collector: %s", e.name, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// slowCollector is a collector taking delay to run, which is interrupted by the scrape deadline.
+type slowCollector struct {
+	ctx   context.Context
+	base  *baseCollector
+	name  string
+	delay time.Duration
+}
+
+func (d *slowCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *slowCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *slowCollector) collect(ch chan<- prometheus.Metric) {
+	select {
+	case <-time.After(d.delay):
+		desc := prometheus.NewDesc("test_"+d.name+"_collected", "The collector ran", nil, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)
+	case <-d.ctx.Done():
+	}
+}
+
+func TestCollectorQueuePriority(t *testing.T) {
+	t.Parallel()
+
+	// The collectors skip collecting after the deadline only with a client.
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	collected := func(priority []string) []string {
+		// Each collector takes 100ms, the deadline cuts off the second one.
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		defer cancel()
+
+		q := newCollectorQueue(priority)
+		for _, name := range []string{"collstats", "indexstats", "replicasetstatus"} {
+			q.add(name, &slowCollector{
+				ctx:   ctx,
+				base:  newBaseCollector(client, logger.WithField("collector", name)),
+				name:  name,
+				delay: 100 * time.Millisecond,
+			})
+		}
+
+		registry := prometheus.NewRegistry()
+		q.register(registry, nil, logger)
+
+		mfs, err := registry.Gather()
+		require.NoError(t, err)
+
+		var names []string
+		for _, mf := range mfs {
+			if strings.HasPrefix(mf.GetName(), "test_") {
+				names = append(names, mf.GetName())
+			}
+		}
+
+		return names
+	}
+
+	assert.Equal(t, []string{"test_collstats_collected"}, collected(nil))
+	assert.Equal(t, []string{"test_replicasetstatus_collected"}, collected([]string{"replicasetstatus"}))
+}
+
+func TestCollectorPriorityValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(&Opts{URI: "mongodb://127.0.0.1:12345", CollectorPriority: []string{"nosuchcollector"}})
+	assert.Error(t, err)
+
+	_, err = New(&Opts{URI: "mongodb://127.0.0.1:12345", CollectorPriority: []string{"replicasetstatus", "diagnosticdata"}})
+	assert.NoError(t, err)
+}
+
+func TestCollectorQueueLabelConflicts(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"database", "collection", "name", "namespace", "shard", "key_name"} {
+		logger, hook := logrustest.NewNullLogger()
+
+		conflicting := metricsCollector{prometheus.MustNewConstMetric(
+			prometheus.NewDesc("test_conflicting", "Metric with the label", nil, prometheus.Labels{name: "x"}),
+			prometheus.GaugeValue, 1)}
+		other := metricsCollector{prometheus.MustNewConstMetric(
+			prometheus.NewDesc("test_other", "Metric without the label", []string{"op"}, nil),
+			prometheus.GaugeValue, 1, "insert")}
+
+		q := newCollectorQueue(nil)
+		q.add("collstats", conflicting)
+		q.add("dbstats", other)
+
+		// The conflicting collector is skipped instead of making the scrape panic.
+		registry := prometheus.NewRegistry()
+		q.register(prometheus.WrapRegistererWith(prometheus.Labels{name: "prod"}, registry), nil, logger)
+
+		mfs, err := registry.Gather()
+		require.NoError(t, err, name)
+		require.Len(t, mfs, 1, name)
+		assert.Equal(t, "test_other", mfs[0].GetName(), name)
+
+		require.Len(t, hook.AllEntries(), 1, name)
+		assert.Contains(t, hook.LastEntry().Message, "Cannot register the collstats collector", name)
+	}
+}
@@ -0,0 +1,153 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collectorSampler runs the collectors with a sample rate only every N scrapes and serves the
+// metrics of their last run in between, to reduce the load of the expensive collectors.
+type collectorSampler struct {
+	rates map[string]int
+
+	lock    sync.Mutex
+	scrapes map[string]int
+	cache   map[string]cachedMetrics
+	running map[string]*sync.Mutex
+}
+
+func newCollectorSampler(rates map[string]int) *collectorSampler {
+	return &collectorSampler{
+		rates:   rates,
+		scrapes: make(map[string]int),
+		cache:   make(map[string]cachedMetrics),
+		running: make(map[string]*sync.Mutex),
+	}
+}
+
+// register registers the collectors, named as in collect[], if it is their turn to run.
+// Otherwise the metrics of their last run are registered instead. A nil sampler always
+// registers the collectors. The failed runs are not cached, the collectors run again on the
+// next scrape.
+func (s *collectorSampler) register(registry prometheus.Registerer, name string, collectors ...prometheus.Collector) error {
+	if s == nil || s.rates[name] <= 1 {
+		return registerAll(registry, collectors...)
+	}
+
+	// Concurrent scrapes wait for the running one, so the collectors don't run twice.
+	s.lock.Lock()
+	running, ok := s.running[name]
+	if !ok {
+		running = new(sync.Mutex)
+		s.running[name] = running
+	}
+	s.lock.Unlock()
+
+	running.Lock()
+	defer running.Unlock()
+
+	s.lock.Lock()
+	scrape := s.scrapes[name]
+	s.scrapes[name]++
+	cached, ok := s.cache[name]
+	s.lock.Unlock()
+
+	if ok && scrape%s.rates[name] != 0 {
+		return registry.Register(cached)
+	}
+
+	// The collectors run when they are registered, and then only replay their metrics.
+	if err := registerAll(registry, collectors...); err != nil {
+		return err
+	}
+	metrics := collectNow(collectors...)
+
+	s.lock.Lock()
+	if failedRun(metrics) {
+		delete(s.cache, name)
+	} else {
+		s.cache[name] = metrics
+	}
+	s.lock.Unlock()
+
+	return nil
+}
+
+// registerAll registers the collectors and returns the first error. A collector which cannot
+// be registered, e.g. because a constant label has the name of one of its labels, doesn't
+// prevent the others from being registered.
+func registerAll(registry prometheus.Registerer, collectors ...prometheus.Collector) error {
+	var err error
+	for _, c := range collectors {
+		if rerr := registry.Register(c); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+
+	return err
+}
+
+// collectNow collects the metrics of the collectors.
+func collectNow(collectors ...prometheus.Collector) cachedMetrics {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		for _, c := range collectors {
+			c.Collect(ch)
+		}
+		close(ch)
+	}()
+
+	metrics := cachedMetrics{}
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
+
+// failedRun returns true if a collector reported a failed scrape in mongodb_collector_success or
+// sent an invalid metric.
+func failedRun(metrics cachedMetrics) bool {
+	for _, m := range metrics {
+		if s, ok := m.(collectorSuccess); ok && s.failed {
+			return true
+		}
+		if err := m.Write(&dto.Metric{}); err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cachedMetrics is a collector of the metrics of a previous collector run.
+type cachedMetrics []prometheus.Metric
+
+func (c cachedMetrics) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c cachedMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
+
+var _ prometheus.Collector = cachedMetrics(nil)
@@ -0,0 +1,135 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runsCollector runs when it is described, like the collectors using baseCollector, and
+// reports the number of runs. If fail is set, it also reports whether the run failed in
+// mongodb_collector_success.
+type runsCollector struct {
+	runs *int
+	fail *bool
+}
+
+func (c runsCollector) successDesc() *prometheus.Desc {
+	return prometheus.NewDesc("mongodb_collector_success", "Whether the last scrape succeeded", nil, nil)
+}
+
+func (c runsCollector) desc() *prometheus.Desc {
+	return prometheus.NewDesc("test_collector_runs", "Number of runs of the collector", nil, nil)
+}
+
+func (c runsCollector) Describe(ch chan<- *prometheus.Desc) {
+	*c.runs++
+	ch <- c.desc()
+	if c.fail != nil {
+		ch <- c.successDesc()
+	}
+}
+
+func (c runsCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.desc(), prometheus.GaugeValue, float64(*c.runs))
+	if c.fail != nil {
+		ch <- collectorSuccess{
+			Metric: prometheus.MustNewConstMetric(c.successDesc(), prometheus.GaugeValue, 0),
+			failed: *c.fail,
+		}
+	}
+}
+
+func TestCollectorSampler(t *testing.T) {
+	t.Parallel()
+
+	s := newCollectorSampler(map[string]int{"collstats": 3})
+
+	var sampled, unsampled int
+	var values []float64
+	for i := 0; i < 7; i++ {
+		registry := prometheus.NewRegistry()
+		s.register(registry, "collstats", runsCollector{runs: &sampled})
+		mfs, err := registry.Gather()
+		require.NoError(t, err)
+		require.Len(t, mfs, 1)
+		values = append(values, mfs[0].GetMetric()[0].GetGauge().GetValue())
+
+		s.register(prometheus.NewRegistry(), "dbstats", runsCollector{runs: &unsampled})
+	}
+
+	// The collector runs on the first scrape and then every 3 scrapes, the last metrics are
+	// served in between.
+	assert.Equal(t, 3, sampled)
+	assert.Equal(t, []float64{1, 1, 1, 2, 2, 2, 3}, values)
+	assert.Equal(t, 7, unsampled)
+
+	// Without sampler the collectors always run.
+	var runs int
+	(*collectorSampler)(nil).register(prometheus.NewRegistry(), "collstats", runsCollector{runs: &runs})
+	(*collectorSampler)(nil).register(prometheus.NewRegistry(), "collstats", runsCollector{runs: &runs})
+	assert.Equal(t, 2, runs)
+}
+
+func TestCollectorSamplerFailedRuns(t *testing.T) {
+	t.Parallel()
+
+	s := newCollectorSampler(map[string]int{"collstats": 3})
+
+	// The failed first run is not served again, the collector runs on the next scrape.
+	var runs int
+	fail := true
+	for i := 0; i < 4; i++ {
+		require.NoError(t, s.register(prometheus.NewRegistry(), "collstats", runsCollector{runs: &runs, fail: &fail}))
+		fail = false
+	}
+	assert.Equal(t, 3, runs)
+}
+
+func TestCollectorSamplerConcurrentScrapes(t *testing.T) {
+	t.Parallel()
+
+	s := newCollectorSampler(map[string]int{"collstats": 3})
+
+	var runs int
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.register(prometheus.NewRegistry(), "collstats", runsCollector{runs: &runs}))
+		}()
+	}
+	wg.Wait()
+
+	// The second scrape serves the metrics of the first one.
+	assert.Equal(t, 1, runs)
+}
+
+func TestCollectorSampleRatesValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(&Opts{CollectorSampleRates: map[string]int{"nosuchcollector": 2}})
+	assert.Error(t, err)
+
+	_, err = New(&Opts{CollectorSampleRates: map[string]int{"collstats": 0}})
+	assert.Error(t, err)
+}
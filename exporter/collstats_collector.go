@@ -19,6 +19,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -31,22 +32,31 @@ type collstatsCollector struct {
 
 	compatibleMode  bool
 	discoveringMode bool
+	includeViews    bool
 	topologyInfo    labelsGetter
 
-	collections []string
+	collections    []string
+	commandRetries int
+
+	// Export the typed WiredTiger cache and block manager metrics of the collections listed in
+	// collections, not of the discovered ones.
+	wiredTiger bool
 }
 
 // newCollectionStatsCollector creates a collector for statistics about collections.
-func newCollectionStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, discovery bool, topology labelsGetter, collections []string) *collstatsCollector {
+func newCollectionStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, discovery, includeViews bool, topology labelsGetter, collections []string, commandRetries int, wiredTiger bool) *collstatsCollector {
 	return &collstatsCollector{
 		ctx:  ctx,
 		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "collstats"})),
 
 		compatibleMode:  false, // there are no compatible metrics for this collector.
 		discoveringMode: discovery,
+		includeViews:    includeViews,
 		topologyInfo:    topology,
 
-		collections: collections,
+		collections:    collections,
+		commandRetries: commandRetries,
+		wiredTiger:     wiredTiger,
 	}
 }
 
@@ -74,6 +84,18 @@ func (d *collstatsCollector) collect(ch chan<- prometheus.Metric) {
 		}
 
 		collections = fromMapToSlice(onlyCollectionsNamespaces)
+	} else if d.includeViews {
+		var views []string
+		var err error
+		collections, views, err = splitViews(d.ctx, client, d.collections)
+		if err != nil {
+			logger.Errorf("cannot list views: %s", err.Error())
+			return
+		}
+
+		for _, metric := range collectionIsViewMetrics(collections, views, d.topologyInfo.baseLabels()) {
+			ch <- metric
+		}
 	} else {
 		var err error
 		collections, err = checkNamespacesForViews(d.ctx, client, d.collections)
@@ -83,6 +105,19 @@ func (d *collstatsCollector) collect(ch chan<- prometheus.Metric) {
 		}
 	}
 
+	// Free storage of the collections by database.
+	freeStorage := make(map[string]float64)
+
+	// The typed WiredTiger metrics are only exported for the namespaces explicitly requested.
+	// The raw storageStats.wiredTiger values are exported by makeMetrics for all of them.
+	requested := make(map[string]bool, len(d.collections))
+	for _, ns := range d.collections {
+		requested[ns] = true
+	}
+
+	// Reported once, whatever the number of collections failing for missing privileges.
+	var unauthorized prometheus.Metric
+
 	for _, dbCollection := range collections {
 		parts := strings.Split(dbCollection, ".")
 		if len(parts) < 2 { //nolint:gomnd
@@ -110,15 +145,19 @@ func (d *collstatsCollector) collect(ch chan<- prometheus.Metric) {
 
 		pipeline := mongo.Pipeline{aggregation}
 
-		cursor, err := client.Database(database).Collection(collection).Aggregate(d.ctx, pipeline)
-		if err != nil {
-			logger.Errorf("cannot get $collstats cursor for collection %s.%s: %s", database, collection, err)
-
-			continue
-		}
-
 		var stats []bson.M
-		if err = cursor.All(d.ctx, &stats); err != nil {
+		err := withRetries(d.ctx, d.commandRetries, func() error {
+			cursor, err := client.Database(database).Collection(collection).Aggregate(d.ctx, pipeline)
+			if err != nil {
+				return errors.Wrap(err, "cannot get $collstats cursor")
+			}
+
+			return cursor.All(d.ctx, &stats)
+		})
+		if err != nil {
+			if m := unauthorizedMetric(err, d.base.name, "$collStats"); m != nil {
+				unauthorized = m
+			}
 			logger.Errorf("cannot get $collstats for collection %s.%s: %s", database, collection, err)
 
 			continue
@@ -140,8 +179,220 @@ func (d *collstatsCollector) collect(ch chan<- prometheus.Metric) {
 			for _, metric := range makeMetrics(prefix, metrics, labels, d.compatibleMode) {
 				ch <- metric
 			}
+
+			for _, metric := range collStatsLatencyMetrics(metrics, labels) {
+				ch <- metric
+			}
+
+			for _, metric := range collStatsIndexSizeMetrics(metrics, labels) {
+				ch <- metric
+			}
+
+			if d.wiredTiger && requested[dbCollection] {
+				for _, metric := range collStatsWiredTigerMetrics(metrics, labels) {
+					ch <- metric
+				}
+			}
+
+			addFreeStorage(freeStorage, database, metrics)
 		}
 	}
+
+	if unauthorized != nil {
+		ch <- unauthorized
+	}
+
+	for _, metric := range databaseReclaimableMetrics(freeStorage, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// addFreeStorage adds the free storage of the collection in a $collStats result to the one of
+// its database. freeStorageSize is only reported by WiredTiger, since MongoDB 4.4.
+func addFreeStorage(freeStorage map[string]float64, database string, stats bson.M) {
+	if v, err := asFloat64(walkTo(stats, []string{"storageStats", "freeStorageSize"})); err == nil && v != nil {
+		freeStorage[database] += *v
+	}
+}
+
+// databaseReclaimableMetrics returns the free storage of the collections of each database, the
+// space compact could give back, to find the databases that benefit the most from it. Only the
+// collections whose stats are collected are counted.
+func databaseReclaimableMetrics(freeStorage map[string]float64, labels map[string]string) []prometheus.Metric {
+	d := prometheus.NewDesc("mongodb_database_reclaimable_bytes",
+		"Free storage of the collected collections of the database, which compact can reclaim, in bytes",
+		[]string{"database"}, labels)
+
+	metrics := make([]prometheus.Metric, 0, len(freeStorage))
+	for database, free := range freeStorage {
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, free, database))
+	}
+
+	return metrics
+}
+
+// collStatsLatencyMetrics returns the number of operations and their cumulative latency per
+// type from the latencyStats of a $collStats result, to compute the average latency of each
+// collection. They are also exposed as the mongodb_collstats_latencyStats_* counters.
+func collStatsLatencyMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	latencyStats, ok := stats["latencyStats"].(bson.M)
+	if !ok {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+
+	// transactions is only reported since MongoDB 4.4.
+	for _, op := range []string{"reads", "writes", "commands", "transactions"} {
+		opStats, ok := latencyStats[op].(bson.M)
+		if !ok {
+			continue
+		}
+
+		if v, err := asFloat64(opStats["ops"]); err == nil && v != nil {
+			d := prometheus.NewDesc("mongodb_collstats_latency_"+op+"_ops_total",
+				"Number of "+op+" operations on the collection", nil, labels)
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *v))
+		}
+
+		if v, err := asFloat64(opStats["latency"]); err == nil && v != nil {
+			d := prometheus.NewDesc("mongodb_collstats_latency_"+op+"_micros_total",
+				"Cumulative latency of the "+op+" operations on the collection, in microseconds", nil, labels)
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *v))
+		}
+	}
+
+	return metrics
+}
+
+// collStatsIndexSizeMetrics returns the size of each index of the collection from the
+// storageStats of a $collStats result, to find the bloated indexes. They are also exposed
+// untyped as mongodb_collstats_storageStats_indexSizes.
+func collStatsIndexSizeMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	indexSizes, ok := walkTo(stats, []string{"storageStats", "indexSizes"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_collstats_index_size_bytes", "Size of the index, in bytes", []string{"index"}, labels)
+
+	metrics := make([]prometheus.Metric, 0, len(indexSizes))
+	for index, size := range indexSizes {
+		if v, err := asFloat64(size); err == nil && v != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *v, index))
+		}
+	}
+
+	return metrics
+}
+
+// collStatsWiredTigerMetrics returns the WiredTiger cache usage and the bytes read and written
+// by the block manager from the storageStats of a $collStats result, to find the collections
+// putting pressure on the cache. The pages evicted are the modified and unmodified ones.
+func collStatsWiredTigerMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	wt, ok := walkTo(stats, []string{"storageStats", "wiredTiger"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	values := []struct {
+		name      string
+		help      string
+		keys      [][]string
+		valueType prometheus.ValueType
+	}{
+		{
+			name:      "mongodb_collstats_wiredtiger_cache_bytes",
+			help:      "Size of the data of the collection currently in the WiredTiger cache, in bytes",
+			keys:      [][]string{{"cache", "bytes currently in the cache"}},
+			valueType: prometheus.GaugeValue,
+		},
+		{
+			name:      "mongodb_collstats_wiredtiger_cache_read_bytes_total",
+			help:      "Bytes of the collection read into the WiredTiger cache",
+			keys:      [][]string{{"cache", "bytes read into cache"}},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_collstats_wiredtiger_cache_written_bytes_total",
+			help:      "Bytes of the collection written from the WiredTiger cache",
+			keys:      [][]string{{"cache", "bytes written from cache"}},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name: "mongodb_collstats_wiredtiger_cache_pages_evicted_total",
+			help: "Pages of the collection evicted from the WiredTiger cache",
+			keys: [][]string{
+				{"cache", "modified pages evicted"},
+				{"cache", "unmodified pages evicted"},
+			},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_collstats_wiredtiger_block_read_bytes_total",
+			help:      "Bytes of the collection read from disk by the WiredTiger block manager",
+			keys:      [][]string{{"block-manager", "bytes read"}},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_collstats_wiredtiger_block_written_bytes_total",
+			help:      "Bytes of the collection written to disk by the WiredTiger block manager",
+			keys:      [][]string{{"block-manager", "bytes written"}},
+			valueType: prometheus.CounterValue,
+		},
+	}
+
+	metrics := make([]prometheus.Metric, 0, len(values))
+	for _, v := range values {
+		var sum float64
+		found := false
+		for _, key := range v.keys {
+			f, err := asFloat64(walkTo(wt, key))
+			if err != nil || f == nil {
+				continue
+			}
+			sum += *f
+			found = true
+		}
+		if !found {
+			continue
+		}
+
+		d := prometheus.NewDesc(v.name, v.help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, v.valueType, sum))
+	}
+
+	return metrics
+}
+
+// collectionIsViewMetrics returns whether each of the requested namespaces is a view. Views
+// have no stats, they are skipped.
+func collectionIsViewMetrics(collections, views []string, labels map[string]string) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, len(collections)+len(views))
+
+	add := func(namespace string, isView float64) {
+		database, collection := splitNamespace(namespace)
+
+		l := make(map[string]string, len(labels)+2)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["database"] = database
+		l["collection"] = collection
+
+		d := prometheus.NewDesc("mongodb_collection_is_view",
+			"Whether the requested namespace is a view, whose stats cannot be collected.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, isView))
+	}
+
+	for _, namespace := range collections {
+		add(namespace, 0)
+	}
+	for _, namespace := range views {
+		add(namespace, 1)
+	}
+
+	return metrics
 }
 
 var _ prometheus.Collector = (*collstatsCollector)(nil)
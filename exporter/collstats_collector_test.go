@@ -54,17 +54,17 @@ func TestCollStatsCollector(t *testing.T) {
 
 	collection := []string{"testdb.testcol_00", "testdb.testcol_01", "testdb.testcol_02"}
 	logger := logrus.New()
-	c := newCollectionStatsCollector(ctx, client, logger, false, ti, collection)
+	c := newCollectionStatsCollector(ctx, client, logger, false, false, ti, collection, 0, false)
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
 # HELP mongodb_collstats_latencyStats_commands_latency collstats.latencyStats.commands.latency
-# TYPE mongodb_collstats_latencyStats_commands_latency untyped
+# TYPE mongodb_collstats_latencyStats_commands_latency counter
 mongodb_collstats_latencyStats_commands_latency{collection="testcol_00",database="testdb"} 0
 mongodb_collstats_latencyStats_commands_latency{collection="testcol_01",database="testdb"} 0
 mongodb_collstats_latencyStats_commands_latency{collection="testcol_02",database="testdb"} 0
 # HELP mongodb_collstats_latencyStats_transactions_ops collstats.latencyStats.transactions.ops
-# TYPE mongodb_collstats_latencyStats_transactions_ops untyped
+# TYPE mongodb_collstats_latencyStats_transactions_ops counter
 mongodb_collstats_latencyStats_transactions_ops{collection="testcol_00",database="testdb"} 0
 mongodb_collstats_latencyStats_transactions_ops{collection="testcol_01",database="testdb"} 0
 mongodb_collstats_latencyStats_transactions_ops{collection="testcol_02",database="testdb"} 0
@@ -93,3 +93,164 @@ mongodb_collstats_storageStats_capped{collection="testcol_02",database="testdb"}
 	err := testutil.CollectAndCompare(c, expected, filter...)
 	assert.NoError(t, err)
 }
+
+func TestCollStatsLatencyMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := bson.M{
+		"ns":   "testdb.testcol",
+		"host": "127.0.0.1:17001",
+		"latencyStats": bson.M{
+			"reads":        bson.M{"latency": int64(2400), "ops": int64(12), "queryableEncryptionLatencyMicros": int64(0)},
+			"writes":       bson.M{"latency": int64(9000), "ops": int64(30)},
+			"commands":     bson.M{"latency": int64(0), "ops": int64(0)},
+			"transactions": bson.M{"latency": int64(150), "ops": int64(1)},
+		},
+		"storageStats": bson.M{"size": int32(4096), "count": int32(30)},
+	}
+
+	expected := strings.NewReader(`
+# HELP mongodb_collstats_latency_commands_micros_total Cumulative latency of the commands operations on the collection, in microseconds
+# TYPE mongodb_collstats_latency_commands_micros_total counter
+mongodb_collstats_latency_commands_micros_total{collection="testcol",database="testdb"} 0
+# HELP mongodb_collstats_latency_commands_ops_total Number of commands operations on the collection
+# TYPE mongodb_collstats_latency_commands_ops_total counter
+mongodb_collstats_latency_commands_ops_total{collection="testcol",database="testdb"} 0
+# HELP mongodb_collstats_latency_reads_micros_total Cumulative latency of the reads operations on the collection, in microseconds
+# TYPE mongodb_collstats_latency_reads_micros_total counter
+mongodb_collstats_latency_reads_micros_total{collection="testcol",database="testdb"} 2400
+# HELP mongodb_collstats_latency_reads_ops_total Number of reads operations on the collection
+# TYPE mongodb_collstats_latency_reads_ops_total counter
+mongodb_collstats_latency_reads_ops_total{collection="testcol",database="testdb"} 12
+# HELP mongodb_collstats_latency_transactions_micros_total Cumulative latency of the transactions operations on the collection, in microseconds
+# TYPE mongodb_collstats_latency_transactions_micros_total counter
+mongodb_collstats_latency_transactions_micros_total{collection="testcol",database="testdb"} 150
+# HELP mongodb_collstats_latency_transactions_ops_total Number of transactions operations on the collection
+# TYPE mongodb_collstats_latency_transactions_ops_total counter
+mongodb_collstats_latency_transactions_ops_total{collection="testcol",database="testdb"} 1
+# HELP mongodb_collstats_latency_writes_micros_total Cumulative latency of the writes operations on the collection, in microseconds
+# TYPE mongodb_collstats_latency_writes_micros_total counter
+mongodb_collstats_latency_writes_micros_total{collection="testcol",database="testdb"} 9000
+# HELP mongodb_collstats_latency_writes_ops_total Number of writes operations on the collection
+# TYPE mongodb_collstats_latency_writes_ops_total counter
+mongodb_collstats_latency_writes_ops_total{collection="testcol",database="testdb"} 30` + "\n")
+
+	metrics := collStatsLatencyMetrics(stats, map[string]string{"database": "testdb", "collection": "testcol"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	assert.Empty(t, collStatsLatencyMetrics(bson.M{"storageStats": bson.M{"size": int32(4096)}}, nil))
+}
+
+func TestCollStatsIndexSizeMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := bson.M{
+		"storageStats": bson.M{
+			"size":           int64(81920),
+			"totalIndexSize": int64(57344),
+			"indexSizes": bson.M{
+				"_id_":            int32(16384),
+				"customer_1_ts_1": int64(40960),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collstats_index_size_bytes Size of the index, in bytes
+	# TYPE mongodb_collstats_index_size_bytes gauge
+	mongodb_collstats_index_size_bytes{collection="orders",database="shop",index="_id_"} 16384
+	mongodb_collstats_index_size_bytes{collection="orders",database="shop",index="customer_1_ts_1"} 40960` + "\n")
+
+	metrics := collStatsIndexSizeMetrics(stats, map[string]string{"database": "shop", "collection": "orders"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	assert.Empty(t, collStatsIndexSizeMetrics(bson.M{"latencyStats": bson.M{}}, nil))
+}
+
+func TestCollStatsWiredTigerMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := bson.M{
+		"storageStats": bson.M{
+			"wiredTiger": bson.M{
+				"cache": bson.M{
+					"bytes currently in the cache": int64(1048576),
+					"bytes read into cache":        int64(524288),
+					"bytes written from cache":     int32(65536),
+					"modified pages evicted":       int32(12),
+					"unmodified pages evicted":     int64(30),
+				},
+				"block-manager": bson.M{
+					"bytes read":    int64(491520),
+					"bytes written": int64(81920),
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collstats_wiredtiger_block_read_bytes_total Bytes of the collection read from disk by the WiredTiger block manager
+	# TYPE mongodb_collstats_wiredtiger_block_read_bytes_total counter
+	mongodb_collstats_wiredtiger_block_read_bytes_total{collection="orders",database="shop"} 491520
+	# HELP mongodb_collstats_wiredtiger_block_written_bytes_total Bytes of the collection written to disk by the WiredTiger block manager
+	# TYPE mongodb_collstats_wiredtiger_block_written_bytes_total counter
+	mongodb_collstats_wiredtiger_block_written_bytes_total{collection="orders",database="shop"} 81920
+	# HELP mongodb_collstats_wiredtiger_cache_bytes Size of the data of the collection currently in the WiredTiger cache, in bytes
+	# TYPE mongodb_collstats_wiredtiger_cache_bytes gauge
+	mongodb_collstats_wiredtiger_cache_bytes{collection="orders",database="shop"} 1.048576e+06
+	# HELP mongodb_collstats_wiredtiger_cache_pages_evicted_total Pages of the collection evicted from the WiredTiger cache
+	# TYPE mongodb_collstats_wiredtiger_cache_pages_evicted_total counter
+	mongodb_collstats_wiredtiger_cache_pages_evicted_total{collection="orders",database="shop"} 42
+	# HELP mongodb_collstats_wiredtiger_cache_read_bytes_total Bytes of the collection read into the WiredTiger cache
+	# TYPE mongodb_collstats_wiredtiger_cache_read_bytes_total counter
+	mongodb_collstats_wiredtiger_cache_read_bytes_total{collection="orders",database="shop"} 524288
+	# HELP mongodb_collstats_wiredtiger_cache_written_bytes_total Bytes of the collection written from the WiredTiger cache
+	# TYPE mongodb_collstats_wiredtiger_cache_written_bytes_total counter
+	mongodb_collstats_wiredtiger_cache_written_bytes_total{collection="orders",database="shop"} 65536` + "\n")
+
+	metrics := collStatsWiredTigerMetrics(stats, map[string]string{"database": "shop", "collection": "orders"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Other storage engines don't have the wiredTiger section.
+	assert.Empty(t, collStatsWiredTigerMetrics(bson.M{"storageStats": bson.M{"size": int64(4096)}}, nil))
+}
+
+func TestDatabaseReclaimableMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := func(free interface{}) bson.M {
+		return bson.M{"storageStats": bson.M{"size": int64(1 << 20), "freeStorageSize": free}}
+	}
+
+	freeStorage := make(map[string]float64)
+	addFreeStorage(freeStorage, "shop", stats(int64(4096)))
+	addFreeStorage(freeStorage, "shop", stats(int32(8192)))
+	// A sharded collection has a result per shard.
+	addFreeStorage(freeStorage, "shop", stats(float64(12288)))
+	addFreeStorage(freeStorage, "logs", stats(int64(65536)))
+	// Not reported before MongoDB 4.4.
+	addFreeStorage(freeStorage, "legacy", bson.M{"storageStats": bson.M{"size": int64(1024)}})
+
+	expected := strings.NewReader(`
+	# HELP mongodb_database_reclaimable_bytes Free storage of the collected collections of the database, which compact can reclaim, in bytes
+	# TYPE mongodb_database_reclaimable_bytes gauge
+	mongodb_database_reclaimable_bytes{database="logs",rs_nm="rs"} 65536
+	mongodb_database_reclaimable_bytes{database="shop",rs_nm="rs"} 24576` + "\n")
+
+	metrics := databaseReclaimableMetrics(freeStorage, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+}
+
+func TestCollectionIsViewMetrics(t *testing.T) {
+	t.Parallel()
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collection_is_view Whether the requested namespace is a view, whose stats cannot be collected.
+	# TYPE mongodb_collection_is_view gauge
+	mongodb_collection_is_view{collection="col01",database="testdb01",rs_nm="rs"} 0
+	mongodb_collection_is_view{collection="view.with.dots",database="testdb01",rs_nm="rs"} 1` + "\n")
+
+	metrics := collectionIsViewMetrics([]string{"testdb01.col01"}, []string{"testdb01.view.with.dots"}, map[string]string{"rs_nm": "rs"})
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
@@ -71,6 +71,19 @@ func listCollections(ctx context.Context, client *mongo.Client, database string,
 	return collections, nil
 }
 
+// listViews returns the names of the views in the database.
+func listViews(ctx context.Context, client *mongo.Client, database string) ([]string, error) {
+	opts := &options.ListCollectionsOptions{NameOnly: pointer.ToBool(true), AuthorizedCollections: pointer.ToBool(true)}
+	filter := bson.D{{Key: "type", Value: "view"}}
+
+	views, err := client.Database(database).ListCollectionNames(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get the list of views")
+	}
+
+	return views, nil
+}
+
 // databases returns the list of databases matching the filters.
 // - filterInNamespaces: Include only the database names matching the any of the regular expressions in this list.
 //
@@ -190,6 +203,43 @@ func checkNamespacesForViews(ctx context.Context, client *mongo.Client, collecti
 	return filteredCollections, nil
 }
 
+// splitViews splits the namespaces into the ones which are not views, to get their stats, and
+// the ones of views.
+func splitViews(ctx context.Context, client *mongo.Client, namespaces []string) ([]string, []string, error) {
+	viewsByDB := make(map[string]map[string]struct{})
+
+	collections := []string{}
+	views := []string{}
+	for _, namespace := range removeEmptyStrings(namespaces) {
+		db, collection := splitNamespace(namespace)
+		if collection == "" {
+			continue
+		}
+
+		dbViews, ok := viewsByDB[db]
+		if !ok {
+			names, err := listViews(ctx, client, db)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			dbViews = make(map[string]struct{}, len(names))
+			for _, name := range names {
+				dbViews[name] = struct{}{}
+			}
+			viewsByDB[db] = dbViews
+		}
+
+		if _, ok := dbViews[collection]; ok {
+			views = append(views, namespace)
+		} else {
+			collections = append(collections, namespace)
+		}
+	}
+
+	return collections, views, nil
+}
+
 func listAllCollections(ctx context.Context, client *mongo.Client, filterInNamespaces []string, excludeDBs []string, skipViews bool) (map[string][]string, error) {
 	namespaces := make(map[string][]string)
 
@@ -209,3 +209,19 @@ func TestCheckNamespacesForViews(t *testing.T) {
 		assert.Equal(t, []string{"testdb01.col01", "testdb01.system.views"}, filtered)
 	})
 }
+
+//nolint:paralleltest
+func TestSplitViews(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	setupDB(ctx, t, client)
+	defer cleanupDB(ctx, client)
+
+	collections, views, err := splitViews(ctx, client, []string{"testdb01.col01", "testdb01.view01", "", "testdb01"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testdb01.col01"}, collections)
+	assert.Equal(t, []string{"testdb01.view01"}, views)
+}
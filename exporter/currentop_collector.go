@@ -18,6 +18,7 @@ package exporter
 import (
 	"context"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -44,7 +45,7 @@ func newCurrentopCollector(ctx context.Context, client *mongo.Client, logger *lo
 ) *currentopCollector {
 	return &currentopCollector{
 		ctx:               ctx,
-		base:              newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "currentop"})),
+		base:              newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "currentopmetrics"})),
 		compatibleMode:    compatible,
 		topologyInfo:      topology,
 		currentopslowtime: currentOpSlowTime,
@@ -149,4 +150,59 @@ func (d *currentopCollector) collect(ch chan<- prometheus.Metric) {
 
 		ch <- prometheus.MustNewConstMetric(pd, prometheus.GaugeValue, float64(microsecs_running), lv...)
 	}
+
+	// The index build coordinators run regardless of the slow time.
+	cmd = bson.D{
+		{Key: "currentOp", Value: true},
+		{Key: "desc", Value: bson.D{{Key: "$regex", Value: "^IndexBuildsCoordinator"}}},
+	}
+
+	var coordinators primitive.M
+	if err := client.Database("admin").RunCommand(d.ctx, cmd).Decode(&coordinators); err != nil {
+		logger.Errorf("Failed to get the index build coordinators from currentOp: %s", err)
+		return
+	}
+
+	coordinatorOps, _ := coordinators["inprog"].(primitive.A)
+	for _, metric := range indexBuildCoordinatorMetrics(coordinatorOps, labels) {
+		ch <- metric
+	}
+}
+
+// indexBuildCoordinatorMetrics returns the number of index build coordinator operations per
+// namespace. On replica sets, they coordinate the two-phase index builds of the members.
+func indexBuildCoordinatorMetrics(inprog primitive.A, labels map[string]string) []prometheus.Metric {
+	builds := make(map[string]float64)
+	for _, op := range inprog {
+		m, ok := op.(primitive.M)
+		if !ok {
+			continue
+		}
+
+		if desc, _ := m["desc"].(string); !strings.HasPrefix(desc, "IndexBuildsCoordinator") {
+			continue
+		}
+
+		if namespace, ok := m["ns"].(string); ok && namespace != "" {
+			builds[namespace]++
+		}
+	}
+
+	metrics := make([]prometheus.Metric, 0, len(builds))
+	for namespace, n := range builds {
+		database, collection := splitNamespace(namespace)
+
+		l := make(map[string]string, len(labels)+2)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["database"] = database
+		l["collection"] = collection
+
+		d := prometheus.NewDesc("mongodb_index_build_coordinator_active",
+			"Number of index builds coordinated on the collection", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, n))
+	}
+
+	return metrics
 }
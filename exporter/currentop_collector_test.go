@@ -17,6 +17,7 @@ package exporter
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -25,6 +26,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -82,3 +84,39 @@ func TestCurrentopCollector(t *testing.T) {
 	assert.True(t, count > 0)
 	wg.Wait()
 }
+
+func TestIndexBuildCoordinatorMetrics(t *testing.T) {
+	t.Parallel()
+
+	inprog := primitive.A{
+		primitive.M{
+			"desc":    "IndexBuildsCoordinatorMongod-3",
+			"op":      "command",
+			"ns":      "testdb.orders",
+			"command": primitive.M{"createIndexes": "orders", "indexes": primitive.A{primitive.M{"key": primitive.M{"sku": 1}}}},
+		},
+		primitive.M{
+			"desc": "IndexBuildsCoordinatorMongod-4",
+			"op":   "command",
+			"ns":   "testdb.orders",
+		},
+		primitive.M{
+			"desc": "IndexBuildsCoordinatorMongod-5",
+			"op":   "command",
+			"ns":   "testdb.users",
+		},
+		// Other operations are ignored.
+		primitive.M{"desc": "conn12", "op": "query", "ns": "testdb.users"},
+	}
+
+	expected := strings.NewReader(`
+# HELP mongodb_index_build_coordinator_active Number of index builds coordinated on the collection
+# TYPE mongodb_index_build_coordinator_active gauge
+mongodb_index_build_coordinator_active{collection="orders",database="testdb",rs_nm="rs1"} 2
+mongodb_index_build_coordinator_active{collection="users",database="testdb",rs_nm="rs1"} 1` + "\n")
+
+	metrics := indexBuildCoordinatorMetrics(inprog, map[string]string{"rs_nm": "rs1"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	assert.Empty(t, indexBuildCoordinatorMetrics(nil, nil))
+}
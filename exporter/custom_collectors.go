@@ -0,0 +1,53 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CustomCollectorFactory creates a collector for a scrape, with the MongoDB client and the
+// topology labels of the scrape, like cl_role and rs_nm.
+type CustomCollectorFactory func(ctx context.Context, client *mongo.Client, labels map[string]string) prometheus.Collector
+
+// RegisterCustomCollector adds a collector created by factory to every scrape, after the
+// collectors of the exporter, so the packages embedding the exporter can add their own MongoDB
+// metrics. It is safe to call while the exporter serves the metrics.
+func (e *Exporter) RegisterCustomCollector(factory CustomCollectorFactory) {
+	e.customCollectorsMu.Lock()
+	defer e.customCollectorsMu.Unlock()
+
+	e.customCollectors = append(e.customCollectors, factory)
+}
+
+// registerCustomCollectors registers the metrics of the custom collectors. They are collected
+// right away, as the collectors of the exporter, because the client can be disconnected by the
+// time the registry is gathered. A collector conflicting with other metrics is skipped.
+func (e *Exporter) registerCustomCollectors(ctx context.Context, registry prometheus.Registerer, client *mongo.Client, topologyInfo labelsGetter) {
+	e.customCollectorsMu.Lock()
+	factories := append([]CustomCollectorFactory(nil), e.customCollectors...)
+	e.customCollectorsMu.Unlock()
+
+	for i, factory := range factories {
+		metrics := collectNow(factory(ctx, client, topologyInfo.baseLabels()))
+		if err := registry.Register(metrics); err != nil {
+			e.logger.Errorf("Cannot register custom collector %d: %s", i, err)
+		}
+	}
+}
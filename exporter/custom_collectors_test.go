@@ -0,0 +1,74 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRegisterCustomCollector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+	require.NoError(t, err)
+
+	e := &Exporter{
+		logger: logrus.New(),
+		opts:   &Opts{Logger: logrus.New()},
+	}
+
+	var gotClient *mongo.Client
+	e.RegisterCustomCollector(func(_ context.Context, client *mongo.Client, labels map[string]string) prometheus.Collector {
+		gotClient = client
+
+		g := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "myorg_orders_pending",
+			Help:        "Orders waiting to be shipped",
+			ConstLabels: labels,
+		})
+		g.Set(42)
+
+		return g
+	})
+	// Conflicts with the first one, it is skipped instead of failing the scrape.
+	e.RegisterCustomCollector(func(context.Context, *mongo.Client, map[string]string) prometheus.Collector {
+		return prometheus.NewGauge(prometheus.GaugeOpts{Name: "myorg_orders_pending", Help: "Duplicate"})
+	})
+
+	registry := e.makeRegistry(ctx, client, labelsGetterMock{}, newScrapeCache(client, 0), *e.opts)
+	assert.Same(t, client, gotClient)
+
+	// The metrics were collected while the client was connected.
+	require.NoError(t, client.Disconnect(ctx))
+
+	expected := strings.NewReader(`
+	# HELP myorg_orders_pending Orders waiting to be shipped
+	# TYPE myorg_orders_pending gauge
+	myorg_orders_pending 42` + "\n")
+	assert.NoError(t, testutil.GatherAndCompare(registry, expected, "myorg_orders_pending"))
+}
@@ -0,0 +1,95 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type databaseAccessCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	topologyInfo labelsGetter
+}
+
+// newDatabaseAccessCollector creates a collector checking which user databases the exporter can read.
+func newDatabaseAccessCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, topology labelsGetter) *databaseAccessCollector {
+	return &databaseAccessCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "dbaccess"})),
+		topologyInfo: topology,
+	}
+}
+
+func (d *databaseAccessCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *databaseAccessCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *databaseAccessCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "database_access")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// Users with the listDatabases privilege, like the clusterMonitor role, get all the databases
+	// even if they cannot read them.
+	dbNames, err := databases(d.ctx, client, nil, systemDBs)
+	if err != nil {
+		logger.Errorf("cannot get databases: %s", err)
+		return
+	}
+
+	check := func(database string) error {
+		_, err := client.Database(database).ListCollectionNames(d.ctx, bson.D{}, options.ListCollections().SetNameOnly(true))
+		return err
+	}
+
+	for _, metric := range databaseAccessMetrics(logger, dbNames, check, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// databaseAccessMetrics returns whether each database could be read by the check function.
+func databaseAccessMetrics(logger *logrus.Entry, dbNames []string, check func(database string) error, labels map[string]string) []prometheus.Metric {
+	desc := prometheus.NewDesc("mongodb_database_accessible",
+		"Whether the exporter can list the collections of the database with its permissions.",
+		[]string{"database"}, labels)
+
+	metrics := make([]prometheus.Metric, 0, len(dbNames))
+	for _, db := range dbNames {
+		accessible := float64(1)
+		if err := check(db); err != nil {
+			// Not an error of the collector, the metric reports it.
+			logger.Debugf("cannot list collections of database %s: %s", db, err)
+			accessible = 0
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, accessible, db))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*databaseAccessCollector)(nil)
@@ -0,0 +1,81 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestDatabaseAccessCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	database := client.Database("testdb_access")
+	database.Drop(ctx)       //nolint:errcheck
+	defer database.Drop(ctx) //nolint:errcheck
+
+	_, err := database.Collection("testcol").InsertOne(ctx, bson.M{"f1": 1})
+	assert.NoError(t, err)
+
+	c := newDatabaseAccessCollector(ctx, client, logrus.New(), labelsGetterMock{})
+
+	// Other tests create databases too, only check the one created here.
+	metrics := filterMetricsWithLabels(helpers.ReadMetrics(helpers.CollectMetrics(c)),
+		[]string{"mongodb_database_accessible"}, map[string]string{"database": "testdb_access"})
+	if assert.Len(t, metrics, 1) {
+		assert.Equal(t, float64(1), metrics[0].Value)
+	}
+}
+
+func TestDatabaseAccessMetrics(t *testing.T) {
+	t.Parallel()
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	check := func(database string) error {
+		if database == "billing" {
+			return mongo.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized on billing to execute command"}
+		}
+
+		return nil
+	}
+
+	metrics := databaseAccessMetrics(logger.WithField("component", "test"), []string{"app", "billing"}, check, map[string]string{"rs_nm": "rs1"})
+
+	expected := strings.NewReader(`
+# HELP mongodb_database_accessible Whether the exporter can list the collections of the database with its permissions.
+# TYPE mongodb_database_accessible gauge
+mongodb_database_accessible{database="app",rs_nm="rs1"} 1
+mongodb_database_accessible{database="billing",rs_nm="rs1"} 0` + "\n")
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
@@ -17,6 +17,7 @@ package exporter
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -31,13 +32,19 @@ type dbstatsCollector struct {
 	compatibleMode bool
 	topologyInfo   labelsGetter
 
-	databaseFilter []string
+	databaseFilter   []string
+	excludeDatabases []string
 
-	freeStorage bool
+	freeStorage    bool
+	commandRetries int
 }
 
 // newDBStatsCollector creates a collector for statistics on database storage.
-func newDBStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter, databaseRegex []string, freeStorage bool) *dbstatsCollector {
+// databaseRegex are regular expressions matching the whole database name, empty means all the
+// databases. excludeDatabases are regular expressions matching the databases to skip among them.
+func newDBStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter,
+	databaseRegex []string, excludeDatabases []string, freeStorage bool, commandRetries int,
+) *dbstatsCollector {
 	return &dbstatsCollector{
 		ctx:  ctx,
 		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "dbstats"})),
@@ -45,9 +52,11 @@ func newDBStatsCollector(ctx context.Context, client *mongo.Client, logger *logr
 		compatibleMode: compatible,
 		topologyInfo:   topology,
 
-		databaseFilter: databaseRegex,
+		databaseFilter:   databaseRegex,
+		excludeDatabases: excludeDatabases,
 
-		freeStorage: freeStorage,
+		freeStorage:    freeStorage,
+		commandRetries: commandRetries,
 	}
 }
 
@@ -65,13 +74,20 @@ func (d *dbstatsCollector) collect(ch chan<- prometheus.Metric) {
 	logger := d.base.logger
 	client := d.base.client
 
-	dbNames, err := databases(d.ctx, client, d.databaseFilter, nil)
+	dbNames, err := databases(d.ctx, client, nil, nil)
 	if err != nil {
 		logger.Errorf("Failed to get database names: %s", err)
 
 		return
 	}
 
+	dbNames, err = filterDatabases(dbNames, d.databaseFilter, d.excludeDatabases)
+	if err != nil {
+		logger.Errorf("Invalid database filter: %s", err)
+
+		return
+	}
+
 	logger.Debugf("getting stats for databases: %v", dbNames)
 	for _, db := range dbNames {
 		var dbStats bson.M
@@ -81,7 +97,7 @@ func (d *dbstatsCollector) collect(ch chan<- prometheus.Metric) {
 		} else {
 			cmd = bson.D{{Key: "dbStats", Value: 1}, {Key: "scale", Value: 1}}
 		}
-		r := client.Database(db).RunCommand(d.ctx, cmd)
+		r := runCommand(d.ctx, client.Database(db), cmd, d.commandRetries)
 		err := r.Decode(&dbStats)
 		if err != nil {
 			logger.Errorf("Failed to get $dbstats for database %s: %s", db, err)
@@ -101,10 +117,104 @@ func (d *dbstatsCollector) collect(ch chan<- prometheus.Metric) {
 		labels["database"] = db
 
 		newMetrics := makeMetrics(prefix, dbStats, labels, d.compatibleMode)
+		newMetrics = append(newMetrics, storageEfficiencyMetrics(dbStats, labels)...)
 		for _, metric := range newMetrics {
 			ch <- metric
 		}
+
+		views, err := listViews(d.ctx, client, db)
+		if err != nil {
+			logger.Errorf("Failed to get views for database %s: %s", db, err)
+
+			continue
+		}
+
+		desc := prometheus.NewDesc("mongodb_database_views_total", "Number of views in the database", nil, labels)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(len(views)))
+	}
+}
+
+// storageEfficiencyMetrics returns the ratio of the uncompressed data size of the database to
+// the storage allocated to it in its dbStats response. Compression raises it, fragmentation
+// lowers it. Empty databases, without storage, have no ratio.
+func storageEfficiencyMetrics(dbStats bson.M, labels map[string]string) []prometheus.Metric {
+	dataSize, err := asFloat64(dbStats["dataSize"])
+	if err != nil || dataSize == nil {
+		return nil
+	}
+
+	storageSize, err := asFloat64(dbStats["storageSize"])
+	if err != nil || storageSize == nil || *storageSize == 0 {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_dbstats_storage_efficiency_ratio",
+		"Uncompressed size of the data of the database divided by the storage allocated to it", nil, labels)
+
+	return []prometheus.Metric{prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *dataSize / *storageSize)}
+}
+
+// matchDatabases returns the names fully matching any of the regular expressions.
+// Without expressions, all the names are returned.
+func matchDatabases(names []string, patterns []string) ([]string, error) {
+	patterns = removeEmptyStrings(patterns)
+	if len(patterns) == 0 {
+		return names, nil
+	}
+
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+
+	matched := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, re := range res {
+			if re.MatchString(name) {
+				matched = append(matched, name)
+				break
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// filterDatabases returns the names fully matching any of the include regular expressions and
+// none of the exclude ones, e.g. all the tenant_.* databases but tenant_scratch. Without
+// include expressions, all the names which are not excluded are returned.
+func filterDatabases(names []string, include []string, exclude []string) ([]string, error) {
+	included, err := matchDatabases(names, include)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(removeEmptyStrings(exclude)) == 0 {
+		return included, nil
+	}
+
+	excluded, err := matchDatabases(included, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]struct{}, len(excluded))
+	for _, name := range excluded {
+		skip[name] = struct{}{}
+	}
+
+	filtered := make([]string, 0, len(included)-len(excluded))
+	for _, name := range included {
+		if _, ok := skip[name]; !ok {
+			filtered = append(filtered, name)
+		}
 	}
+
+	return filtered, nil
 }
 
 var _ prometheus.Collector = (*dbstatsCollector)(nil)
@@ -59,7 +59,7 @@ func TestDBStatsCollector(t *testing.T) {
 	ti := labelsGetterMock{}
 
 	logger := logrus.New()
-	c := newDBStatsCollector(ctx, client, logger, false, ti, []string{dbName}, false)
+	c := newDBStatsCollector(ctx, client, logger, false, ti, []string{dbName}, nil, false, 0)
 	expected := strings.NewReader(`
 	# HELP mongodb_dbstats_collections dbstats.collections
 	# TYPE mongodb_dbstats_collections untyped
@@ -79,4 +79,101 @@ func TestDBStatsCollector(t *testing.T) {
 	}
 	err := testutil.CollectAndCompare(c, expected, filters...)
 	assert.NoError(t, err)
+
+	// The value depends on the storage engine, the database is not empty so it has one.
+	assert.Equal(t, 1, testutil.CollectAndCount(c, "mongodb_dbstats_storage_efficiency_ratio"))
+}
+
+func TestStorageEfficiencyMetrics(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"database": "shop"}
+	dbStats := bson.M{"db": "shop", "collections": int32(2), "dataSize": float64(3000), "storageSize": float64(1200)}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_dbstats_storage_efficiency_ratio Uncompressed size of the data of the database divided by the storage allocated to it
+	# TYPE mongodb_dbstats_storage_efficiency_ratio gauge
+	mongodb_dbstats_storage_efficiency_ratio{database="shop"} 2.5` + "\n")
+	err := testutil.CollectAndCompare(metricsCollector(storageEfficiencyMetrics(dbStats, labels)), expected)
+	assert.NoError(t, err)
+
+	// An empty database has no storage.
+	assert.Empty(t, storageEfficiencyMetrics(bson.M{"dataSize": int32(0), "storageSize": int32(0)}, labels))
+	assert.Empty(t, storageEfficiencyMetrics(bson.M{"collections": int32(0)}, labels))
+}
+
+func TestDBStatsCollectorViews(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	database := client.Database(dbName + "_views")
+	database.Drop(ctx) //nolint
+
+	defer func() {
+		err := database.Drop(ctx)
+		assert.NoError(t, err)
+	}()
+
+	_, err := database.Collection("testcol").InsertOne(ctx, bson.M{"f1": 1})
+	assert.NoError(t, err)
+
+	for _, view := range []string{"view_01", "view_02"} {
+		err := database.CreateView(ctx, view, "testcol", bson.A{bson.M{"$match": bson.M{"f1": 1}}})
+		assert.NoError(t, err)
+	}
+
+	c := newDBStatsCollector(ctx, client, logrus.New(), false, labelsGetterMock{}, []string{dbName + "_views"}, nil, false, 0)
+	expected := strings.NewReader(`
+	# HELP mongodb_database_views_total Number of views in the database
+	# TYPE mongodb_database_views_total gauge
+	mongodb_database_views_total{database="testdb_views"} 2` + "\n")
+
+	err = testutil.CollectAndCompare(c, expected, "mongodb_database_views_total")
+	assert.NoError(t, err)
+}
+
+func TestMatchDatabases(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"app_orders", "app_users", "reporting", "testdb", "testdb_views"}
+
+	got, err := matchDatabases(names, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, names, got)
+
+	got, err = matchDatabases(names, []string{"app_.*", "testdb", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app_orders", "app_users", "testdb"}, got)
+
+	_, err = matchDatabases(names, []string{"app_(.*"})
+	assert.Error(t, err)
+}
+
+func TestFilterDatabases(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"admin", "config", "local", "reporting", "tenant_acme", "tenant_globex", "tenant_scratch"}
+
+	got, err := filterDatabases(names, []string{"tenant_.*"}, []string{"admin", "config", "local", "tenant_scratch"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tenant_acme", "tenant_globex"}, got)
+
+	// Without include patterns, all the databases but the excluded ones.
+	got, err = filterDatabases(names, nil, []string{"admin", "config", "local", "tenant_s.*"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reporting", "tenant_acme", "tenant_globex"}, got)
+
+	// Exclusions match the whole name.
+	got, err = filterDatabases(names, []string{"tenant_.*"}, []string{"tenant"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tenant_acme", "tenant_globex", "tenant_scratch"}, got)
+
+	got, err = filterDatabases(names, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, names, got)
+
+	_, err = filterDatabases(names, nil, []string{"tenant_(.*"})
+	assert.Error(t, err)
 }
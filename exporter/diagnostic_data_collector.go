@@ -17,6 +17,7 @@ package exporter
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,12 +38,16 @@ type diagnosticDataCollector struct {
 
 	buildInfo buildInfo
 
-	compatibleMode bool
-	topologyInfo   labelsGetter
+	compatibleMode  bool
+	fallback        bool
+	changelogWindow time.Duration
+	sections        []string
+	topologyInfo    labelsGetter
+	cache           *scrapeCache
 }
 
 // newDiagnosticDataCollector creates a collector for diagnostic information.
-func newDiagnosticDataCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter, buildInfo buildInfo) *diagnosticDataCollector {
+func newDiagnosticDataCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible, fallback bool, changelogWindow time.Duration, sections []string, topology labelsGetter, buildInfo buildInfo, cache *scrapeCache) *diagnosticDataCollector {
 	nodeType, err := getNodeType(ctx, client)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
@@ -57,12 +62,16 @@ func newDiagnosticDataCollector(ctx context.Context, client *mongo.Client, logge
 
 	return &diagnosticDataCollector{
 		ctx:  ctx,
-		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "diagnostic_data"})),
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "diagnosticdata"})),
 
 		buildInfo: buildInfo,
 
-		compatibleMode: compatible,
-		topologyInfo:   topology,
+		compatibleMode:  compatible,
+		fallback:        fallback,
+		changelogWindow: changelogWindow,
+		sections:        sections,
+		topologyInfo:    topology,
+		cache:           cache,
 	}
 }
 
@@ -90,29 +99,18 @@ func (d *diagnosticDataCollector) collect(ch chan<- prometheus.Metric) {
 	}
 
 	var metrics []prometheus.Metric
-	cmd := bson.D{{Key: "getDiagnosticData", Value: "1"}}
-	res := client.Database("admin").RunCommand(d.ctx, cmd)
-	if res.Err() != nil {
+	m, err = diagnosticData(d.ctx, d.cache, d.fallback, logger)
+	if err != nil {
+		// Arbiters have no users, the command always fails on them.
 		if nodeType != typeArbiter {
-			logger.Warnf("failed to run command: getDiagnosticData, some metrics might be unavailable %s", res.Err())
+			logger.Warnf("failed to run command: getDiagnosticData, some metrics might be unavailable %s", err)
+			if m := unauthorizedMetric(err, d.base.name, "getDiagnosticData"); m != nil {
+				metrics = append(metrics, m)
+			}
 		}
+	} else if m, err = diagnosticDataDocument(m); err != nil {
+		logger.Errorf("cannot decode getDiagnosticData: %s", err)
 	} else {
-		if err := res.Decode(&m); err != nil {
-			logger.Errorf("cannot run getDiagnosticData: %s", err)
-			return
-		}
-
-		if m == nil || m["data"] == nil {
-			logger.Error("cannot run getDiagnosticData: response is empty")
-		}
-
-		var ok bool
-		m, ok = m["data"].(bson.M)
-		if !ok {
-			err = errors.Wrapf(errUnexpectedDataType, "%T for data field", m["data"])
-			logger.Errorf("cannot decode getDiagnosticData: %s", err)
-		}
-
 		logger.Debug("getDiagnosticData result")
 		debugResult(logger, m)
 
@@ -131,8 +129,15 @@ func (d *diagnosticDataCollector) collect(ch chan<- prometheus.Metric) {
 			m = b
 		}
 
-		metrics = makeMetrics("", m, d.topologyInfo.baseLabels(), d.compatibleMode)
+		metrics = makeMetrics("", diagnosticDataSections(m, d.sections), d.topologyInfo.baseLabels(), d.compatibleMode)
 		metrics = append(metrics, locksMetrics(logger, m)...)
+		metrics = append(metrics, wiredTigerEvictionMetrics(m, d.topologyInfo.baseLabels())...)
+		metrics = append(metrics, wiredTigerCacheFillMetrics(m, d.topologyInfo.baseLabels())...)
+		metrics = append(metrics, wiredTigerMaintenanceMetrics(m, d.topologyInfo.baseLabels())...)
+		metrics = append(metrics, cursorMetrics(m, d.topologyInfo.baseLabels())...)
+		metrics = append(metrics, getLastErrorMetrics(m, d.topologyInfo.baseLabels())...)
+		metrics = append(metrics, opWriteConcernMetrics(m, d.topologyInfo.baseLabels())...)
+		metrics = append(metrics, oplogTruncationMetrics(m, d.topologyInfo.baseLabels())...)
 
 		securityMetric, err := d.getSecurityMetricFromLineOptions(client)
 		if err != nil {
@@ -142,7 +147,7 @@ func (d *diagnosticDataCollector) collect(ch chan<- prometheus.Metric) {
 		}
 
 		if d.compatibleMode {
-			metrics = append(metrics, specialMetrics(d.ctx, client, m, nodeType, logger)...)
+			metrics = append(metrics, specialMetrics(d.ctx, client, d.cache, m, nodeType, logger)...)
 
 			if cem, err := cacheEvictedTotalMetric(m); err == nil {
 				metrics = append(metrics, cem)
@@ -160,7 +165,7 @@ func (d *diagnosticDataCollector) collect(ch chan<- prometheus.Metric) {
 		}
 
 		if nodeType == typeMongos {
-			metrics = append(metrics, mongosMetrics(d.ctx, client, logger)...)
+			metrics = append(metrics, mongosMetrics(d.ctx, client, d.changelogWindow, logger)...)
 		}
 	}
 
@@ -169,6 +174,111 @@ func (d *diagnosticDataCollector) collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// diagnosticDataSections returns the top-level sections of the diagnostic data m named in
+// sections, or m without sections.
+func diagnosticDataSections(m bson.M, sections []string) bson.M {
+	if len(sections) == 0 {
+		return m
+	}
+
+	filtered := make(bson.M, len(sections))
+	for _, name := range sections {
+		if v, ok := m[name]; ok {
+			filtered[name] = v
+		}
+	}
+
+	return filtered
+}
+
+// diagnosticDataFallbackCommands are the commands whose results getDiagnosticData includes,
+// run one by one when it is not available.
+var diagnosticDataFallbackCommands = []string{"serverStatus", "replSetGetStatus", "getCmdLineOpts"}
+
+// diagnosticData returns the result of getDiagnosticData. Some managed services block it but
+// allow serverStatus, so with fallback enabled an equivalent result is assembled from the
+// results of the separate commands when getDiagnosticData is not authorized or not found.
+func diagnosticData(ctx context.Context, cache *scrapeCache, fallback bool, logger *logrus.Entry) (bson.M, error) {
+	m, err := cache.command(ctx, "getDiagnosticData")
+	if err == nil || !fallback || !diagnosticDataUnavailable(err) {
+		return m, err
+	}
+
+	logger.Debugf("getDiagnosticData is not available, running the commands separately: %s", err)
+
+	data := bson.M{}
+	for _, name := range diagnosticDataFallbackCommands {
+		res, cmdErr := cache.command(ctx, name)
+		if cmdErr != nil {
+			// replSetGetStatus fails on standalone servers, the other results are still useful.
+			logger.Debugf("cannot run %s for the getDiagnosticData fallback: %s", name, cmdErr)
+			continue
+		}
+		data[name] = res
+	}
+
+	if len(data) == 0 {
+		return nil, err
+	}
+
+	return bson.M{"data": data}, nil
+}
+
+// diagnosticDataDocument returns the data document of a getDiagnosticData result. Depending on
+// the decoding options of the client, the documents are bson.M or bson.D, which is converted
+// to the bson.M the metrics are made from.
+func diagnosticDataDocument(m bson.M) (bson.M, error) {
+	switch data := m["data"].(type) {
+	case bson.M:
+		return data, nil
+	case map[string]interface{}:
+		return data, nil
+	case bson.D:
+		return documentToMap(data), nil
+	case nil:
+		return nil, errors.New("response is empty")
+	default:
+		return nil, errors.Wrapf(errUnexpectedDataType, "%T for data field", data)
+	}
+}
+
+// documentToMap converts d and its embedded documents to bson.M.
+func documentToMap(d bson.D) bson.M {
+	m := make(bson.M, len(d))
+	for _, e := range d {
+		m[e.Key] = documentsToMaps(e.Value)
+	}
+
+	return m
+}
+
+// documentsToMaps converts the bson.D documents in v, including the embedded ones, to bson.M.
+func documentsToMaps(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		return documentToMap(v)
+	case bson.A:
+		a := make(bson.A, len(v))
+		for i, e := range v {
+			a[i] = documentsToMaps(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// diagnosticDataUnavailable returns true if the server rejected getDiagnosticData because the
+// user is not authorized to run it or the command doesn't exist.
+func diagnosticDataUnavailable(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+
+	return serverErr.HasErrorCode(errCodeUnauthorized) || serverErr.HasErrorCode(errCodeCommandNotFound)
+}
+
 func (d *diagnosticDataCollector) getSecurityMetricFromLineOptions(client *mongo.Client) (prometheus.Metric, error) {
 	var cmdLineOpionsBson bson.M
 	cmdLineOptions := bson.D{{Key: "getCmdLineOpts", Value: "1"}}
@@ -231,5 +341,227 @@ func (d *diagnosticDataCollector) retrieveSecurityEncryptionMetric(securityOptio
 	return metric, nil
 }
 
+// wiredTigerEvictionMetrics returns the pages evicted by the eviction worker threads and by
+// the application threads. Application threads only evict when the workers cannot keep up,
+// which shows up as latency spikes. Nothing is returned if the storage engine is not WiredTiger.
+func wiredTigerEvictionMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	cache, ok := walkTo(m, []string{"serverStatus", "wiredTiger", "cache"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	return documentMetrics(cache, []documentMetric{
+		{
+			name:      "mongodb_wiredtiger_cache_eviction_worker_pages_evicted_total",
+			help:      "Pages evicted from the WiredTiger cache by the eviction worker threads",
+			path:      []string{"eviction worker thread evicting pages"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_wiredtiger_cache_application_threads_evicting_pages_total",
+			help:      "Pages evicted from the WiredTiger cache by application threads",
+			path:      []string{"pages evicted by application threads"},
+			valueType: prometheus.CounterValue,
+		},
+	}, labels)
+}
+
+// wiredTigerCacheFillMetrics returns the ratio of the WiredTiger cache in use. Eviction gets
+// aggressive as it approaches 95%, at which application threads are throttled to evict pages.
+// Nothing is returned if the storage engine is not WiredTiger or the cache size is unknown.
+func wiredTigerCacheFillMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	cache, ok := walkTo(m, []string{"serverStatus", "wiredTiger", "cache"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	used, err := asFloat64(cache["bytes currently in the cache"])
+	if err != nil || used == nil {
+		return nil
+	}
+
+	maxBytes, err := asFloat64(cache["maximum bytes configured"])
+	if err != nil || maxBytes == nil || *maxBytes == 0 {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_wiredtiger_cache_fill_ratio",
+		"Ratio of the WiredTiger cache in use, bytes currently in the cache over the maximum bytes configured", nil, labels)
+
+	return []prometheus.Metric{prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *used / *maxBytes)}
+}
+
+// wiredTigerMaintenanceMetrics returns the page reconciliations and the compact operations of
+// WiredTiger, background work that competes with the foreground operations for the disk and the
+// cache. Nothing is returned if the storage engine is not WiredTiger.
+func wiredTigerMaintenanceMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	wiredTiger, ok := walkTo(m, []string{"serverStatus", "wiredTiger"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	return documentMetrics(wiredTiger, []documentMetric{
+		{
+			name:      "mongodb_wiredtiger_reconciliation_pages_total",
+			help:      "Pages reconciled by WiredTiger, written from the cache to their on disk format",
+			path:      []string{"reconciliation", "page reconciliation calls"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_wiredtiger_reconciliation_eviction_pages_total",
+			help:      "Pages reconciled by WiredTiger to evict them from the cache",
+			path:      []string{"reconciliation", "page reconciliation calls for eviction"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_wiredtiger_compact_running",
+			help:      "Compact operations running on WiredTiger tables",
+			path:      []string{"session", "table compact running"},
+			valueType: prometheus.GaugeValue,
+		},
+		{
+			name:      "mongodb_wiredtiger_compact_successful_total",
+			help:      "Compact operations on WiredTiger tables which succeeded",
+			path:      []string{"session", "table compact successful calls"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_wiredtiger_compact_failed_total",
+			help:      "Compact operations on WiredTiger tables which failed",
+			path:      []string{"session", "table compact failed calls"},
+			valueType: prometheus.CounterValue,
+		},
+	}, labels)
+}
+
+// cursorMetrics returns the cursors closed by the server after being idle, typically leaked by
+// clients, and the open cursors without timeout, which are never closed if leaked. They are
+// also exposed untyped as mongodb_ss_metrics_cursor_*.
+func cursorMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	cursor, ok := walkTo(m, []string{"serverStatus", "metrics", "cursor"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	return documentMetrics(cursor, []documentMetric{
+		{
+			name:      "mongodb_metrics_cursor_timed_out_total",
+			help:      "Cursors closed by the server because they were idle for longer than the cursor timeout",
+			path:      []string{"timedOut"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_metrics_cursor_open_no_timeout",
+			help:      "Open cursors with the noCursorTimeout option, they are never closed by the server",
+			path:      []string{"open", "noTimeout"},
+			valueType: prometheus.GaugeValue,
+		},
+	}, labels)
+}
+
+// getLastErrorMetrics returns the time spent waiting for the write concern and the number of
+// write concern timeouts, the latency added by the durability requirements of the writes.
+// They are also exposed untyped as mongodb_ss_metrics_getLastError_*.
+func getLastErrorMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	getLastError, ok := walkTo(m, []string{"serverStatus", "metrics", "getLastError"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	return documentMetrics(getLastError, []documentMetric{
+		{
+			name:      "mongodb_metrics_get_last_error_wtime_total_millis",
+			help:      "Time spent waiting for the write concern of the writes, in milliseconds",
+			path:      []string{"wtime", "totalMillis"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_metrics_get_last_error_wtimeouts_total",
+			help:      "Writes whose write concern timed out because of the wtimeout",
+			path:      []string{"wtimeouts"},
+			valueType: prometheus.CounterValue,
+		},
+	}, labels)
+}
+
+// opWriteConcernMetrics returns the number of inserts, updates and deletes by write concern.
+// serverStatus.opWriteConcernCounters is only reported by MongoDB 5.0+ started with the
+// reportOpWriteConcernCountersInServerStatus parameter. The wc label is "majority", "none",
+// the number of nodes or the tag of the write concern.
+func opWriteConcernMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	counters, ok := walkTo(m, []string{"serverStatus", "opWriteConcernCounters"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_op_write_concern_total",
+		"Number of write operations by write concern", []string{"op", "wc"}, labels)
+
+	var metrics []prometheus.Metric
+	add := func(op, wc string, value interface{}) {
+		if v, err := asFloat64(value); err == nil && v != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *v, op, wc))
+		}
+	}
+
+	for _, op := range []string{"insert", "update", "delete"} {
+		wcs, ok := counters[op].(bson.M)
+		if !ok {
+			continue
+		}
+
+		add(op, "majority", wcs["wmajority"])
+		add(op, "none", wcs["none"])
+
+		// The number of nodes and the tags are sub-documents, noneInfo only details none.
+		for _, key := range []string{"wnum", "wtag"} {
+			values, _ := wcs[key].(bson.M)
+			for wc, value := range values {
+				add(op, wc, value)
+			}
+		}
+	}
+
+	return metrics
+}
+
+// oplogTruncationMetrics returns the time spent truncating the oplog to its maximum size and
+// the number of truncations, which complement the oplog window. They are only reported by
+// the WiredTiger storage engine, in serverStatus.oplogTruncation or, on older versions, in
+// serverStatus.wiredTiger.oplog.
+func oplogTruncationMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	if _, ok := walkTo(m, []string{"serverStatus", "wiredTiger"}).(bson.M); !ok {
+		return nil
+	}
+
+	truncation, ok := walkTo(m, []string{"serverStatus", "oplogTruncation"}).(bson.M)
+	if !ok {
+		if truncation, ok = walkTo(m, []string{"serverStatus", "wiredTiger", "oplog"}).(bson.M); !ok {
+			return nil
+		}
+	}
+
+	return documentMetrics(truncation, []documentMetric{
+		{
+			name:      "mongodb_oplog_truncation_total_time_processing_micros",
+			help:      "Time spent scanning or sampling the oplog at startup to find the truncation points, in microseconds",
+			path:      []string{"totalTimeProcessingMicros"},
+			valueType: prometheus.GaugeValue,
+		},
+		{
+			name:      "mongodb_oplog_truncation_time_truncating_micros_total",
+			help:      "Cumulative time spent truncating the oplog, in microseconds",
+			path:      []string{"totalTimeTruncatingMicros"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_oplog_truncations_total",
+			help:      "Number of oplog truncations",
+			path:      []string{"truncateCount"},
+			valueType: prometheus.CounterValue,
+		},
+	}, labels)
+}
+
 // check interface.
 var _ prometheus.Collector = (*diagnosticDataCollector)(nil)
@@ -34,6 +34,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -49,7 +50,7 @@ func TestDiagnosticDataCollector(t *testing.T) {
 	dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 	require.NoError(t, err)
 
-	c := newDiagnosticDataCollector(ctx, client, logger, false, ti, dbBuildInfo)
+	c := newDiagnosticDataCollector(ctx, client, logger, false, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 
 	prefix := "local.oplog.rs.stats.storageStats.wiredTiger"
 	if dbBuildInfo.VersionArray[0] < 7 {
@@ -196,7 +197,7 @@ func TestCollectorWithCompatibleMode(t *testing.T) {
 			dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 			require.NoError(t, err)
 
-			c := newDiagnosticDataCollector(ctx, client, logger, true, ti, dbBuildInfo)
+			c := newDiagnosticDataCollector(ctx, client, logger, true, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 
 			err = testutil.CollectAndCompare(c, tt.expectedMetrics(), tt.metricsFilter...)
 			assert.NoError(t, err)
@@ -212,12 +213,12 @@ func TestAllDiagnosticDataCollectorMetrics(t *testing.T) {
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.DebugLevel)
-	ti := newTopologyInfo(ctx, client, logger)
+	ti := newTopologyInfo(ctx, client, logger, newScrapeCache(client, 0))
 
 	dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 	require.NoError(t, err)
 
-	c := newDiagnosticDataCollector(ctx, client, logger, true, ti, dbBuildInfo)
+	c := newDiagnosticDataCollector(ctx, client, logger, true, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 
 	reg := prometheus.NewRegistry()
 	err = reg.Register(c)
@@ -293,12 +294,12 @@ func TestDiagnosticDataErrors(t *testing.T) {
 			client := tu.TestClient(ctx, port, t)
 
 			logger, hook := logrustest.NewNullLogger()
-			ti := newTopologyInfo(ctx, client, logger)
+			ti := newTopologyInfo(ctx, client, logger, newScrapeCache(client, 0))
 
 			dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 			require.NoError(t, err)
 
-			c := newDiagnosticDataCollector(ctx, client, logger, true, ti, dbBuildInfo)
+			c := newDiagnosticDataCollector(ctx, client, logger, true, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 
 			reg := prometheus.NewRegistry()
 			err = reg.Register(c)
@@ -336,7 +337,7 @@ func TestContextTimeout(t *testing.T) {
 	client := tu.DefaultTestClient(ctx, t)
 
 	logger := logrus.New()
-	ti := newTopologyInfo(ctx, client, logger)
+	ti := newTopologyInfo(ctx, client, logger, newScrapeCache(client, 0))
 
 	dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 	require.NoError(t, err)
@@ -351,7 +352,7 @@ func TestContextTimeout(t *testing.T) {
 	cctx, ccancel := context.WithCancel(context.Background())
 	ccancel()
 
-	c := newDiagnosticDataCollector(cctx, client, logger, true, ti, dbBuildInfo)
+	c := newDiagnosticDataCollector(cctx, client, logger, true, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 	// it should not panic
 	helpers.CollectMetrics(c)
 }
@@ -439,7 +440,7 @@ func TestDisconnectedDiagnosticDataCollector(t *testing.T) {
 	dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 	require.Error(t, err)
 
-	c := newDiagnosticDataCollector(ctx, client, logger, true, ti, dbBuildInfo)
+	c := newDiagnosticDataCollector(ctx, client, logger, true, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
@@ -457,3 +458,451 @@ func TestDisconnectedDiagnosticDataCollector(t *testing.T) {
 	err = testutil.CollectAndCompare(c, expected, filter...)
 	assert.NoError(t, err)
 }
+
+func TestWiredTigerEvictionMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"wiredTiger": bson.M{
+				"cache": bson.M{
+					"eviction worker thread evicting pages": int64(1200),
+					"pages evicted by application threads":  int32(15),
+					"bytes currently in the cache":          int64(4096),
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_wiredtiger_cache_application_threads_evicting_pages_total Pages evicted from the WiredTiger cache by application threads
+	# TYPE mongodb_wiredtiger_cache_application_threads_evicting_pages_total counter
+	mongodb_wiredtiger_cache_application_threads_evicting_pages_total{rs_nm="rs"} 15
+	# HELP mongodb_wiredtiger_cache_eviction_worker_pages_evicted_total Pages evicted from the WiredTiger cache by the eviction worker threads
+	# TYPE mongodb_wiredtiger_cache_eviction_worker_pages_evicted_total counter
+	mongodb_wiredtiger_cache_eviction_worker_pages_evicted_total{rs_nm="rs"} 1200` + "\n")
+
+	metrics := wiredTigerEvictionMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Other storage engines don't have the wiredTiger section.
+	assert.Empty(t, wiredTigerEvictionMetrics(bson.M{"serverStatus": bson.M{"storageEngine": bson.M{"name": "inMemory"}}}, nil))
+}
+
+func TestWiredTigerCacheFillMetrics(t *testing.T) {
+	t.Parallel()
+
+	cache := func(used, maxBytes interface{}) bson.M {
+		return bson.M{
+			"serverStatus": bson.M{
+				"wiredTiger": bson.M{
+					"cache": bson.M{
+						"bytes currently in the cache": used,
+						"maximum bytes configured":     maxBytes,
+					},
+				},
+			},
+		}
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_wiredtiger_cache_fill_ratio Ratio of the WiredTiger cache in use, bytes currently in the cache over the maximum bytes configured
+	# TYPE mongodb_wiredtiger_cache_fill_ratio gauge
+	mongodb_wiredtiger_cache_fill_ratio{rs_nm="rs"} 0.75` + "\n")
+
+	metrics := wiredTigerCacheFillMetrics(cache(int64(805306368), float64(1073741824)), map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// No cache size, no division by zero.
+	assert.Empty(t, wiredTigerCacheFillMetrics(cache(int64(1024), int64(0)), nil))
+	assert.Empty(t, wiredTigerCacheFillMetrics(cache(int64(1024), nil), nil))
+	// Not WiredTiger.
+	assert.Empty(t, wiredTigerCacheFillMetrics(bson.M{"serverStatus": bson.M{}}, nil))
+}
+
+func TestCursorMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"metrics": bson.M{
+				"cursor": bson.M{
+					"timedOut": int64(7),
+					"open": bson.M{
+						"noTimeout": int64(2),
+						"pinned":    int64(1),
+						"total":     int64(5),
+					},
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_metrics_cursor_open_no_timeout Open cursors with the noCursorTimeout option, they are never closed by the server
+	# TYPE mongodb_metrics_cursor_open_no_timeout gauge
+	mongodb_metrics_cursor_open_no_timeout{rs_nm="rs"} 2
+	# HELP mongodb_metrics_cursor_timed_out_total Cursors closed by the server because they were idle for longer than the cursor timeout
+	# TYPE mongodb_metrics_cursor_timed_out_total counter
+	mongodb_metrics_cursor_timed_out_total{rs_nm="rs"} 7` + "\n")
+
+	metrics := cursorMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Only the reported values are returned.
+	metrics = cursorMetrics(bson.M{"serverStatus": bson.M{"metrics": bson.M{"cursor": bson.M{"timedOut": int32(1)}}}}, nil)
+	assert.Equal(t, []string{"mongodb_metrics_cursor_timed_out_total"}, metricNames(metrics))
+
+	assert.Empty(t, cursorMetrics(bson.M{"serverStatus": bson.M{}}, nil))
+}
+
+func TestWiredTigerMaintenanceMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"wiredTiger": bson.M{
+				"reconciliation": bson.M{
+					"page reconciliation calls":              int64(4200),
+					"page reconciliation calls for eviction": int64(310),
+					"split bytes currently awaiting free":    int64(0),
+				},
+				"session": bson.M{
+					"open session count":             int32(22),
+					"table compact running":          int32(1),
+					"table compact successful calls": int32(5),
+					"table compact failed calls":     int32(2),
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_wiredtiger_compact_failed_total Compact operations on WiredTiger tables which failed
+	# TYPE mongodb_wiredtiger_compact_failed_total counter
+	mongodb_wiredtiger_compact_failed_total{rs_nm="rs"} 2
+	# HELP mongodb_wiredtiger_compact_running Compact operations running on WiredTiger tables
+	# TYPE mongodb_wiredtiger_compact_running gauge
+	mongodb_wiredtiger_compact_running{rs_nm="rs"} 1
+	# HELP mongodb_wiredtiger_compact_successful_total Compact operations on WiredTiger tables which succeeded
+	# TYPE mongodb_wiredtiger_compact_successful_total counter
+	mongodb_wiredtiger_compact_successful_total{rs_nm="rs"} 5
+	# HELP mongodb_wiredtiger_reconciliation_eviction_pages_total Pages reconciled by WiredTiger to evict them from the cache
+	# TYPE mongodb_wiredtiger_reconciliation_eviction_pages_total counter
+	mongodb_wiredtiger_reconciliation_eviction_pages_total{rs_nm="rs"} 310
+	# HELP mongodb_wiredtiger_reconciliation_pages_total Pages reconciled by WiredTiger, written from the cache to their on disk format
+	# TYPE mongodb_wiredtiger_reconciliation_pages_total counter
+	mongodb_wiredtiger_reconciliation_pages_total{rs_nm="rs"} 4200` + "\n")
+
+	metrics := wiredTigerMaintenanceMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Older versions have no compact stats in the session section.
+	metrics = wiredTigerMaintenanceMetrics(bson.M{
+		"serverStatus": bson.M{
+			"wiredTiger": bson.M{"reconciliation": bson.M{"page reconciliation calls": int64(7)}},
+		},
+	}, nil)
+	assert.Equal(t, []string{"mongodb_wiredtiger_reconciliation_pages_total"}, metricNames(metrics))
+
+	assert.Empty(t, wiredTigerMaintenanceMetrics(bson.M{
+		"serverStatus": bson.M{"storageEngine": bson.M{"name": "inMemory"}},
+	}, nil))
+}
+
+func TestGetLastErrorMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"metrics": bson.M{
+				"getLastError": bson.M{
+					"wtime":     bson.M{"num": int32(120), "totalMillis": int64(3450)},
+					"wtimeouts": int64(3),
+					"default":   bson.M{"unsatisfiable": int64(0), "wtimeouts": int64(1)},
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_metrics_get_last_error_wtime_total_millis Time spent waiting for the write concern of the writes, in milliseconds
+	# TYPE mongodb_metrics_get_last_error_wtime_total_millis counter
+	mongodb_metrics_get_last_error_wtime_total_millis{rs_nm="rs"} 3450
+	# HELP mongodb_metrics_get_last_error_wtimeouts_total Writes whose write concern timed out because of the wtimeout
+	# TYPE mongodb_metrics_get_last_error_wtimeouts_total counter
+	mongodb_metrics_get_last_error_wtimeouts_total{rs_nm="rs"} 3` + "\n")
+
+	metrics := getLastErrorMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	assert.Empty(t, getLastErrorMetrics(bson.M{"serverStatus": bson.M{"metrics": bson.M{}}}, nil))
+}
+
+func TestOpWriteConcernMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"opWriteConcernCounters": bson.M{
+				"insert": bson.M{
+					"wmajority": int64(80),
+					"wnum":      bson.M{"0": int64(2), "1": int64(15)},
+					"wtag":      bson.M{"dc-east": int64(4)},
+					"none":      int64(10),
+					"noneInfo":  bson.M{"CWWC": bson.M{"wmajority": int64(0)}, "implicitDefault": bson.M{"wmajority": int64(10)}},
+				},
+				"update": bson.M{
+					"wmajority": int64(30),
+					"wnum":      bson.M{},
+					"wtag":      bson.M{},
+					"none":      int64(0),
+				},
+				"delete": bson.M{
+					"wmajority": int64(5),
+					"wnum":      bson.M{"1": int64(1)},
+					"wtag":      bson.M{},
+					"none":      int64(2),
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_op_write_concern_total Number of write operations by write concern
+	# TYPE mongodb_op_write_concern_total counter
+	mongodb_op_write_concern_total{op="delete",rs_nm="rs",wc="1"} 1
+	mongodb_op_write_concern_total{op="delete",rs_nm="rs",wc="majority"} 5
+	mongodb_op_write_concern_total{op="delete",rs_nm="rs",wc="none"} 2
+	mongodb_op_write_concern_total{op="insert",rs_nm="rs",wc="0"} 2
+	mongodb_op_write_concern_total{op="insert",rs_nm="rs",wc="1"} 15
+	mongodb_op_write_concern_total{op="insert",rs_nm="rs",wc="dc-east"} 4
+	mongodb_op_write_concern_total{op="insert",rs_nm="rs",wc="majority"} 80
+	mongodb_op_write_concern_total{op="insert",rs_nm="rs",wc="none"} 10
+	mongodb_op_write_concern_total{op="update",rs_nm="rs",wc="majority"} 30
+	mongodb_op_write_concern_total{op="update",rs_nm="rs",wc="none"} 0` + "\n")
+
+	metrics := opWriteConcernMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Before MongoDB 5.0 or without reportOpWriteConcernCountersInServerStatus.
+	assert.Empty(t, opWriteConcernMetrics(bson.M{"serverStatus": bson.M{"opcounters": bson.M{}}}, nil))
+}
+
+func TestOplogTruncationMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"storageEngine": bson.M{"name": "wiredTiger"},
+			"wiredTiger":    bson.M{"cache": bson.M{}},
+			"oplogTruncation": bson.M{
+				"totalTimeProcessingMicros": int64(1520),
+				"processingMethod":          "sampling",
+				"totalTimeTruncatingMicros": int64(98000),
+				"truncateCount":             int64(12),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_oplog_truncation_time_truncating_micros_total Cumulative time spent truncating the oplog, in microseconds
+	# TYPE mongodb_oplog_truncation_time_truncating_micros_total counter
+	mongodb_oplog_truncation_time_truncating_micros_total{rs_nm="rs"} 98000
+	# HELP mongodb_oplog_truncation_total_time_processing_micros Time spent scanning or sampling the oplog at startup to find the truncation points, in microseconds
+	# TYPE mongodb_oplog_truncation_total_time_processing_micros gauge
+	mongodb_oplog_truncation_total_time_processing_micros{rs_nm="rs"} 1520
+	# HELP mongodb_oplog_truncations_total Number of oplog truncations
+	# TYPE mongodb_oplog_truncations_total counter
+	mongodb_oplog_truncations_total{rs_nm="rs"} 12` + "\n")
+
+	metrics := oplogTruncationMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Older versions report the stats in the WiredTiger section.
+	metrics = oplogTruncationMetrics(bson.M{
+		"serverStatus": bson.M{
+			"wiredTiger": bson.M{"oplog": bson.M{"truncateCount": int32(3)}},
+		},
+	}, nil)
+	assert.Equal(t, []string{"mongodb_oplog_truncations_total"}, metricNames(metrics))
+
+	// Other storage engines have no oplog truncation stats.
+	assert.Empty(t, oplogTruncationMetrics(bson.M{
+		"serverStatus": bson.M{
+			"storageEngine":   bson.M{"name": "inMemory"},
+			"oplogTruncation": bson.M{"truncateCount": int64(1)},
+		},
+	}, nil))
+}
+
+func TestDiagnosticDataFallback(t *testing.T) {
+	t.Parallel()
+
+	logger := logrus.NewEntry(logrus.New())
+	newCache := func(diagnosticDataErr error) *scrapeCache {
+		return &scrapeCache{
+			run: func(_ context.Context, name string) (bson.M, error) {
+				switch name {
+				case "getDiagnosticData":
+					return nil, diagnosticDataErr
+				case "serverStatus":
+					return bson.M{"uptime": int64(3600), "connections": bson.M{"current": int32(12)}}, nil
+				case "getCmdLineOpts":
+					return bson.M{"argv": bson.A{"mongod"}}, nil
+				default:
+					return nil, mongo.CommandError{Code: 76, Name: "NoReplicationEnabled"}
+				}
+			},
+			results: make(map[string]*cachedCommand),
+		}
+	}
+
+	unauthorized := mongo.CommandError{Code: 13, Name: "Unauthorized"}
+
+	// Without the fallback the error is returned as is.
+	_, err := diagnosticData(context.Background(), newCache(unauthorized), false, logger)
+	assert.Equal(t, unauthorized, err)
+
+	for _, cmdErr := range []error{unauthorized, mongo.CommandError{Code: 59, Name: "CommandNotFound"}} {
+		m, err := diagnosticData(context.Background(), newCache(cmdErr), true, logger)
+		require.NoError(t, err)
+
+		data, ok := m["data"].(bson.M)
+		require.True(t, ok)
+		assert.NotContains(t, data, "replSetGetStatus")
+
+		names := metricNames(makeMetrics("", data, nil, false))
+		assert.Contains(t, names, "mongodb_ss_uptime")
+		assert.Contains(t, names, "mongodb_ss_connections")
+	}
+
+	// Other errors, e.g. a timeout, are not hidden by the fallback.
+	_, err = diagnosticData(context.Background(), newCache(context.DeadlineExceeded), true, logger)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDiagnosticDataSections(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"start":                bson.M{},
+		"serverStatus":         bson.M{"uptime": int64(10), "connections": bson.M{"current": int32(5)}},
+		"replSetGetStatus":     bson.M{"myState": int32(1)},
+		"local.oplog.rs.stats": bson.M{"count": int64(1000)},
+	}
+
+	assert.Equal(t, m, diagnosticDataSections(m, nil))
+
+	filtered := diagnosticDataSections(m, []string{"serverStatus", "local.oplog.rs.stats", "hostInfo"})
+	assert.Equal(t, bson.M{
+		"serverStatus":         m["serverStatus"],
+		"local.oplog.rs.stats": m["local.oplog.rs.stats"],
+	}, filtered)
+
+	// Only the metrics of the selected sections are made.
+	names := metricNames(makeMetrics("", filtered, nil, false))
+	assert.NotContains(t, names, "mongodb_rs_myState")
+	assert.Contains(t, names, "mongodb_ss_uptime")
+}
+
+func TestDiagnosticDataDocument(t *testing.T) {
+	t.Parallel()
+
+	want := bson.M{
+		"serverStatus": bson.M{
+			"opcounters": bson.M{"insert": int64(3)},
+			"locks":      bson.A{bson.M{"mode": "IS"}, "global"},
+		},
+	}
+
+	t.Run("bson.M", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := diagnosticDataDocument(bson.M{"data": want})
+		assert.NoError(t, err)
+		assert.Equal(t, want, data)
+	})
+
+	t.Run("bson.D", func(t *testing.T) {
+		t.Parallel()
+
+		m := bson.M{
+			"data": bson.D{
+				{Key: "serverStatus", Value: bson.D{
+					{Key: "opcounters", Value: bson.D{{Key: "insert", Value: int64(3)}}},
+					{Key: "locks", Value: bson.A{bson.D{{Key: "mode", Value: "IS"}}, "global"}},
+				}},
+			},
+		}
+
+		data, err := diagnosticDataDocument(m)
+		assert.NoError(t, err)
+		assert.Equal(t, want, data)
+		assert.Equal(t, int64(3), walkTo(data, []string{"serverStatus", "opcounters", "insert"}))
+	})
+
+	t.Run("unexpected type", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := diagnosticDataDocument(bson.M{"data": "ok"})
+		assert.ErrorIs(t, err, errUnexpectedDataType)
+
+		_, err = diagnosticDataDocument(nil)
+		assert.EqualError(t, err, "response is empty")
+	})
+}
+
+func TestDiagnosticDataCommandError(t *testing.T) {
+	t.Parallel()
+
+	logger, hook := logrustest.NewNullLogger()
+	cache := &scrapeCache{
+		run: func(context.Context, string) (bson.M, error) {
+			return nil, mongo.CommandError{Code: 11600, Name: "InterruptedAtShutdown"}
+		},
+		results: make(map[string]*cachedCommand),
+	}
+	c := newDiagnosticDataCollector(context.Background(), nil, logger, false, false, 0, nil, labelsGetterMock{}, buildInfo{}, cache)
+
+	ch := make(chan prometheus.Metric, 100)
+	c.collect(ch)
+	close(ch)
+
+	// The failed command is logged, its missing result is not decoded.
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+		assert.NotContains(t, entry.Message, "response is empty")
+		assert.NotContains(t, entry.Message, "cannot decode")
+	}
+	assert.Contains(t, strings.Join(messages, "\n"), "failed to run command: getDiagnosticData")
+}
+
+func TestDiagnosticDataUnauthorizedCollectorLabel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	logger, _ := logrustest.NewNullLogger()
+	cache := &scrapeCache{
+		run: func(context.Context, string) (bson.M, error) {
+			return nil, mongo.CommandError{Code: 13, Name: "Unauthorized"}
+		},
+		results: make(map[string]*cachedCommand),
+	}
+	c := newDiagnosticDataCollector(ctx, client, logger, false, false, 0, nil, labelsGetterMock{}, buildInfo{}, cache)
+
+	// The unauthorized command can be joined with the status of the collector.
+	expected := strings.NewReader(`
+# HELP mongodb_collector_unauthorized Whether the last scrape of the collector failed to run the command for missing privileges
+# TYPE mongodb_collector_unauthorized gauge
+mongodb_collector_unauthorized{collector="diagnosticdata",command="getDiagnosticData"} 1
+# HELP mongodb_collector_success Whether the last scrape of the collector succeeded, without logging an error
+# TYPE mongodb_collector_success gauge
+mongodb_collector_success{collector="diagnosticdata"} 0` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(c, expected, "mongodb_collector_unauthorized", "mongodb_collector_success"))
+}
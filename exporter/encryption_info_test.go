@@ -54,7 +54,7 @@ func TestGetEncryptionInfo(t *testing.T) {
 	dbBuildInfo, err := retrieveMongoDBBuildInfo(ctx, client, logger.WithField("component", "test"))
 	require.NoError(t, err)
 
-	c := newDiagnosticDataCollector(ctx, client, logger, true, ti, dbBuildInfo)
+	c := newDiagnosticDataCollector(ctx, client, logger, true, false, 0, nil, ti, dbBuildInfo, newScrapeCache(client, 0))
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(fmt.Sprintf(`
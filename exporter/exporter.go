@@ -17,18 +17,30 @@
 package exporter
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"github.com/percona/mongodb_exporter/exporter/dsn_fix"
 )
@@ -37,28 +49,166 @@ import (
 type Exporter struct {
 	client                *mongo.Client
 	clientMu              sync.Mutex
+	closed                bool
 	logger                *logrus.Logger
 	opts                  *Opts
 	lock                  *sync.Mutex
 	totalCollectionsCount int
+
+	// Monitors the connection pool of the global client, nil if there is none.
+	poolMonitor *poolMonitor
+
+	// Limits the concurrent scrapes to Opts.MaxConcurrentScrapes, nil without limit.
+	scrapeSlots chan struct{}
+	// mongodb_exporter_scrapes_in_flight, nil for the exporters built without New.
+	scrapesInFlight prometheus.Gauge
+
+	// Serves the cached metrics when Opts.MaxStaleness or Opts.MetricsCacheTTL is set, nil otherwise.
+	metricsCache *metricsCache
+
+	// Runs the collectors with a sample rate every N scrapes, nil if there are none.
+	sampler *collectorSampler
+
+	// Epochs of the sharded collections, to count their changes across scrapes. Nil for the
+	// exporters built without New.
+	shardedCollectionEpochs *epochTracker
+
+	// Scrape errors and last successful scrapes of the collectors, nil for the exporters built
+	// without New.
+	collectorStatus *collectorStatus
+
+	// mongodb_exporter_config_info, built from the options in New.
+	configInfo prometheus.Gauge
+
+	// Collectors added by the packages embedding the exporter, see RegisterCustomCollector.
+	customCollectorsMu sync.Mutex
+	customCollectors   []CustomCollectorFactory
+
+	// Warns once that the collections limit is exceeded, instead of on every scrape.
+	collStatsLimitWarning sync.Once
+
+	// connectFn connects to MongoDB. It is connect, replaced in tests.
+	connectFn func(ctx context.Context, opts *Opts, poolMonitor *event.PoolMonitor) (*mongo.Client, error)
 }
 
 // Opts holds new exporter options.
 type Opts struct {
 	// Only get stats for the collections matching this list of namespaces.
 	// Example: db1.col1,db.col1
-	CollStatsNamespaces    []string
-	CollStatsLimit         int
+	CollStatsNamespaces []string
+	CollStatsLimit      int
+
+	// Export the WiredTiger cache and block manager stats of the CollStatsNamespaces as typed
+	// metrics, e.g. mongodb_collstats_wiredtiger_cache_bytes. The discovered collections don't
+	// have them. This doesn't limit the raw mongodb_collstats_storageStats_wiredTiger_* metrics,
+	// which are exported for all the collections.
+	CollStatsWiredTiger bool
+
+	// Databases to get dbStats for, as regular expressions matching the whole name. Empty means
+	// all the databases.
+	DBStatsDatabases []string
+	// Databases to skip in dbStats among the DBStatsDatabases ones, as regular expressions
+	// matching the whole name. Nil means the system databases: admin, config and local.
+	DBStatsExcludeDatabases []string
+
 	CompatibleMode         bool
-	DirectConnect          bool
 	ConnectTimeoutMS       int
 	DisableDefaultRegistry bool
 	DiscoveringMode        bool
 	GlobalConnPool         bool
 	ProfileTimeTS          int
+	ProfileWindow          time.Duration
 	TimeoutOffset          int
 	CurrentOpSlowTime      string
 
+	// Assemble the diagnostic data from serverStatus, replSetGetStatus and getCmdLineOpts when
+	// getDiagnosticData is not authorized or not found, as on some managed services.
+	DiagnosticDataFallback bool
+
+	// Top-level sections of getDiagnosticData exported as generic metrics, e.g. serverStatus or
+	// local.oplog.rs.stats, to limit the cardinality. Empty means all of them. The metrics
+	// computed from the sections, such as the locks or the WiredTiger eviction, are kept.
+	DiagnosticDataSections []string
+
+	// Time window of the sharding changelog events reported on mongos in compatible mode.
+	// 0 uses the 10 minutes of mongodb_mongos_sharding_changelog_10min_total.
+	ShardingChangelogWindow time.Duration
+
+	// Skip the views in CollStatsNamespaces and IndexStatsCollections, reporting them in
+	// mongodb_collection_is_view, instead of failing the collstats and indexstats collectors.
+	IncludeViews bool
+
+	// Number of times a command failing with a transient error (e.g. during an election)
+	// is retried within the scrape deadline.
+	CommandRetries int
+
+	// Number of times a failed connection is retried in a scrape when the global connection
+	// pool is not used. The wait between attempts starts at ConnectRetryInterval and doubles.
+	ConnectRetries       int
+	ConnectRetryInterval time.Duration
+
+	// Read preference used for the monitoring connection: primary, primaryPreferred,
+	// secondary, secondaryPreferred or nearest. Empty means the driver default.
+	ReadPreference string
+
+	// Wire protocol compressors, in order of preference: zstd, snappy or zlib. They are only
+	// used if the server supports them too. ZstdLevel zero keeps the driver default.
+	Compressors []string
+	ZstdLevel   int
+
+	// Size limits of the connection pool, zero keeps the driver defaults (at most 100
+	// connections). With GlobalConnPool disabled every scrape uses its own client, so a
+	// MaxPoolSize of 1 is usually enough.
+	MaxPoolSize uint64
+	MinPoolSize uint64
+
+	// Authentication database and mechanism properties (e.g. AWS_SESSION_TOKEN or
+	// SERVICE_NAME) of the credential of the URI. They override the ones set in the URI.
+	AuthSource              string
+	AuthMechanismProperties map[string]string
+
+	// User and file with the password of the credential, e.g. a mounted container secret, to
+	// not have the password in the URI. The file is read for every new client, so a rotated
+	// password is used without restarting when GlobalConnPool is disabled. Username overrides
+	// the user of the URI.
+	Username     string
+	PasswordFile string
+
+	// Suffix appended to the mongodb_exporter application name of the connections, e.g. the
+	// host or pod name, to tell the exporter instances apart in currentOp and the server logs.
+	AppNameSuffix string
+
+	// TLS configs by target host. The config of the first target matching a host of the URI
+	// replaces the TLS options of the URI. Nil uses the URI options for every target.
+	TargetTLS *TargetTLSConfigs
+
+	// Causal consistency of the session used by the collectors in a scrape. Nil keeps the
+	// driver default of running the commands without an explicit session.
+	CausalConsistency *bool
+
+	// Connect directly to the host of the URI. False lets the driver discover the replica set
+	// and route the commands by read preference, e.g. to the primary whatever the seed host.
+	// Nil connects directly.
+	DirectConnect *bool
+
+	// Serve the OpenMetrics format to the scrapers negotiating it, with a target_info metric
+	// carrying the topology labels. Otherwise the Prometheus text format is always used.
+	EnableOpenMetrics bool
+
+	// Maximum age of the metrics served from the cache. When set, a scrape returns the metrics
+	// of a previous one and refreshes them in the background. Zero disables the cache.
+	MaxStaleness time.Duration
+
+	// Age below which the cached metrics are served without refreshing them, so scrapes more
+	// frequent than the TTL don't run the collectors again. It also enables the cache, with
+	// a maximum staleness of the TTL if MaxStaleness is not set. Zero disables it.
+	MetricsCacheTTL time.Duration
+
+	// Maximum number of scrapes served at the same time by Handler, the others get a 503
+	// response. Without the global connection pool, each scrape opens its own connection.
+	// Zero means no limit.
+	MaxConcurrentScrapes int
+
 	CollectAll               bool
 	EnableDBStats            bool
 	EnableDBStatsFreeStorage bool
@@ -70,8 +220,26 @@ type Opts struct {
 	EnableIndexStats         bool
 	EnableCollStats          bool
 	EnableProfile            bool
+	EnableProfileStats       bool
 	EnableShards             bool
 	EnableFCV                bool // Feature Compatibility Version.
+	EnableShardingStatistics bool
+	EnableClusterHealth      bool
+	EnableDatabaseAccess     bool
+	EnableParameters         bool
+	EnablePreImages          bool
+	EnableParameterMetrics   bool
+	EnableQuerySampling      bool
+	EnableResharding         bool
+	EnableFsyncLockMetrics   bool
+
+	// Server parameters exported by the parametermetrics collector. Without names, the storage
+	// engine tickets, the checkpoint interval and the TTL monitor interval are exported.
+	ParameterMetricsNames []string
+
+	// Replication lag limits for the degraded and critical cluster health values.
+	ClusterHealthLagDegraded time.Duration
+	ClusterHealthLagCritical time.Duration
 
 	EnableOverrideDescendingIndex bool
 
@@ -81,6 +249,31 @@ type Opts struct {
 	IndexStatsCollections []string
 	Logger                *logrus.Logger
 
+	// Log level overrides by collector name (the names used in collect[]), e.g.
+	// {"collstats": "debug"}. Collectors not listed log at the Logger level.
+	CollectorLogLevels map[string]string
+
+	// Sample rates by collector name (the names used in collect[]): a collector with a rate
+	// of N runs every N scrapes and its last metrics are served in between.
+	CollectorSampleRates map[string]int
+
+	// Timeouts by collector name (the names used in collect[]), e.g. {"diagnosticdata": 5 *
+	// time.Second}, counted from the start of the scrape. A collector reaching its timeout stops
+	// without delaying the others. The scrape timeout applies to all the collectors.
+	CollectorTimeouts map[string]time.Duration
+
+	// Collector names (the names used in collect[]) to run first in a scrape, in this order, so
+	// their metrics are collected even if the scrape deadline cuts off the others. The general
+	// collector, reporting mongodb_up, always runs before them.
+	CollectorPriority []string
+
+	// Labels added to every metric of the exporter, e.g. {"environment": "prod"}. They cannot
+	// be named as the labels set by the exporter, e.g. the topology labels, collector, database
+	// or collection.
+	ConstantLabels map[string]string
+
+	// MongoDB connection URI. If empty, New reads it from the MONGODB_URI environment variable,
+	// adding the MONGODB_USER and MONGODB_PASSWORD credentials if it has none.
 	URI      string
 	NodeName string
 }
@@ -88,6 +281,8 @@ type Opts struct {
 var (
 	errCannotHandleType   = fmt.Errorf("don't know how to handle data type")
 	errUnexpectedDataType = fmt.Errorf("unexpected data type")
+	errExporterClosed     = fmt.Errorf("exporter is closed")
+	errNoURI              = fmt.Errorf("no MongoDB URI: set Opts.URI or the MONGODB_URI environment variable")
 )
 
 const (
@@ -95,15 +290,80 @@ const (
 )
 
 // New connects to the database and returns a new Exporter instance.
-func New(opts *Opts) *Exporter {
+func New(opts *Opts) (*Exporter, error) {
 	if opts == nil {
 		opts = new(Opts)
 	}
 
+	if _, err := readPreference(opts.ReadPreference); err != nil {
+		return nil, err
+	}
+
+	if err := checkCompressors(opts.Compressors); err != nil {
+		return nil, err
+	}
+
+	if opts.MaxPoolSize != 0 && opts.MinPoolSize > opts.MaxPoolSize {
+		return nil, fmt.Errorf("min pool size %d is greater than the max pool size %d", opts.MinPoolSize, opts.MaxPoolSize)
+	}
+
+	for name, level := range opts.CollectorLogLevels {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			return nil, fmt.Errorf("invalid log level for collector %q: %w", name, err)
+		}
+	}
+
+	for name, rate := range opts.CollectorSampleRates {
+		if _, ok := requestOptsSetters[name]; !ok {
+			return nil, fmt.Errorf("invalid sample rate for unknown collector %q", name)
+		}
+		if rate < 1 {
+			return nil, fmt.Errorf("invalid sample rate %d for collector %q, it must be at least 1", rate, name)
+		}
+	}
+
+	if err := checkConstantLabels(opts.ConstantLabels); err != nil {
+		return nil, err
+	}
+
+	for name, timeout := range opts.CollectorTimeouts {
+		if _, ok := requestOptsSetters[name]; !ok {
+			return nil, fmt.Errorf("invalid timeout for unknown collector %q", name)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %s for collector %q, it must be positive", timeout, name)
+		}
+	}
+
+	for _, name := range opts.CollectorPriority {
+		if _, ok := requestOptsSetters[name]; !ok {
+			return nil, fmt.Errorf("invalid priority for unknown collector %q", name)
+		}
+	}
+
+	if opts.MaxConcurrentScrapes < 0 {
+		return nil, fmt.Errorf("invalid max concurrent scrapes %d, it must be positive or 0", opts.MaxConcurrentScrapes)
+	}
+
+	if opts.MetricsCacheTTL < 0 || (opts.MaxStaleness > 0 && opts.MetricsCacheTTL > opts.MaxStaleness) {
+		return nil, fmt.Errorf("invalid metrics cache TTL %s, it must be between 0 and the max staleness %s", opts.MetricsCacheTTL, opts.MaxStaleness)
+	}
+
+	if opts.URI == "" {
+		opts.URI = uriFromEnv()
+		if opts.URI == "" {
+			return nil, errNoURI
+		}
+	}
+
 	if opts.Logger == nil {
 		opts.Logger = logrus.New()
 	}
 
+	if opts.CollectAll {
+		enableAllCollectors(opts)
+	}
+
 	ctx := context.Background()
 
 	exp := &Exporter{
@@ -111,6 +371,28 @@ func New(opts *Opts) *Exporter {
 		opts:                  opts,
 		lock:                  &sync.Mutex{},
 		totalCollectionsCount: -1, // Not calculated yet. waiting the db connection.
+		connectFn:             connect,
+		configInfo:            newConfigInfo(opts),
+		scrapesInFlight:       newScrapesInFlight(),
+
+		shardedCollectionEpochs: newEpochTracker(),
+		collectorStatus:         newCollectorStatus(),
+	}
+	if opts.MaxConcurrentScrapes > 0 {
+		exp.scrapeSlots = make(chan struct{}, opts.MaxConcurrentScrapes)
+	}
+	if opts.GlobalConnPool {
+		exp.poolMonitor = newPoolMonitor()
+	}
+	if len(opts.CollectorSampleRates) > 0 {
+		exp.sampler = newCollectorSampler(opts.CollectorSampleRates)
+	}
+	if opts.MaxStaleness > 0 || opts.MetricsCacheTTL > 0 {
+		maxStaleness := opts.MaxStaleness
+		if maxStaleness == 0 {
+			maxStaleness = opts.MetricsCacheTTL
+		}
+		exp.metricsCache = newMetricsCache(maxStaleness, opts.MetricsCacheTTL, opts.Logger, exp.gather)
 	}
 	// Try initial connect. Connection will be retried with every scrape.
 	go func() {
@@ -120,7 +402,25 @@ func New(opts *Opts) *Exporter {
 		}
 	}()
 
-	return exp
+	return exp, nil
+}
+
+// uriFromEnv returns the URI of the MONGODB_URI environment variable, with the credentials of
+// MONGODB_USER and MONGODB_PASSWORD if it has none, as the exporter flags read them.
+func uriFromEnv() string {
+	uri := os.Getenv("MONGODB_URI")
+	user, password := os.Getenv("MONGODB_USER"), os.Getenv("MONGODB_PASSWORD")
+	if uri == "" || user == "" || password == "" {
+		return uri
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.User != nil {
+		return uri
+	}
+	parsed.User = url.UserPassword(user, password)
+
+	return parsed.String()
 }
 
 func (e *Exporter) getTotalCollectionsCount() int {
@@ -130,8 +430,51 @@ func (e *Exporter) getTotalCollectionsCount() int {
 	return e.totalCollectionsCount
 }
 
-func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topologyInfo labelsGetter, requestOpts Opts) *prometheus.Registry {
+// collectorLogger returns the logger for the named collector. If there is a log level
+// override for it, the returned logger shares the output, formatter and hooks of the
+// exporter logger but has its own level.
+func (e *Exporter) collectorLogger(name string) *logrus.Logger {
+	level, ok := e.opts.CollectorLogLevels[name]
+	if !ok {
+		return e.opts.Logger
+	}
+
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		e.logger.Warnf("Invalid log level %q for collector %s: %s", level, name, err)
+		return e.opts.Logger
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(e.opts.Logger.Out)
+	logger.SetFormatter(e.opts.Logger.Formatter)
+	logger.ReplaceHooks(e.opts.Logger.Hooks)
+	logger.SetReportCaller(e.opts.Logger.ReportCaller)
+	logger.ExitFunc = e.opts.Logger.ExitFunc
+	logger.SetLevel(lvl)
+
+	return logger
+}
+
+// makeRegistry runs the collectors enabled in the request options. cache must be the scrape
+// cache used to load topologyInfo, so the commands it ran are not run again.
+func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topologyInfo labelsGetter, cache *scrapeCache,
+	requestOpts Opts,
+) *prometheus.Registry {
 	registry := prometheus.NewRegistry()
+	// The constant labels are added to every metric of the scrape.
+	registerer := prometheus.WrapRegistererWith(e.opts.ConstantLabels, registry)
+
+	// Concurrent scrapes share e.opts, so the adjustments below are made on a copy.
+	opts := *e.opts
+
+	ctx = withCollectorStatus(ctx, e.collectorStatus)
+
+	// The collectors scrape while they are registered, one after the other, so they can share
+	// the session. pbm connects with its own client and cannot use it.
+	pbmCtx := ctx
+	ctx, endSession := e.sessionContext(ctx, client)
+	defer endSession()
 
 	nodeType, err := getNodeType(ctx, client)
 	if err != nil {
@@ -143,128 +486,294 @@ func (e *Exporter) makeRegistry(ctx context.Context, client *mongo.Client, topol
 		e.logger.Warnf("Registry - Cannot get MongoDB buildInfo: %s", err)
 	}
 
-	gc := newGeneralCollector(ctx, client, nodeType, e.opts.Logger)
-	registry.MustRegister(gc)
+	// The collectors have run when the registry is returned.
+	timeouts := newCollectorTimeouts(opts.CollectorTimeouts)
+	defer timeouts.cancel()
+
+	// The collectors run when the queue is registered, in the order of CollectorPriority.
+	queue := newCollectorQueue(opts.CollectorPriority)
+
+	gc := newGeneralCollector(ctx, client, nodeType, e.collectorLogger("general"))
+	registerer.MustRegister(gc)
+
+	if opts.EnableOpenMetrics {
+		registerer.MustRegister(newTargetInfo(topologyInfo.baseLabels()))
+	}
 
 	// Enable collectors like collstats and indexstats depending on the number of collections
 	// present in the database.
 	limitsOk := false
-	if e.opts.CollStatsLimit <= 0 || // Unlimited
-		e.getTotalCollectionsCount() <= e.opts.CollStatsLimit {
+	if opts.CollStatsLimit <= 0 || // Unlimited
+		e.getTotalCollectionsCount() <= opts.CollStatsLimit {
 		limitsOk = true
 	}
 
-	if e.opts.CollectAll {
-		if len(e.opts.CollStatsNamespaces) == 0 {
-			e.opts.DiscoveringMode = true
-		}
-		e.opts.EnableDiagnosticData = true
-		e.opts.EnableDBStats = true
-		e.opts.EnableDBStatsFreeStorage = true
-		e.opts.EnableCollStats = true
-		e.opts.EnableTopMetrics = true
-		e.opts.EnableReplicasetStatus = true
-		e.opts.EnableReplicasetConfig = true
-		e.opts.EnableIndexStats = true
-		e.opts.EnableCurrentopMetrics = true
-		e.opts.EnableProfile = true
-		e.opts.EnableShards = true
-		e.opts.EnableFCV = true
-		e.opts.EnablePBMMetrics = true
+	if opts.CollectAll {
+		enableAllCollectors(&opts)
 	}
 
 	// arbiter only have isMaster privileges
 	if nodeType == typeArbiter {
-		e.opts.EnableDBStats = false
-		e.opts.EnableDBStatsFreeStorage = false
-		e.opts.EnableCollStats = false
-		e.opts.EnableTopMetrics = false
-		e.opts.EnableReplicasetStatus = false
-		e.opts.EnableIndexStats = false
-		e.opts.EnableCurrentopMetrics = false
-		e.opts.EnableProfile = false
-		e.opts.EnableShards = false
-		e.opts.EnableFCV = false
-		e.opts.EnablePBMMetrics = false
+		opts.EnableDBStats = false
+		opts.EnableDBStatsFreeStorage = false
+		opts.EnableCollStats = false
+		opts.EnableTopMetrics = false
+		opts.EnableReplicasetStatus = false
+		opts.EnableIndexStats = false
+		opts.EnableCurrentopMetrics = false
+		opts.EnableProfile = false
+		opts.EnableProfileStats = false
+		opts.EnableShards = false
+		opts.EnableFCV = false
+		opts.EnablePBMMetrics = false
+		opts.EnableShardingStatistics = false
+		opts.EnableClusterHealth = false
+		opts.EnableDatabaseAccess = false
+		opts.EnableParameters = false
+		opts.EnablePreImages = false
+		opts.EnableParameterMetrics = false
+		opts.EnableQuerySampling = false
+		opts.EnableResharding = false
+		opts.EnableFsyncLockMetrics = false
+	}
+
+	if opts.CollStatsLimit > 0 && opts.EnableCollStats && requestOpts.EnableCollStats {
+		if !limitsOk {
+			e.collStatsLimitWarning.Do(func() {
+				e.logger.Warnf("There are %d collections, more than the collections limit of %d: skipping collstats, indexstats, dbstats, topmetrics, currentopmetrics and profile collectors",
+					e.getTotalCollectionsCount(), opts.CollStatsLimit)
+			})
+		}
+		registerer.MustRegister(newCollStatsSkipped(topologyInfo.baseLabels(), !limitsOk))
 	}
 
 	// If we manually set the collection names we want or auto discovery is set.
-	if (len(e.opts.CollStatsNamespaces) > 0 || e.opts.DiscoveringMode) && e.opts.EnableCollStats && limitsOk && requestOpts.EnableCollStats {
-		cc := newCollectionStatsCollector(ctx, client, e.opts.Logger,
-			e.opts.DiscoveringMode,
-			topologyInfo, e.opts.CollStatsNamespaces)
-		registry.MustRegister(cc)
+	if (len(opts.CollStatsNamespaces) > 0 || opts.DiscoveringMode) && opts.EnableCollStats && limitsOk && requestOpts.EnableCollStats {
+		cc := newCollectionStatsCollector(timeouts.context(ctx, "collstats"), client, e.collectorLogger("collstats"),
+			opts.DiscoveringMode, opts.IncludeViews,
+			topologyInfo, opts.CollStatsNamespaces, opts.CommandRetries, opts.CollStatsWiredTiger)
+		queue.add("collstats", cc)
 	}
 
 	// If we manually set the collection names we want or auto discovery is set.
-	if (len(e.opts.IndexStatsCollections) > 0 || e.opts.DiscoveringMode) && e.opts.EnableIndexStats && limitsOk && requestOpts.EnableIndexStats {
-		ic := newIndexStatsCollector(ctx, client, e.opts.Logger,
-			e.opts.DiscoveringMode, e.opts.EnableOverrideDescendingIndex,
-			topologyInfo, e.opts.IndexStatsCollections)
-		registry.MustRegister(ic)
+	if (len(opts.IndexStatsCollections) > 0 || opts.DiscoveringMode) && opts.EnableIndexStats && limitsOk && requestOpts.EnableIndexStats {
+		ic := newIndexStatsCollector(timeouts.context(ctx, "indexstats"), client, e.collectorLogger("indexstats"),
+			opts.DiscoveringMode, opts.IncludeViews, opts.EnableOverrideDescendingIndex,
+			topologyInfo, opts.IndexStatsCollections)
+		queue.add("indexstats", ic)
+	}
+
+	if opts.EnableDiagnosticData && requestOpts.EnableDiagnosticData {
+		ddc := newDiagnosticDataCollector(timeouts.context(ctx, "diagnosticdata"), client, e.collectorLogger("diagnosticdata"),
+			opts.CompatibleMode, opts.DiagnosticDataFallback, opts.ShardingChangelogWindow, opts.DiagnosticDataSections,
+			topologyInfo, dbBuildInfo, cache)
+		queue.add("diagnosticdata", ddc)
+	}
+
+	if opts.EnableDBStats && limitsOk && requestOpts.EnableDBStats {
+		dbStatsExclude := opts.DBStatsExcludeDatabases
+		if dbStatsExclude == nil {
+			dbStatsExclude = systemDBs
+		}
+		cc := newDBStatsCollector(timeouts.context(ctx, "dbstats"), client, e.collectorLogger("dbstats"),
+			opts.CompatibleMode, topologyInfo, opts.DBStatsDatabases, dbStatsExclude, opts.EnableDBStatsFreeStorage, opts.CommandRetries)
+		queue.add("dbstats", cc)
 	}
 
-	if e.opts.EnableDiagnosticData && requestOpts.EnableDiagnosticData {
-		ddc := newDiagnosticDataCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, dbBuildInfo)
-		registry.MustRegister(ddc)
+	if opts.EnableCurrentopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableCurrentopMetrics && opts.CurrentOpSlowTime != "" {
+		coc := newCurrentopCollector(timeouts.context(ctx, "currentopmetrics"), client, e.collectorLogger("currentopmetrics"),
+			opts.CompatibleMode, topologyInfo, opts.CurrentOpSlowTime)
+		queue.add("currentopmetrics", coc)
 	}
 
-	if e.opts.EnableDBStats && limitsOk && requestOpts.EnableDBStats {
-		cc := newDBStatsCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, nil, e.opts.EnableDBStatsFreeStorage)
-		registry.MustRegister(cc)
+	if opts.EnableProfile && nodeType != typeMongos && limitsOk && requestOpts.EnableProfile && opts.ProfileTimeTS != 0 {
+		pc := newProfileCollector(timeouts.context(ctx, "profile"), client, e.collectorLogger("profile"),
+			opts.CompatibleMode, topologyInfo, opts.ProfileTimeTS)
+		queue.add("profile", pc)
 	}
 
-	if e.opts.EnableCurrentopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableCurrentopMetrics && e.opts.CurrentOpSlowTime != "" {
-		coc := newCurrentopCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, e.opts.CurrentOpSlowTime)
-		registry.MustRegister(coc)
+	if opts.EnableProfileStats && nodeType != typeMongos && limitsOk && requestOpts.EnableProfileStats && opts.ProfileWindow > 0 {
+		psc := newProfileStatsCollector(timeouts.context(ctx, "profilestats"), client, e.collectorLogger("profilestats"), topologyInfo, opts.ProfileWindow)
+		queue.add("profilestats", psc)
 	}
 
-	if e.opts.EnableProfile && nodeType != typeMongos && limitsOk && requestOpts.EnableProfile && e.opts.ProfileTimeTS != 0 {
-		pc := newProfileCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo, e.opts.ProfileTimeTS)
-		registry.MustRegister(pc)
+	if opts.EnableDatabaseAccess && requestOpts.EnableDatabaseAccess {
+		dac := newDatabaseAccessCollector(timeouts.context(ctx, "dbaccess"), client, e.collectorLogger("dbaccess"), topologyInfo)
+		queue.add("dbaccess", dac)
 	}
 
-	if e.opts.EnableTopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableTopMetrics {
-		tc := newTopCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo)
-		registry.MustRegister(tc)
+	if opts.EnableParameters && requestOpts.EnableParameters {
+		pc := newParametersCollector(timeouts.context(ctx, "parameters"), client, e.collectorLogger("parameters"), topologyInfo)
+		queue.add("parameters", pc)
+	}
+
+	if opts.EnableParameterMetrics && requestOpts.EnableParameterMetrics {
+		pmc := newParameterMetricsCollector(timeouts.context(ctx, "parametermetrics"), client, e.collectorLogger("parametermetrics"), opts.ParameterMetricsNames, topologyInfo)
+		queue.add("parametermetrics", pmc)
+	}
+
+	// The collector skips the versions before MongoDB 7.0, which don't sample the queries.
+	if opts.EnableQuerySampling && requestOpts.EnableQuerySampling {
+		qsc := newQuerySamplingCollector(timeouts.context(ctx, "querysampling"), client, e.collectorLogger("querysampling"), dbBuildInfo, topologyInfo)
+		queue.add("querysampling", qsc)
+	}
+
+	// The pre-images are stored by the replica set members, mongos has no config.system.preimages.
+	if opts.EnablePreImages && nodeType != typeMongos && requestOpts.EnablePreImages {
+		pic := newPreImagesCollector(timeouts.context(ctx, "preimages"), client, e.collectorLogger("preimages"), topologyInfo)
+		queue.add("preimages", pic)
+	}
+
+	// mongos cannot be fsync locked.
+	if opts.EnableFsyncLockMetrics && nodeType != typeMongos && requestOpts.EnableFsyncLockMetrics {
+		flc := newFsyncLockCollector(timeouts.context(ctx, "fsynclock"), client, e.collectorLogger("fsynclock"), topologyInfo)
+		queue.add("fsynclock", flc)
+	}
+
+	if opts.EnableTopMetrics && nodeType != typeMongos && limitsOk && requestOpts.EnableTopMetrics {
+		tc := newTopCollector(timeouts.context(ctx, "topmetrics"), client, e.collectorLogger("topmetrics"),
+			opts.CompatibleMode, topologyInfo, opts.CommandRetries)
+		queue.add("topmetrics", tc)
 	}
 
 	// replSetGetStatus is not supported through mongos.
-	if e.opts.EnableReplicasetStatus && nodeType != typeMongos && requestOpts.EnableReplicasetStatus {
-		rsgsc := newReplicationSetStatusCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo)
-		registry.MustRegister(rsgsc)
+	if opts.EnableReplicasetStatus && nodeType != typeMongos && requestOpts.EnableReplicasetStatus {
+		rsgsc := newReplicationSetStatusCollector(timeouts.context(ctx, "replicasetstatus"), client, e.collectorLogger("replicasetstatus"),
+			opts.CompatibleMode, topologyInfo, cache)
+		queue.add("replicasetstatus", rsgsc)
 	}
 
 	// replSetGetStatus is not supported through mongos.
-	if e.opts.EnableReplicasetConfig && nodeType != typeMongos && requestOpts.EnableReplicasetConfig {
-		rsgsc := newReplicationSetConfigCollector(ctx, client, e.opts.Logger,
-			e.opts.CompatibleMode, topologyInfo)
-		registry.MustRegister(rsgsc)
+	if opts.EnableReplicasetConfig && nodeType != typeMongos && requestOpts.EnableReplicasetConfig {
+		rsgsc := newReplicationSetConfigCollector(timeouts.context(ctx, "replicasetconfig"), client, e.collectorLogger("replicasetconfig"),
+			opts.CompatibleMode, topologyInfo, opts.CommandRetries)
+		queue.add("replicasetconfig", rsgsc)
 	}
-	if e.opts.EnableShards && nodeType == typeMongos && requestOpts.EnableShards {
-		sc := newShardsCollector(ctx, client, e.opts.Logger, e.opts.CompatibleMode)
-		registry.MustRegister(sc)
+
+	if opts.EnableShards && nodeType == typeMongos && requestOpts.EnableShards {
+		sc := newShardsCollector(timeouts.context(ctx, "shards"), client, e.collectorLogger("shards"), opts.CompatibleMode,
+			e.shardedCollectionEpochs)
+		bc := newBalancerCollector(timeouts.context(ctx, "shards"), client, e.collectorLogger("shards"))
+		queue.add("shards", sc, bc)
+	}
+
+	// mongos reports the resharding operations of all the shards. The collector skips the
+	// versions before MongoDB 5.0, which cannot reshard.
+	if opts.EnableResharding && nodeType == typeMongos && requestOpts.EnableResharding {
+		rc := newReshardingCollector(timeouts.context(ctx, "resharding"), client, e.collectorLogger("resharding"), dbBuildInfo, topologyInfo)
+		queue.add("resharding", rc)
+	}
+
+	// shardingStatistics on mongos doesn't have the per shard stats.
+	if opts.EnableShardingStatistics && nodeType != typeMongos && requestOpts.EnableShardingStatistics {
+		ssc := newShardingStatisticsCollector(timeouts.context(ctx, "shardingstatistics"), client, e.collectorLogger("shardingstatistics"), topologyInfo)
+		queue.add("shardingstatistics", ssc)
 	}
 
-	if e.opts.EnableFCV && nodeType != typeMongos {
-		fcvc := newFeatureCompatibilityCollector(ctx, client, e.opts.Logger)
-		registry.MustRegister(fcvc)
+	if opts.EnableFCV && requestOpts.EnableFCV {
+		fcvc := newFeatureCompatibilityCollector(timeouts.context(ctx, "fcv"), client, e.collectorLogger("fcv"), nodeType)
+		queue.add("fcv", fcvc)
 	}
 
-	if e.opts.EnablePBMMetrics && requestOpts.EnablePBMMetrics {
-		pbmc := newPbmCollector(ctx, client, e.opts.URI, e.opts.Logger)
-		registry.MustRegister(pbmc)
+	if opts.EnablePBMMetrics && requestOpts.EnablePBMMetrics {
+		pbmc := newPbmCollector(timeouts.context(pbmCtx, "pbm"), client, opts.URI, e.collectorLogger("pbm"))
+		queue.add("pbm", pbmc)
 	}
 
+	// replSetGetStatus is not supported through mongos. The collector is added last, to know the
+	// other collectors of the scrape.
+	if opts.EnableClusterHealth && nodeType != typeMongos && requestOpts.EnableClusterHealth {
+		chc := newClusterHealthCollector(timeouts.context(ctx, "clusterhealth"), client, e.collectorLogger("clusterhealth"), topologyInfo, cache, clusterHealthThresholds{
+			lagDegraded: opts.ClusterHealthLagDegraded,
+			lagCritical: opts.ClusterHealthLagCritical,
+		}, queue.names())
+		queue.add("clusterhealth", chc)
+	}
+
+	queue.register(registerer, e.sampler, e.logger)
+
+	e.registerCustomCollectors(ctx, registerer, client, topologyInfo)
+
 	return registry
 }
 
+// enableAllCollectors enables every collector, as requested by the CollectAll option.
+func enableAllCollectors(opts *Opts) {
+	if len(opts.CollStatsNamespaces) == 0 {
+		opts.DiscoveringMode = true
+	}
+	opts.EnableDiagnosticData = true
+	opts.EnableDBStats = true
+	opts.EnableDBStatsFreeStorage = true
+	opts.EnableCollStats = true
+	opts.EnableTopMetrics = true
+	opts.EnableReplicasetStatus = true
+	opts.EnableReplicasetConfig = true
+	opts.EnableIndexStats = true
+	opts.EnableCurrentopMetrics = true
+	opts.EnableProfile = true
+	opts.EnableProfileStats = true
+	opts.EnableShards = true
+	opts.EnableFCV = true
+	opts.EnablePBMMetrics = true
+	opts.EnableShardingStatistics = true
+	opts.EnableClusterHealth = true
+	opts.EnableDatabaseAccess = true
+	opts.EnableParameters = true
+	opts.EnablePreImages = true
+	opts.EnableParameterMetrics = true
+	opts.EnableQuerySampling = true
+	opts.EnableResharding = true
+	opts.EnableFsyncLockMetrics = true
+}
+
+// collectorTimeouts derives the contexts of the collectors from the scrape context, with the
+// timeout of the collector if it has one.
+type collectorTimeouts struct {
+	timeouts map[string]time.Duration
+	cancels  []context.CancelFunc
+}
+
+func newCollectorTimeouts(timeouts map[string]time.Duration) *collectorTimeouts {
+	return &collectorTimeouts{timeouts: timeouts}
+}
+
+// context returns the context of the named collector.
+func (t *collectorTimeouts) context(ctx context.Context, name string) context.Context {
+	timeout, ok := t.timeouts[name]
+	if !ok {
+		return ctx
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	t.cancels = append(t.cancels, cancel)
+
+	return ctx
+}
+
+// cancel releases the contexts with a timeout.
+func (t *collectorTimeouts) cancel() {
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+}
+
+// sessionContext returns ctx bound to a session with the configured causal consistency and
+// the function to end it. Without the option, ctx is returned as is.
+func (e *Exporter) sessionContext(ctx context.Context, client *mongo.Client) (context.Context, func()) {
+	if e.opts.CausalConsistency == nil || client == nil {
+		return ctx, func() {}
+	}
+
+	sess, err := client.StartSession(options.Session().SetCausalConsistency(*e.opts.CausalConsistency))
+	if err != nil {
+		e.logger.Warnf("Cannot start session, using the driver defaults: %s", err)
+		return ctx, func() {}
+	}
+
+	return mongo.NewSessionContext(ctx, sess), func() { sess.EndSession(context.Background()) }
+}
+
 func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
 	if e.opts.GlobalConnPool {
 		// Get global client. Maybe it must be initialized first.
@@ -272,12 +781,16 @@ func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
 		e.clientMu.Lock()
 		defer e.clientMu.Unlock()
 
+		if e.closed {
+			return nil, errExporterClosed
+		}
+
 		// If client is already initialized, return it.
 		if e.client != nil {
 			return e.client, nil
 		}
 
-		client, err := connect(context.Background(), e.opts)
+		client, err := e.connect(context.Background(), e.poolMonitor.driverMonitor())
 		if err != nil {
 			return nil, err
 		}
@@ -287,7 +800,21 @@ func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
 	}
 
 	// !e.opts.GlobalConnPool: create new client for every scrape.
-	client, err := connect(ctx, e.opts)
+	// Retry so a short election or network blip doesn't leave a gap in the metrics.
+	client, err := e.connect(ctx, nil)
+	for attempt := 0; err != nil && attempt < e.opts.ConnectRetries; attempt++ {
+		delay := e.opts.ConnectRetryInterval << attempt
+		e.logger.Debugf("Cannot connect to MongoDB, retrying in %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+
+		client, err = e.connect(ctx, nil)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -295,6 +822,176 @@ func (e *Exporter) getClient(ctx context.Context) (*mongo.Client, error) {
 	return client, nil
 }
 
+func (e *Exporter) connect(ctx context.Context, poolMonitor *event.PoolMonitor) (*mongo.Client, error) {
+	if e.connectFn != nil {
+		return e.connectFn(ctx, e.opts, poolMonitor)
+	}
+
+	return connect(ctx, e.opts, poolMonitor)
+}
+
+// Close disconnects the client of the global connection pool. The client is disconnected only
+// once, so it is safe to call Close more than once. Scrapes after Close fail to connect.
+func (e *Exporter) Close(ctx context.Context) error {
+	e.clientMu.Lock()
+	defer e.clientMu.Unlock()
+
+	e.closed = true
+	if e.client == nil {
+		return nil
+	}
+
+	client := e.client
+	e.client = nil
+
+	return client.Disconnect(ctx)
+}
+
+// CollectOnce runs the enabled collectors once, without starting the HTTP server, and returns
+// the metrics in the Prometheus text exposition format.
+func (e *Exporter) CollectOnce(ctx context.Context) (string, error) {
+	mfs, err := e.gather(ctx, *e.opts)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return "", fmt.Errorf("cannot encode metrics: %w", err)
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// PushOnce runs the enabled collectors once and pushes the metrics to a Pushgateway, replacing
+// the metrics of the previous push with the same grouping key. The grouping key is the node name
+// and the topology labels, except the replica set state which changes with the elections.
+// As for a scrape, if MongoDB is not reachable only mongodb_up=0 is pushed.
+func (e *Exporter) PushOnce(ctx context.Context, pushgatewayURL, jobName string) error {
+	registry, ti := e.scrapeRegistry(ctx, *e.opts)
+
+	var labels map[string]string
+	if ti != nil {
+		labels = ti.baseLabels()
+	}
+	grouping := pushGrouping(e.opts.NodeName, labels)
+
+	// The Pushgateway adds the grouping labels, the pushed metrics cannot have them. As for a
+	// scrape, a collector failing to gather doesn't prevent pushing the other metrics.
+	gatherer := &partialGatherer{gatherer: registry, logger: e.logger}
+	pusher := push.New(pushgatewayURL, jobName).Gatherer(newLabelsRemover(gatherer, grouping))
+	for name, value := range grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("cannot push metrics: %w", err)
+	}
+
+	return nil
+}
+
+// pushGrouping returns the grouping key of the pushes for the node name and topology labels.
+func pushGrouping(nodeName string, labels map[string]string) map[string]string {
+	grouping := make(map[string]string)
+	if nodeName != "" {
+		grouping["instance"] = nodeName
+	}
+
+	for _, name := range []string{labelClusterRole, labelClusterID, labelReplicasetName} {
+		if value := labels[name]; value != "" {
+			grouping[name] = value
+		}
+	}
+
+	return grouping
+}
+
+// gather runs the collectors enabled in the request options once and returns their metrics.
+// Unlike the Handler, it fails if MongoDB is not reachable.
+func (e *Exporter) gather(ctx context.Context, requestOpts Opts) ([]*dto.MetricFamily, error) {
+	client, err := e.getClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to MongoDB: %w", err)
+	}
+
+	if !e.opts.GlobalConnPool {
+		defer func() {
+			if err := client.Disconnect(ctx); err != nil {
+				e.logger.Errorf("Cannot disconnect client: %v", err)
+			}
+		}()
+	}
+
+	e.updateTotalCollectionsCount(ctx, client)
+
+	cache := newScrapeCache(client, e.opts.CommandRetries)
+	ti := newTopologyInfo(ctx, client, e.logger, cache)
+	registry := e.makeRegistry(ctx, client, ti, cache, requestOpts)
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("cannot gather metrics: %w", err)
+	}
+
+	return mfs, nil
+}
+
+// updateTotalCollectionsCount counts the collections once, it is used to check CollStatsLimit.
+func (e *Exporter) updateTotalCollectionsCount(ctx context.Context, client *mongo.Client) {
+	if e.getTotalCollectionsCount() > 0 {
+		return
+	}
+
+	count, err := nonSystemCollectionsCount(ctx, client, nil, nil)
+	if err == nil {
+		e.lock.Lock()
+		e.totalCollectionsCount = count
+		e.lock.Unlock()
+	}
+}
+
+// scrapeRegistry returns a registry with the metrics of the collectors enabled in the request
+// options and the topology of the node. If MongoDB is not reachable, the registry only has the
+// general metrics with mongodb_up=0 and the topology is nil.
+func (e *Exporter) scrapeRegistry(ctx context.Context, requestOpts Opts) (*prometheus.Registry, *topologyInfo) {
+	client, err := e.getClient(ctx)
+	if err != nil {
+		e.logger.Errorf("Cannot connect to MongoDB: %v", err)
+	}
+
+	if client == nil {
+		registry := prometheus.NewRegistry()
+		gc := newGeneralCollector(withCollectorStatus(ctx, e.collectorStatus), client, "", e.opts.Logger)
+		prometheus.WrapRegistererWith(e.opts.ConstantLabels, registry).MustRegister(gc)
+
+		return registry, nil
+	}
+
+	// Close client after usage. The collectors cache their metrics while they are registered,
+	// so the registry can still be gathered.
+	if !e.opts.GlobalConnPool {
+		defer func() {
+			err := client.Disconnect(ctx)
+			if err != nil {
+				e.logger.Errorf("Cannot disconnect client: %v", err)
+			}
+		}()
+	}
+
+	e.updateTotalCollectionsCount(ctx, client)
+
+	// Topology can change between requests, so we need to get it every time.
+	// Commands needed by the topology and several collectors run only once per scrape.
+	cache := newScrapeCache(client, e.opts.CommandRetries)
+	ti := newTopologyInfo(ctx, client, e.logger, cache)
+
+	return e.makeRegistry(ctx, client, ti, cache, requestOpts), ti
+}
+
 // Handler returns an http.Handler that serves metrics. Can be used instead of
 // run for hooking up custom HTTP servers.
 func (e *Exporter) Handler() http.Handler {
@@ -306,69 +1003,122 @@ func (e *Exporter) Handler() http.Handler {
 		}
 		seconds -= e.opts.TimeoutOffset
 
-		var client *mongo.Client
-		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(seconds)*time.Second)
+		timeout := time.Duration(seconds) * time.Second
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 
-		requestOpts := GetRequestOpts(r.URL.Query()["collect[]"], e.opts)
-
-		client, err = e.getClient(ctx)
-		if err != nil {
-			e.logger.Errorf("Cannot connect to MongoDB: %v", err)
+		filters := r.URL.Query()["collect[]"]
+		if err := CheckRequestFilters(filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		if client != nil && e.getTotalCollectionsCount() <= 0 {
-			count, err := nonSystemCollectionsCount(ctx, client, nil, nil)
-			if err == nil {
-				e.lock.Lock()
-				e.totalCollectionsCount = count
-				e.lock.Unlock()
+		// Scrape storms are rejected instead of queued, a queued scrape would time out anyway.
+		if e.scrapeSlots != nil {
+			select {
+			case e.scrapeSlots <- struct{}{}:
+				defer func() { <-e.scrapeSlots }()
+			default:
+				http.Error(w, "too many concurrent scrapes", http.StatusServiceUnavailable)
+				return
 			}
 		}
 
-		// Close client after usage.
-		if !e.opts.GlobalConnPool {
-			defer func() {
-				if client != nil {
-					err := client.Disconnect(ctx)
-					if err != nil {
-						e.logger.Errorf("Cannot disconnect client: %v", err)
-					}
-				}
-			}()
+		if e.scrapesInFlight != nil {
+			e.scrapesInFlight.Inc()
+			defer e.scrapesInFlight.Dec()
 		}
 
+		requestOpts := GetRequestOpts(filters, e.opts)
+
 		var gatherers prometheus.Gatherers
 
 		if !e.opts.DisableDefaultRegistry {
 			gatherers = append(gatherers, prometheus.DefaultGatherer)
 		}
 
-		var registry *prometheus.Registry
-		var ti *topologyInfo
-		if client != nil {
-			// Topology can change between requests, so we need to get it every time.
-			ti = newTopologyInfo(ctx, client, e.logger)
-			registry = e.makeRegistry(ctx, client, ti, requestOpts)
+		if e.metricsCache != nil {
+			families, age, err := e.metricsCache.get(ctx, metricsCacheKey(filters), requestOpts, timeout)
+			if err == nil {
+				gatherers = append(gatherers, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+					return families, nil
+				}), cacheAgeGatherer(age, e.opts.ConstantLabels))
+			} else {
+				e.logger.Warnf("Cannot serve cached metrics, scraping them: %s", err)
+				registry, _ := e.scrapeRegistry(ctx, requestOpts)
+				gatherers = append(gatherers, registry)
+			}
 		} else {
-			registry = prometheus.NewRegistry()
-			gc := newGeneralCollector(ctx, client, "", e.opts.Logger)
-			registry.MustRegister(gc)
+			registry, _ := e.scrapeRegistry(ctx, requestOpts)
+			gatherers = append(gatherers, registry)
 		}
 
-		gatherers = append(gatherers, registry)
+		// Exporters built without New have no config info.
+		exporterRegistry := prometheus.NewRegistry()
+		var exporterMetrics []prometheus.Collector
+		if e.configInfo != nil {
+			exporterMetrics = append(exporterMetrics, e.configInfo)
+		}
+		if e.poolMonitor != nil {
+			exporterMetrics = append(exporterMetrics, e.poolMonitor)
+		}
+		if e.scrapesInFlight != nil {
+			exporterMetrics = append(exporterMetrics, e.scrapesInFlight)
+		}
+		if err := registerAll(prometheus.WrapRegistererWith(e.opts.ConstantLabels, exporterRegistry), exporterMetrics...); err != nil {
+			e.logger.Errorf("Cannot register the exporter metrics: %s", err)
+		}
+		gatherers = append(gatherers, exporterRegistry)
 
 		// Delegate http serving to Prometheus client library, which will call collector.Collect.
 		h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
-			ErrorHandling: promhttp.ContinueOnError,
-			ErrorLog:      e.logger,
+			ErrorHandling:     promhttp.ContinueOnError,
+			ErrorLog:          e.logger,
+			EnableOpenMetrics: e.opts.EnableOpenMetrics,
 		})
 
 		h.ServeHTTP(w, r)
 	})
 }
 
+// metricsCacheKey returns the key of the cached metrics for the collect[] filters.
+func metricsCacheKey(filters []string) string {
+	filters = unique(filters)
+	sort.Strings(filters)
+
+	return strings.Join(filters, ",")
+}
+
+// requestOptsSetters enable in the request options the collector with the name used in collect[].
+//
+//nolint:gochecknoglobals
+var requestOptsSetters = map[string]func(o *Opts){
+	"diagnosticdata":     func(o *Opts) { o.EnableDiagnosticData = true },
+	"replicasetstatus":   func(o *Opts) { o.EnableReplicasetStatus = true },
+	"replicasetconfig":   func(o *Opts) { o.EnableReplicasetConfig = true },
+	"dbstats":            func(o *Opts) { o.EnableDBStats = true },
+	"topmetrics":         func(o *Opts) { o.EnableTopMetrics = true },
+	"currentopmetrics":   func(o *Opts) { o.EnableCurrentopMetrics = true },
+	"indexstats":         func(o *Opts) { o.EnableIndexStats = true },
+	"collstats":          func(o *Opts) { o.EnableCollStats = true },
+	"profile":            func(o *Opts) { o.EnableProfile = true },
+	"profilestats":       func(o *Opts) { o.EnableProfileStats = true },
+	"shards":             func(o *Opts) { o.EnableShards = true },
+	"fcv":                func(o *Opts) { o.EnableFCV = true },
+	"pbm":                func(o *Opts) { o.EnablePBMMetrics = true },
+	"shardingstatistics": func(o *Opts) { o.EnableShardingStatistics = true },
+	"clusterhealth":      func(o *Opts) { o.EnableClusterHealth = true },
+	"dbaccess":           func(o *Opts) { o.EnableDatabaseAccess = true },
+	"parameters":         func(o *Opts) { o.EnableParameters = true },
+	"preimages":          func(o *Opts) { o.EnablePreImages = true },
+	"parametermetrics":   func(o *Opts) { o.EnableParameterMetrics = true },
+	"querysampling":      func(o *Opts) { o.EnableQuerySampling = true },
+	"resharding":         func(o *Opts) { o.EnableResharding = true },
+	"fsynclock":          func(o *Opts) { o.EnableFsyncLockMetrics = true },
+}
+
 // GetRequestOpts makes exporter.Opts structure from request filters and default options.
+// Unknown filters are ignored, use CheckRequestFilters to reject them.
 func GetRequestOpts(filters []string, defaultOpts *Opts) Opts {
 	requestOpts := Opts{}
 
@@ -377,50 +1127,59 @@ func GetRequestOpts(filters []string, defaultOpts *Opts) Opts {
 	}
 
 	for _, filter := range filters {
-		switch filter {
-		case "diagnosticdata":
-			requestOpts.EnableDiagnosticData = true
-		case "replicasetstatus":
-			requestOpts.EnableReplicasetStatus = true
-		case "replicasetconfig":
-			requestOpts.EnableReplicasetConfig = true
-		case "dbstats":
-			requestOpts.EnableDBStats = true
-		case "topmetrics":
-			requestOpts.EnableTopMetrics = true
-		case "currentopmetrics":
-			requestOpts.EnableCurrentopMetrics = true
-		case "indexstats":
-			requestOpts.EnableIndexStats = true
-		case "collstats":
-			requestOpts.EnableCollStats = true
-		case "profile":
-			requestOpts.EnableProfile = true
-		case "shards":
-			requestOpts.EnableShards = true
-		case "fcv":
-			requestOpts.EnableFCV = true
-		case "pbm":
-			requestOpts.EnablePBMMetrics = true
+		if set, ok := requestOptsSetters[filter]; ok {
+			set(&requestOpts)
 		}
 	}
 
 	return requestOpts
 }
 
-func connect(ctx context.Context, opts *Opts) (*mongo.Client, error) {
-	clientOpts, err := dsn_fix.ClientOptionsForDSN(opts.URI)
-	if err != nil {
-		return nil, fmt.Errorf("invalid dsn: %w", err)
+// exporterLabels are the names of the labels set by the exporter: the topology labels, the
+// labels of mongodb_up and mongodb_instance_node_type, the instance label of the multi-target
+// handlers, the collector label of the collector status metrics and the labels of the
+// collectors metrics. A collector whose metrics have a constant label is not registered.
+var exporterLabels = []string{
+	labelClusterRole, labelClusterID, labelReplicasetName, labelReplicasetState,
+	"instance", "cluster_role", "type", "collector",
+	"database", "db", "collection", "namespace", "index", "key_name", "shard", "name",
+	"member_idx", "member_state", "self", "state", "role", "op", "command", "window", "version",
+	"le", "quantile",
+}
+
+// checkConstantLabels checks that the constant labels have valid names, not used by the
+// labels set by the exporter.
+func checkConstantLabels(labels map[string]string) error {
+	for name := range labels {
+		if !model.LabelName(name).IsValidLegacy() || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid constant label name %q", name)
+		}
+
+		for _, label := range exporterLabels {
+			if name == label {
+				return fmt.Errorf("constant label %q conflicts with the label set by the exporter", name)
+			}
+		}
 	}
 
-	clientOpts.SetDirect(opts.DirectConnect)
-	clientOpts.SetAppName("mongodb_exporter")
+	return nil
+}
 
-	if clientOpts.ConnectTimeout == nil {
-		connectTimeout := time.Duration(opts.ConnectTimeoutMS) * time.Millisecond
-		clientOpts.SetConnectTimeout(connectTimeout)
-		clientOpts.SetServerSelectionTimeout(connectTimeout)
+// CheckRequestFilters returns an error if any of the collect[] filters is not a collector name.
+func CheckRequestFilters(filters []string) error {
+	for _, filter := range filters {
+		if _, ok := requestOptsSetters[filter]; !ok {
+			return fmt.Errorf("unknown collector %q", filter)
+		}
+	}
+
+	return nil
+}
+
+func connect(ctx context.Context, opts *Opts, poolMonitor *event.PoolMonitor) (*mongo.Client, error) {
+	clientOpts, err := clientOptions(opts, poolMonitor)
+	if err != nil {
+		return nil, err
 	}
 
 	client, err := mongo.Connect(ctx, clientOpts)
@@ -437,3 +1196,182 @@ func connect(ctx context.Context, opts *Opts) (*mongo.Client, error) {
 
 	return client, nil
 }
+
+// maxAppNameLength is the maximum size of the application name accepted by the server.
+const maxAppNameLength = 128
+
+// appName returns the application name of the exporter connections, with the suffix if any.
+func appName(suffix string) string {
+	name := "mongodb_exporter"
+	if suffix == "" {
+		return name
+	}
+
+	name += "-" + suffix
+	if len(name) > maxAppNameLength {
+		name = name[:maxAppNameLength]
+	}
+
+	return name
+}
+
+// setCredential sets the user and the password read from the password file in the credential
+// of the URI.
+func setCredential(clientOpts *options.ClientOptions, opts *Opts) error {
+	if opts.Username == "" && opts.PasswordFile == "" {
+		return nil
+	}
+
+	var credential options.Credential
+	if clientOpts.Auth != nil {
+		credential = *clientOpts.Auth
+	}
+
+	if opts.Username != "" {
+		credential.Username = opts.Username
+	}
+
+	if opts.PasswordFile != "" {
+		password, err := os.ReadFile(opts.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("cannot read the password file: %w", err)
+		}
+		credential.Password = strings.TrimRight(string(password), "\r\n")
+		credential.PasswordSet = true
+	}
+
+	if credential.Username == "" {
+		return fmt.Errorf("the password file needs a user, set in the username option or the URI")
+	}
+
+	clientOpts.SetAuth(credential)
+
+	return nil
+}
+
+// setAuthOptions sets the auth source and mechanism properties in the credential of the URI.
+// The user, or the mechanism for the ones without user like MONGODB-AWS, comes from the URI or
+// the Username option.
+func setAuthOptions(clientOpts *options.ClientOptions, opts *Opts) error {
+	if err := setCredential(clientOpts, opts); err != nil {
+		return err
+	}
+
+	if opts.AuthSource == "" && len(opts.AuthMechanismProperties) == 0 {
+		return nil
+	}
+
+	if clientOpts.Auth == nil {
+		return fmt.Errorf("auth source and mechanism properties need a user or an auth mechanism in the URI")
+	}
+
+	credential := *clientOpts.Auth
+	if opts.AuthSource != "" {
+		credential.AuthSource = opts.AuthSource
+	}
+
+	if len(opts.AuthMechanismProperties) > 0 {
+		properties := make(map[string]string, len(credential.AuthMechanismProperties)+len(opts.AuthMechanismProperties))
+		for k, v := range credential.AuthMechanismProperties {
+			properties[k] = v
+		}
+		for k, v := range opts.AuthMechanismProperties {
+			properties[k] = v
+		}
+		credential.AuthMechanismProperties = properties
+	}
+
+	clientOpts.SetAuth(credential)
+
+	return nil
+}
+
+// directConnect returns whether the exporter connects directly to the host of the URI.
+func directConnect(opts *Opts) bool {
+	if opts.DirectConnect == nil {
+		return true
+	}
+
+	return *opts.DirectConnect
+}
+
+// clientOptions returns the driver options to connect to MongoDB with the exporter options.
+func clientOptions(opts *Opts, poolMonitor *event.PoolMonitor) (*options.ClientOptions, error) {
+	clientOpts, err := dsn_fix.ClientOptionsForDSN(opts.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	clientOpts.SetDirect(directConnect(opts))
+	clientOpts.SetAppName(appName(opts.AppNameSuffix))
+
+	if err := setAuthOptions(clientOpts, opts); err != nil {
+		return nil, err
+	}
+
+	rp, err := readPreference(opts.ReadPreference)
+	if err != nil {
+		return nil, err
+	}
+	if rp != nil {
+		clientOpts.SetReadPreference(rp)
+	}
+
+	if len(opts.Compressors) > 0 {
+		clientOpts.SetCompressors(opts.Compressors)
+	}
+	if opts.ZstdLevel != 0 {
+		clientOpts.SetZstdLevel(opts.ZstdLevel)
+	}
+
+	if poolMonitor != nil {
+		clientOpts.SetPoolMonitor(poolMonitor)
+	}
+
+	if tlsConfig := opts.TargetTLS.resolve(clientOpts.Hosts); tlsConfig != nil {
+		clientOpts.SetTLSConfig(tlsConfig)
+	}
+
+	if clientOpts.ConnectTimeout == nil {
+		connectTimeout := time.Duration(opts.ConnectTimeoutMS) * time.Millisecond
+		clientOpts.SetConnectTimeout(connectTimeout)
+		clientOpts.SetServerSelectionTimeout(connectTimeout)
+	}
+
+	if opts.MaxPoolSize != 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize != 0 {
+		clientOpts.SetMinPoolSize(opts.MinPoolSize)
+	}
+
+	return clientOpts, nil
+}
+
+// readPreference parses the read preference mode name. It returns nil when the mode is empty
+// so the driver default (or the one set in the URI) is used.
+func readPreference(mode string) (*readpref.ReadPref, error) {
+	if mode == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read preference: %w", err)
+	}
+
+	return readpref.New(m)
+}
+
+// checkCompressors returns an error if a compressor is not supported by the driver.
+func checkCompressors(compressors []string) error {
+	for _, c := range compressors {
+		switch c {
+		case "zstd", "snappy", "zlib":
+		default:
+			return fmt.Errorf("invalid compressor %q, valid ones are zstd, snappy and zlib", c)
+		}
+	}
+
+	return nil
+}
@@ -16,6 +16,9 @@
 package exporter
 
 import (
+	"context"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -41,3 +44,197 @@ func measureCollectTime(ch chan<- prometheus.Metric, exporter, collector string)
 		ch <- scrapeMetric
 	}
 }
+
+// collectorStatus keeps the scrape errors and the time of the last successful scrape of the
+// collectors of an exporter across its scrapes. The collectors are created for every scrape,
+// so they get it from the scrape context.
+type collectorStatus struct {
+	lock        sync.Mutex
+	errors      map[string]prometheus.Counter
+	lastSuccess map[string]time.Time
+	failed      map[string]bool
+}
+
+func newCollectorStatus() *collectorStatus {
+	return &collectorStatus{
+		errors:      make(map[string]prometheus.Counter),
+		lastSuccess: make(map[string]time.Time),
+		failed:      make(map[string]bool),
+	}
+}
+
+type collectorStatusKey struct{}
+
+// withCollectorStatus returns a context passing the status to the collectors.
+func withCollectorStatus(ctx context.Context, status *collectorStatus) context.Context {
+	return context.WithValue(ctx, collectorStatusKey{}, status)
+}
+
+// collectorStatusFromContext returns the status passed by the context. The collectors run
+// without one, e.g. by an exporter built without New, only report the current scrape.
+func collectorStatusFromContext(ctx context.Context) *collectorStatus {
+	if status, ok := ctx.Value(collectorStatusKey{}).(*collectorStatus); ok && status != nil {
+		return status
+	}
+
+	return newCollectorStatus()
+}
+
+// metrics returns whether the last scrape of the collector succeeded, the number of its
+// failed scrapes since the exporter started and the time of its last successful scrape, to
+// detect a collector which silently stopped returning data.
+func (s *collectorStatus) metrics(collector string, failed bool) []prometheus.Metric {
+	s.lock.Lock()
+	errs, ok := s.errors[collector]
+	if !ok {
+		errs = prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "mongodb_collector_scrape_errors_total",
+			Help:        "Number of scrapes of the collector that logged an error",
+			ConstLabels: prometheus.Labels{"collector": collector},
+		})
+		s.errors[collector] = errs
+	}
+	if !failed {
+		s.lastSuccess[collector] = time.Now()
+	}
+	s.failed[collector] = failed
+	lastSuccess, succeeded := s.lastSuccess[collector]
+	s.lock.Unlock()
+
+	success := float64(1)
+	if failed {
+		success = 0
+		errs.Inc()
+	}
+
+	successDesc := prometheus.NewDesc(
+		"mongodb_collector_success",
+		"Whether the last scrape of the collector succeeded, without logging an error",
+		nil,
+		prometheus.Labels{"collector": collector},
+	)
+
+	metrics := []prometheus.Metric{
+		collectorSuccess{
+			Metric: prometheus.MustNewConstMetric(successDesc, prometheus.GaugeValue, success),
+			failed: failed,
+		},
+		errs,
+	}
+
+	// Nothing until the collector succeeds once.
+	if succeeded {
+		lastSuccessDesc := prometheus.NewDesc(
+			"mongodb_collector_last_scrape_timestamp_seconds",
+			"Unix time of the last scrape of the collector that succeeded, without logging an error",
+			nil,
+			prometheus.Labels{"collector": collector},
+		)
+		metrics = append(metrics, prometheus.MustNewConstMetric(lastSuccessDesc, prometheus.GaugeValue,
+			float64(lastSuccess.UnixNano())/1e9))
+	}
+
+	return metrics
+}
+
+// anyFailed returns true if the last scrape of one of the collectors failed.
+func (s *collectorStatus) anyFailed(collectors []string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, c := range collectors {
+		if s.failed[c] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectorSuccess is the mongodb_collector_success metric, typed so the collector sampler can
+// tell the failed runs, which it doesn't serve again.
+type collectorSuccess struct {
+	prometheus.Metric
+	failed bool
+}
+
+// unauthorizedMetric returns mongodb_collector_unauthorized for the command run by the
+// collector if err is the server rejecting it for missing privileges, or nil, so operators
+// get a signal that the monitoring user lacks a role. The collector is named as in the status
+// metrics, the name of its baseCollector.
+func unauthorizedMetric(err error, collector, command string) prometheus.Metric {
+	if !isUnauthorized(err) {
+		return nil
+	}
+
+	d := prometheus.NewDesc(
+		"mongodb_collector_unauthorized",
+		"Whether the last scrape of the collector failed to run the command for missing privileges",
+		nil,
+		prometheus.Labels{"collector": collector, "command": command},
+	)
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1)
+}
+
+// newConfigInfo returns the mongodb_exporter_config_info metric, with the main settings of the
+// exporter as labels. The URI and the credentials are never included.
+func newConfigInfo(opts *Opts) prometheus.Gauge {
+	info := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_exporter_config_info",
+		Help: "Effective configuration of the exporter, the value is always 1",
+		ConstLabels: prometheus.Labels{
+			"compatible_mode":        strconv.FormatBool(opts.CompatibleMode),
+			"global_conn_pool":       strconv.FormatBool(opts.GlobalConnPool),
+			"direct_connect":         strconv.FormatBool(directConnect(opts)),
+			"discovering_mode":       strconv.FormatBool(opts.DiscoveringMode),
+			"collect_all":            strconv.FormatBool(opts.CollectAll),
+			"timeout_offset":         strconv.Itoa(opts.TimeoutOffset),
+			"connect_timeout_ms":     strconv.Itoa(opts.ConnectTimeoutMS),
+			"command_retries":        strconv.Itoa(opts.CommandRetries),
+			"max_staleness":          opts.MaxStaleness.String(),
+			"metrics_cache_ttl":      opts.MetricsCacheTTL.String(),
+			"max_concurrent_scrapes": strconv.Itoa(opts.MaxConcurrentScrapes),
+		},
+	})
+	info.Set(1)
+
+	return info
+}
+
+// newScrapesInFlight returns mongodb_exporter_scrapes_in_flight, the number of scrapes being
+// served by Handler.
+func newScrapesInFlight() prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_exporter_scrapes_in_flight",
+		Help: "Number of scrapes being served",
+	})
+}
+
+// newTargetInfo returns the OpenMetrics target_info metric with the topology labels of the
+// target, so they can be joined to the series instead of being on each of them.
+func newTargetInfo(labels map[string]string) prometheus.Gauge {
+	info := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "target_info",
+		Help:        "Target metadata, the value is always 1",
+		ConstLabels: labels,
+	})
+	info.Set(1)
+
+	return info
+}
+
+// newCollStatsSkipped returns mongodb_collstats_collections_skipped, 1 when the collectors
+// depending on the number of collections are skipped because there are more than CollStatsLimit.
+func newCollStatsSkipped(labels map[string]string, skipped bool) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "mongodb_collstats_collections_skipped",
+		Help:        "Whether collstats, indexstats, dbstats and the other per collection collectors are skipped because of the collections limit",
+		ConstLabels: labels,
+	})
+	if skipped {
+		g.Set(1)
+	}
+
+	return g
+}
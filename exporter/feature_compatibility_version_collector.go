@@ -29,13 +29,17 @@ import (
 type featureCompatibilityCollector struct {
 	ctx  context.Context
 	base *baseCollector
+
+	nodeType mongoDBNodeType
 }
 
 // newProfileCollector creates a collector for being processed queries.
-func newFeatureCompatibilityCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *featureCompatibilityCollector {
+func newFeatureCompatibilityCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, nodeType mongoDBNodeType) *featureCompatibilityCollector {
 	return &featureCompatibilityCollector{
 		ctx:  ctx,
-		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "featureCompatibility"})),
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "fcv"})),
+
+		nodeType: nodeType,
 	}
 }
 
@@ -50,31 +54,83 @@ func (d *featureCompatibilityCollector) Collect(ch chan<- prometheus.Metric) {
 func (d *featureCompatibilityCollector) collect(ch chan<- prometheus.Metric) {
 	defer measureCollectTime(ch, "mongodb", "fcv")()
 
-	cmd := bson.D{{Key: "getParameter", Value: 1}, {Key: "featureCompatibilityVersion", Value: 1}}
 	client := d.base.client
 	if client == nil {
 		return
 	}
-	res := client.Database("admin").RunCommand(d.ctx, cmd)
 
-	m := make(map[string]interface{})
-	if err := res.Decode(&m); err != nil {
+	m, err := d.featureCompatibilityVersion()
+	if err != nil {
+		if isUnauthorized(err) {
+			d.base.logger.Warnf("Not authorized to get featureCompatibilityVersion: %v", err)
+			return
+		}
 		d.base.logger.Errorf("Failed to decode featureCompatibilityVersion: %v", err)
 		ch <- prometheus.NewInvalidMetric(prometheus.NewInvalidDesc(err), err)
 		return
 	}
 
-	rawValue := walkTo(m, []string{"featureCompatibilityVersion", "version"})
-	if rawValue != nil {
-		versionString := fmt.Sprintf("%v", rawValue)
-		version, err := strconv.ParseFloat(versionString, 64)
-		if err != nil {
-			d.base.logger.Errorf("Failed to parse featureCompatibilityVersion: %v", err)
-			ch <- prometheus.NewInvalidMetric(prometheus.NewInvalidDesc(err), err)
-			return
+	metrics, err := fcvMetrics(m)
+	if err != nil {
+		d.base.logger.Errorf("Failed to parse featureCompatibilityVersion: %v", err)
+		ch <- prometheus.NewInvalidMetric(prometheus.NewInvalidDesc(err), err)
+		return
+	}
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+}
+
+// featureCompatibilityVersion returns the featureCompatibilityVersion document, e.g.
+// {"version": "6.0"}. A mongos has no such parameter, it returns the one stored on the config
+// servers, which is the FCV of the cluster.
+func (d *featureCompatibilityCollector) featureCompatibilityVersion() (bson.M, error) {
+	admin := d.base.client.Database("admin")
+
+	if d.nodeType == typeMongos {
+		var m bson.M
+		filter := bson.M{"_id": "featureCompatibilityVersion"}
+		if err := admin.Collection("system.version").FindOne(d.ctx, filter).Decode(&m); err != nil {
+			return nil, err
 		}
 
-		d := prometheus.NewDesc("mongodb_fcv_feature_compatibility_version", "Feature compatibility version", []string{"version"}, map[string]string{})
-		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, version, versionString)
+		return m, nil
 	}
+
+	cmd := bson.D{{Key: "getParameter", Value: 1}, {Key: "featureCompatibilityVersion", Value: 1}}
+
+	var m bson.M
+	if err := admin.RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	fcv, _ := m["featureCompatibilityVersion"].(bson.M)
+
+	return fcv, nil
+}
+
+// fcvMetrics returns the feature compatibility version as a number and as an info metric,
+// whose version label can be compared with the binary version of the server.
+func fcvMetrics(fcv bson.M) ([]prometheus.Metric, error) {
+	rawValue, ok := fcv["version"]
+	if !ok {
+		return nil, nil
+	}
+
+	versionString := fmt.Sprintf("%v", rawValue)
+	version, err := strconv.ParseFloat(versionString, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	d := prometheus.NewDesc("mongodb_fcv_feature_compatibility_version", "Feature compatibility version", []string{"version"}, map[string]string{})
+	info := prometheus.NewDesc("mongodb_fcv_info", "Feature compatibility version, the value is always 1", []string{"version"}, nil)
+
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(d, prometheus.GaugeValue, version, versionString),
+		prometheus.MustNewConstMetric(info, prometheus.GaugeValue, 1, versionString),
+	}, nil
 }
+
+var _ prometheus.Collector = (*featureCompatibilityCollector)(nil)
@@ -27,6 +27,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -41,7 +42,7 @@ func TestFCVCollector(t *testing.T) {
 	database.Drop(ctx)       //nolint:errcheck
 	defer database.Drop(ctx) //nolint:errcheck
 
-	c := newFeatureCompatibilityCollector(ctx, client, logrus.New())
+	c := newFeatureCompatibilityCollector(ctx, client, logrus.New(), typeMongod)
 
 	sversion, _ := getMongoDBVersionInfo(t, "mongo-1-1")
 
@@ -95,3 +96,32 @@ mongodb_fcv_feature_compatibility_version{version="` + mversion + `"} ` + mversi
 	err = testutil.CollectAndCompare(c, expected, filter...)
 	assert.NoError(t, err)
 }
+
+func TestFCVMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics, err := fcvMetrics(bson.M{"version": "6.0"})
+	require.NoError(t, err)
+
+	expected := strings.NewReader(`
+# HELP mongodb_fcv_feature_compatibility_version Feature compatibility version
+# TYPE mongodb_fcv_feature_compatibility_version gauge
+mongodb_fcv_feature_compatibility_version{version="6.0"} 6
+# HELP mongodb_fcv_info Feature compatibility version, the value is always 1
+# TYPE mongodb_fcv_info gauge
+mongodb_fcv_info{version="6.0"} 1` + "\n")
+	err = testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+
+	// The document stored on the config servers, as read through a mongos.
+	metrics, err = fcvMetrics(bson.M{"_id": "featureCompatibilityVersion", "version": "7.0"})
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+
+	metrics, err = fcvMetrics(nil)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	_, err = fcvMetrics(bson.M{"version": "latest"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fsyncLockCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	topologyInfo labelsGetter
+}
+
+// newFsyncLockCollector creates a collector for the fsync lock status of the server.
+func newFsyncLockCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, topology labelsGetter) *fsyncLockCollector {
+	return &fsyncLockCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "fsynclock"})),
+		topologyInfo: topology,
+	}
+}
+
+func (d *fsyncLockCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *fsyncLockCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *fsyncLockCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "fsynclock")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// currentOp reports fsyncLock whatever the filter, the one on the fsync lock thread keeps
+	// the other operations out of the response.
+	var m bson.M
+	cmd := bson.D{{Key: "currentOp", Value: 1}, {Key: "desc", Value: "fsyncLockWorker"}}
+	if err := client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		logger.Errorf("cannot get the fsync lock status from currentOp: %s", err)
+		return
+	}
+
+	logger.Debug("currentOp fsyncLockWorker result")
+	debugResult(logger, m)
+
+	for _, metric := range fsyncLockMetrics(m, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// fsyncLockMetrics returns whether the server is fsync locked, and the number of fsync locks
+// held when currentOp reports it, from the currentOp response.
+func fsyncLockMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	var locked float64
+	if l, _ := m["fsyncLock"].(bool); l {
+		locked = 1
+	}
+
+	d := prometheus.NewDesc("mongodb_mongod_fsync_locked",
+		"Whether the server is locked against writes by fsync with lock: true", nil, labels)
+	metrics := []prometheus.Metric{prometheus.MustNewConstMetric(d, prometheus.GaugeValue, locked)}
+
+	// Each fsync lock must be released by an fsyncUnlock.
+	if f, err := asFloat64(m["lockCount"]); err == nil && f != nil {
+		d := prometheus.NewDesc("mongodb_mongod_fsync_lock_count",
+			"Number of fsync locks held, each one released by an fsyncUnlock", nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*fsyncLockCollector)(nil)
@@ -0,0 +1,58 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFsyncLockMetrics(t *testing.T) {
+	t.Parallel()
+
+	// currentOp of a server locked by db.fsyncLock() twice.
+	m := bson.M{
+		"inprog":    bson.A{bson.M{"desc": "fsyncLockWorker", "active": true}},
+		"fsyncLock": true,
+		"info":      "use db.fsyncUnlock() to terminate the fsync write/snapshot lock",
+		"lockCount": int64(2),
+		"ok":        float64(1),
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_fsync_lock_count Number of fsync locks held, each one released by an fsyncUnlock
+	# TYPE mongodb_mongod_fsync_lock_count gauge
+	mongodb_mongod_fsync_lock_count{rs_nm="rs"} 2
+	# HELP mongodb_mongod_fsync_locked Whether the server is locked against writes by fsync with lock: true
+	# TYPE mongodb_mongod_fsync_locked gauge
+	mongodb_mongod_fsync_locked{rs_nm="rs"} 1` + "\n")
+
+	metrics := fsyncLockMetrics(m, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Without the lock, currentOp doesn't report fsyncLock.
+	expected = strings.NewReader(`
+	# HELP mongodb_mongod_fsync_locked Whether the server is locked against writes by fsync with lock: true
+	# TYPE mongodb_mongod_fsync_locked gauge
+	mongodb_mongod_fsync_locked{rs_nm="rs"} 0` + "\n")
+
+	metrics = fsyncLockMetrics(bson.M{"inprog": bson.A{}, "ok": float64(1)}, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+}
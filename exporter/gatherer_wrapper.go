@@ -16,12 +16,16 @@
 package exporter
 
 import (
+	"sort"
+
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
 )
 
 // GathererWrapped is a wrapper for prometheus.Gatherer that adds labels to all metrics.
+// Labels already set in a metric are kept.
 type GathererWrapped struct {
 	originalGatherer prometheus.Gatherer
 	labels           prometheus.Labels
@@ -45,6 +49,9 @@ func (g *GathererWrapped) Gather() ([]*io_prometheus_client.MetricFamily, error)
 	for _, metric := range metrics {
 		for _, m := range metric.GetMetric() {
 			for k, v := range g.labels {
+				if hasLabel(m, k) {
+					continue
+				}
 				v := v
 				k := k
 				m.Label = append(m.Label, &io_prometheus_client.LabelPair{
@@ -52,8 +59,74 @@ func (g *GathererWrapped) Gather() ([]*io_prometheus_client.MetricFamily, error)
 					Value: &v,
 				})
 			}
+			// Keep the labels sorted by name, as the registries do.
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+		}
+	}
+
+	return metrics, nil
+}
+
+// labelsRemover is a prometheus.Gatherer that removes labels from all the metrics.
+type labelsRemover struct {
+	gatherer prometheus.Gatherer
+	labels   map[string]string
+}
+
+// newLabelsRemover returns a Gatherer removing the labels named as the keys of labels from the
+// metrics of g.
+func newLabelsRemover(g prometheus.Gatherer, labels map[string]string) *labelsRemover {
+	return &labelsRemover{
+		gatherer: g,
+		labels:   labels,
+	}
+}
+
+// Gather implements prometheus.Gatherer interface.
+func (r *labelsRemover) Gather() ([]*io_prometheus_client.MetricFamily, error) {
+	metrics, err := r.gatherer.Gather()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to gather metrics")
+	}
+
+	for _, metric := range metrics {
+		for _, m := range metric.GetMetric() {
+			kept := m.Label[:0]
+			for _, l := range m.GetLabel() {
+				if _, ok := r.labels[l.GetName()]; !ok {
+					kept = append(kept, l)
+				}
+			}
+			m.Label = kept
 		}
 	}
 
 	return metrics, nil
 }
+
+// partialGatherer is a prometheus.Gatherer that logs the gathering errors and returns the metric
+// families which could be gathered, as the HTTP handler does with promhttp.ContinueOnError.
+type partialGatherer struct {
+	gatherer prometheus.Gatherer
+	logger   *logrus.Logger
+}
+
+// Gather implements prometheus.Gatherer interface.
+func (p *partialGatherer) Gather() ([]*io_prometheus_client.MetricFamily, error) {
+	metrics, err := p.gatherer.Gather()
+	if err != nil {
+		p.logger.Errorf("Some metrics cannot be gathered: %s", err)
+	}
+
+	return metrics, nil
+}
+
+func hasLabel(m *io_prometheus_client.Metric, name string) bool {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return true
+		}
+	}
+
+	return false
+}
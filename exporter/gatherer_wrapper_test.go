@@ -0,0 +1,70 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGathererWrapperKeepsLabels(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_up", "Whether MongoDB is up.", nil, nil),
+			prometheus.GaugeValue, 1),
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_ss_uptime", "uptime", nil, prometheus.Labels{"cl_id": "abc"}),
+			prometheus.GaugeValue, 10),
+	})
+
+	gw := NewGathererWrapper(registry, prometheus.Labels{"instance": "host1:27017", "cl_id": "def"})
+
+	expected := strings.NewReader(`
+# HELP mongodb_ss_uptime uptime
+# TYPE mongodb_ss_uptime gauge
+mongodb_ss_uptime{cl_id="abc",instance="host1:27017"} 10
+# HELP mongodb_up Whether MongoDB is up.
+# TYPE mongodb_up gauge
+mongodb_up{cl_id="def",instance="host1:27017"} 1` + "\n")
+	assert.NoError(t, testutil.GatherAndCompare(gw, expected))
+}
+
+func TestPartialGatherer(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector{
+		prometheus.MustNewConstMetric(prometheus.NewDesc("mongodb_up", "Whether MongoDB is up.", nil, nil),
+			prometheus.GaugeValue, 1),
+		prometheus.NewInvalidMetric(prometheus.NewDesc("mongodb_invalid", "invalid", nil, nil),
+			errors.New("cannot collect")),
+	})
+
+	logger, hook := logrustest.NewNullLogger()
+	expected := strings.NewReader(`
+# HELP mongodb_up Whether MongoDB is up.
+# TYPE mongodb_up gauge
+mongodb_up 1` + "\n")
+	assert.NoError(t, testutil.GatherAndCompare(&partialGatherer{gatherer: registry, logger: logger}, expected))
+	assert.Contains(t, hook.LastEntry().Message, "cannot collect")
+}
@@ -20,8 +20,11 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
 )
 
 // This collector is always enabled and collects general MongoDB connectivity status.
@@ -51,6 +54,45 @@ func (d *generalCollector) Collect(ch chan<- prometheus.Metric) {
 func (d *generalCollector) collect(ch chan<- prometheus.Metric) {
 	defer measureCollectTime(ch, "mongodb", "general")()
 	ch <- mongodbUpMetric(d.ctx, d.base.client, d.nodeType, d.base.logger)
+
+	if d.nodeType == typeOther || d.base.client == nil {
+		return
+	}
+
+	md := proto.MasterDoc{}
+	if d.nodeType != typeArbiter && d.nodeType != typeMongos {
+		if err := d.base.client.Database("admin").RunCommand(d.ctx, primitive.M{"isMaster": 1}).Decode(&md); err != nil {
+			// mongodb_up already reports the unreachable nodes.
+			d.base.logger.Debugf("cannot get the node type: %s", err)
+			return
+		}
+	}
+
+	ch <- nodeTypeMetric(instanceNodeType(d.nodeType, md))
+}
+
+// instanceNodeType returns the role of the node: mongos, arbiter, primary, secondary or
+// standalone, and other for the replica set members in another state (e.g. recovering).
+func instanceNodeType(nodeType mongoDBNodeType, md proto.MasterDoc) string {
+	switch {
+	case nodeType == typeMongos || nodeType == typeArbiter:
+		return string(nodeType)
+	case md.SetName == nil:
+		return "standalone"
+	case md.IsMaster:
+		return "primary"
+	case md.Secondary:
+		return "secondary"
+	default:
+		return "other"
+	}
+}
+
+func nodeTypeMetric(nodeType string) prometheus.Metric { //nolint:ireturn
+	d := prometheus.NewDesc("mongodb_instance_node_type", "The role of the node, set to 1 for its type.", nil,
+		map[string]string{"type": nodeType})
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1)
 }
 
 func mongodbUpMetric(ctx context.Context, client *mongo.Client, nodeType mongoDBNodeType, log *logrus.Entry) prometheus.Metric { //nolint:ireturn
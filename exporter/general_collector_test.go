@@ -21,11 +21,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/percona/mongodb_exporter/internal/proto"
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
 
@@ -116,3 +118,30 @@ func TestGeneralCollector(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestInstanceNodeType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		nodeType mongoDBNodeType
+		md       proto.MasterDoc
+		want     string
+	}{
+		{nodeType: typeMongos, want: "mongos"},
+		{nodeType: typeArbiter, md: proto.MasterDoc{SetName: "rs0"}, want: "arbiter"},
+		{nodeType: typeMongod, md: proto.MasterDoc{IsMaster: true}, want: "standalone"},
+		{nodeType: typeMongod, md: proto.MasterDoc{SetName: "rs0", IsMaster: true}, want: "primary"},
+		{nodeType: typeShardServer, md: proto.MasterDoc{SetName: "rs0", Secondary: true}, want: "secondary"},
+		{nodeType: typeMongod, md: proto.MasterDoc{SetName: "rs0"}, want: "other"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, instanceNodeType(tc.nodeType, tc.md))
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_instance_node_type The role of the node, set to 1 for its type.
+	# TYPE mongodb_instance_node_type gauge
+	mongodb_instance_node_type{type="primary"} 1` + "\n")
+	err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{nodeTypeMetric("primary")}), expected)
+	require.NoError(t, err)
+}
@@ -17,20 +17,35 @@ package exporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Server error codes.
+const (
+	errCodeUnauthorized    = 13 // Missing privileges.
+	errCodeCommandNotFound = 59
+)
+
+// unauthorizedIndexStats keeps the namespaces where $indexStats failed for missing privileges,
+// so the error is logged once instead of on every scrape.
+var unauthorizedIndexStats sync.Map
+
 type indexstatsCollector struct {
 	ctx  context.Context
 	base *baseCollector
 
 	discoveringMode         bool
+	includeViews            bool
 	overrideDescendingIndex bool
 	topologyInfo            labelsGetter
 
@@ -38,12 +53,13 @@ type indexstatsCollector struct {
 }
 
 // newIndexStatsCollector creates a collector for statistics on index usage.
-func newIndexStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, discovery, overrideDescendingIndex bool, topology labelsGetter, collections []string) *indexstatsCollector {
+func newIndexStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, discovery, includeViews, overrideDescendingIndex bool, topology labelsGetter, collections []string) *indexstatsCollector {
 	return &indexstatsCollector{
 		ctx:  ctx,
 		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "indexstats"})),
 
 		discoveringMode:         discovery,
+		includeViews:            includeViews,
 		topologyInfo:            topology,
 		overrideDescendingIndex: overrideDescendingIndex,
 
@@ -75,6 +91,15 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 		}
 
 		collections = fromMapToSlice(onlyCollectionsNamespaces)
+	} else if d.includeViews {
+		// The collstats collector reports the views, they are only skipped here.
+		var err error
+		collections, _, err = splitViews(d.ctx, client, d.collections)
+		if err != nil {
+			logger.Errorf("cannot list views: %s", err.Error())
+
+			return
+		}
 	} else {
 		var err error
 		collections, err = checkNamespacesForViews(d.ctx, client, d.collections)
@@ -85,6 +110,9 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 		}
 	}
 
+	// Reported once, whatever the number of collections failing for missing privileges.
+	var unauthorized prometheus.Metric
+
 	for _, dbCollection := range collections {
 		parts := strings.Split(dbCollection, ".")
 		if len(parts) < 2 { //nolint:gomnd
@@ -105,10 +133,19 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 
 		cursor, err := client.Database(database).Collection(collection).Aggregate(d.ctx, mongo.Pipeline{aggregation})
 		if err != nil {
+			if m := unauthorizedMetric(err, d.base.name, "$indexStats"); m != nil {
+				unauthorized = m
+				if _, logged := unauthorizedIndexStats.LoadOrStore(dbCollection, true); logged {
+					logger.Debugf("cannot get $indexStats cursor for collection %s.%s: %s", database, collection, err)
+
+					continue
+				}
+			}
 			logger.Errorf("cannot get $indexStats cursor for collection %s.%s: %s", database, collection, err)
 
 			continue
 		}
+		unauthorizedIndexStats.Delete(dbCollection)
 
 		var stats []bson.M
 		if err = cursor.All(d.ctx, &stats); err != nil {
@@ -140,8 +177,31 @@ func (d *indexstatsCollector) collect(ch chan<- prometheus.Metric) {
 			for _, metric := range makeMetrics(prefix, metrics, labels, false) {
 				ch <- metric
 			}
+
+			if m := indexAccessStartMetric(metric, labels); m != nil {
+				ch <- m
+			}
+
+			accessLabels := d.topologyInfo.baseLabels()
+			accessLabels["db"] = database
+			accessLabels["collection"] = collection
+			accessLabels["index"] = indexName
+			if m := indexAccessesMetric(metric, accessLabels); m != nil {
+				ch <- m
+			}
+
+			// $indexStats includes the index specification, as listIndexes does, since MongoDB 4.2.
+			if spec, ok := metric["spec"].(bson.M); ok {
+				for _, metric := range indexPropertiesMetrics(spec, labels) {
+					ch <- metric
+				}
+			}
 		}
 	}
+
+	if unauthorized != nil {
+		ch <- unauthorized
+	}
 }
 
 // According to specs, we should expose only this 2 metrics. 'building' might not exist.
@@ -169,4 +229,72 @@ func sanitizeMetrics(m bson.M) bson.M {
 	return filteredMetrics
 }
 
+// indexAccessesMetric returns the number of operations which used the index, or nil if it is
+// unknown. It is mongodb_indexstats_accesses_ops with the db, collection and index labels of the
+// top metrics, to find the unused indexes of a cluster with the same queries.
+func indexAccessesMetric(stat bson.M, labels map[string]string) prometheus.Metric {
+	f, err := asFloat64(walkTo(stat, []string{"accesses", "ops"}))
+	if err != nil || f == nil {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_top_index_accesses_total",
+		"The number of operations that used the index.", nil, labels)
+
+	return prometheus.MustNewConstMetric(d, prometheus.CounterValue, *f)
+}
+
+// indexAccessStartMetric returns the time when the server started counting the operations
+// which used the index, at startup or when the index was created, or nil if it is unknown. The
+// operations are mongodb_indexstats_accesses_ops.
+func indexAccessStartMetric(stat bson.M, labels map[string]string) prometheus.Metric {
+	var since time.Time
+	switch val := walkTo(stat, []string{"accesses", "since"}).(type) {
+	case primitive.DateTime:
+		since = val.Time()
+	case time.Time:
+		since = val
+	}
+	if since.IsZero() {
+		return nil
+	}
+
+	d := prometheus.NewDesc("mongodb_indexstats_accesses_start_time_seconds",
+		"Unix time when the server started counting the operations that used the index.", nil, labels)
+
+	return prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(since.UnixNano())/1e9)
+}
+
+// isUnauthorized returns true if the server rejected the command for missing privileges.
+func isUnauthorized(err error) bool {
+	var serverErr mongo.ServerError
+
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(errCodeUnauthorized)
+}
+
+// indexPropertiesMetrics returns a metric with value 1 for each of the unique, sparse and partial
+// properties set in the index specification.
+func indexPropertiesMetrics(spec bson.M, labels map[string]string) []prometheus.Metric {
+	properties := []struct {
+		name string
+		help string
+		set  bool
+	}{
+		{"mongodb_index_unique", "The index rejects duplicate values for the indexed fields.", spec["unique"] == true},
+		{"mongodb_index_sparse", "The index only has entries for the documents with the indexed fields.", spec["sparse"] == true},
+		{"mongodb_index_partial", "The index only has entries for the documents matching its filter expression.", spec["partialFilterExpression"] != nil},
+	}
+
+	var metrics []prometheus.Metric
+	for _, p := range properties {
+		if !p.set {
+			continue
+		}
+		d := prometheus.NewDesc(p.name, p.help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1))
+	}
+
+	return metrics
+}
+
 var _ prometheus.Collector = (*indexstatsCollector)(nil)
@@ -17,15 +17,18 @@ package exporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/AlekSi/pointer"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -64,12 +67,12 @@ func TestIndexStatsCollector(t *testing.T) {
 	}
 
 	collection := []string{"testdb.testcol_00", "testdb.testcol_01", "testdb.testcol_02"}
-	c := newIndexStatsCollector(ctx, client, logrus.New(), false, true, ti, collection)
+	c := newIndexStatsCollector(ctx, client, logrus.New(), false, false, true, ti, collection)
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
 # HELP mongodb_indexstats_accesses_ops indexstats.accesses.ops
-# TYPE mongodb_indexstats_accesses_ops untyped
+# TYPE mongodb_indexstats_accesses_ops counter
 mongodb_indexstats_accesses_ops{collection="testcol_00",database="testdb",key_name="_id_"} 0
 mongodb_indexstats_accesses_ops{collection="testcol_00",database="testdb",key_name="idx_01"} 0
 mongodb_indexstats_accesses_ops{collection="testcol_01",database="testdb",key_name="_id_"} 0
@@ -114,12 +117,12 @@ func TestDescendingIndexOverride(t *testing.T) {
 	}
 
 	collection := []string{"testdb.testcol_00", "testdb.testcol_01", "testdb.testcol_02"}
-	c := newIndexStatsCollector(ctx, client, logrus.New(), false, true, ti, collection)
+	c := newIndexStatsCollector(ctx, client, logrus.New(), false, false, true, ti, collection)
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
   # HELP mongodb_indexstats_accesses_ops indexstats.accesses.ops
-  # TYPE mongodb_indexstats_accesses_ops untyped
+  # TYPE mongodb_indexstats_accesses_ops counter
   mongodb_indexstats_accesses_ops{collection="testcol_00",database="testdb",key_name="_id_"} 0
   mongodb_indexstats_accesses_ops{collection="testcol_00",database="testdb",key_name="f1_1"} 0
   mongodb_indexstats_accesses_ops{collection="testcol_00",database="testdb",key_name="f1_DESC"} 0
@@ -198,3 +201,132 @@ func TestSanitize(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 }
+
+func TestIndexPropertiesMetrics(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"database": "testdb", "collection": "testcol", "key_name": "idx_01"}
+
+	metrics := indexPropertiesMetrics(bson.M{"v": int32(2), "key": bson.M{"f1": int32(1)}, "name": "idx_01"}, labels)
+	assert.Empty(t, metrics)
+
+	metrics = indexPropertiesMetrics(bson.M{
+		"name":                    "idx_01",
+		"unique":                  true,
+		"sparse":                  false,
+		"partialFilterExpression": bson.M{"f2": bson.M{"$exists": true}},
+	}, labels)
+
+	expected := strings.NewReader(`
+# HELP mongodb_index_partial The index only has entries for the documents matching its filter expression.
+# TYPE mongodb_index_partial gauge
+mongodb_index_partial{collection="testcol",database="testdb",key_name="idx_01"} 1
+# HELP mongodb_index_unique The index rejects duplicate values for the indexed fields.
+# TYPE mongodb_index_unique gauge
+mongodb_index_unique{collection="testcol",database="testdb",key_name="idx_01"} 1` + "\n")
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
+
+func TestIndexStatsCollectorIndexProperties(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	ti := labelsGetterMock{}
+
+	database := client.Database("testdb")
+	database.Drop(ctx)       //nolint:errcheck
+	defer database.Drop(ctx) //nolint:errcheck
+
+	_, err := database.Collection("testcol").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"f1": 1}, Options: options.Index().SetName("idx_unique").SetUnique(true)},
+		{Keys: bson.M{"f2": 1}, Options: options.Index().SetName("idx_sparse").SetSparse(true)},
+		{Keys: bson.M{"f3": 1}, Options: options.Index().SetName("idx_plain")},
+	})
+	assert.NoError(t, err)
+
+	c := newIndexStatsCollector(ctx, client, logrus.New(), false, false, false, ti, []string{"testdb.testcol"})
+
+	// The last \n at the end of this string is important
+	expected := strings.NewReader(`
+# HELP mongodb_index_sparse The index only has entries for the documents with the indexed fields.
+# TYPE mongodb_index_sparse gauge
+mongodb_index_sparse{collection="testcol",database="testdb",key_name="idx_sparse"} 1
+# HELP mongodb_index_unique The index rejects duplicate values for the indexed fields.
+# TYPE mongodb_index_unique gauge
+mongodb_index_unique{collection="testcol",database="testdb",key_name="idx_unique"} 1` +
+		"\n")
+
+	filter := []string{
+		"mongodb_index_sparse",
+		"mongodb_index_unique",
+	}
+	err = testutil.CollectAndCompare(c, expected, filter...)
+	assert.NoError(t, err)
+}
+
+func TestIndexAccessStartMetric(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"database": "testdb", "collection": "testcol", "key_name": "idx_01"}
+
+	since := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	metric := indexAccessStartMetric(bson.M{
+		"name":     "idx_01",
+		"accesses": bson.M{"ops": int64(42), "since": primitive.NewDateTimeFromTime(since)},
+	}, labels)
+
+	expected := strings.NewReader(`
+# HELP mongodb_indexstats_accesses_start_time_seconds Unix time when the server started counting the operations that used the index.
+# TYPE mongodb_indexstats_accesses_start_time_seconds gauge
+mongodb_indexstats_accesses_start_time_seconds{collection="testcol",database="testdb",key_name="idx_01"} 1.7145576e+09` + "\n")
+	err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected)
+	assert.NoError(t, err)
+
+	assert.Nil(t, indexAccessStartMetric(bson.M{"name": "idx_01", "accesses": bson.M{"ops": "n/a"}}, labels))
+}
+
+func TestIndexAccessesMetric(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"db": "testdb", "collection": "testcol", "index": "idx_01"}
+	metric := indexAccessesMetric(bson.M{"name": "idx_01", "accesses": bson.M{"ops": int64(42)}}, labels)
+
+	expected := strings.NewReader(`
+# HELP mongodb_top_index_accesses_total The number of operations that used the index.
+# TYPE mongodb_top_index_accesses_total counter
+mongodb_top_index_accesses_total{collection="testcol",db="testdb",index="idx_01"} 42` + "\n")
+	err := testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected)
+	assert.NoError(t, err)
+
+	assert.Nil(t, indexAccessesMetric(bson.M{"name": "idx_01"}, labels))
+	assert.Nil(t, indexAccessesMetric(bson.M{"name": "idx_01", "accesses": bson.M{"ops": "n/a"}}, labels))
+}
+
+func TestIsUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isUnauthorized(mongo.CommandError{Code: 13, Name: "Unauthorized"}))
+	assert.False(t, isUnauthorized(mongo.CommandError{Code: 26, Name: "NamespaceNotFound"}))
+	assert.False(t, isUnauthorized(errors.New("connection refused")))
+}
+
+func TestUnauthorizedMetric(t *testing.T) {
+	t.Parallel()
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collector_unauthorized Whether the last scrape of the collector failed to run the command for missing privileges
+	# TYPE mongodb_collector_unauthorized gauge
+	mongodb_collector_unauthorized{collector="indexstats",command="$indexStats"} 1` + "\n")
+
+	// The collectors wrap the driver errors.
+	err := fmt.Errorf("cannot get cursor: %w", mongo.CommandError{Code: 13, Name: "Unauthorized"})
+	m := unauthorizedMetric(err, "indexstats", "$indexStats")
+	require.NotNil(t, m)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{m}), expected))
+
+	assert.Nil(t, unauthorizedMetric(mongo.CommandError{Code: 26, Name: "NamespaceNotFound"}, "indexstats", "$indexStats"))
+	assert.Nil(t, unauthorizedMetric(errors.New("connection refused"), "indexstats", "$indexStats"))
+}
@@ -157,6 +157,20 @@ var (
 		"collstats.storageStats.indexDetails.":                     "index_name",
 	}
 
+	// Fields which are cumulative in MongoDB, exported as counters so rate() can be used on
+	// them. The keys are the paths of the fields, including the prefix of the collector.
+	counterMetrics = map[string]bool{
+		"collstats.latencyStats.reads.ops":            true,
+		"collstats.latencyStats.reads.latency":        true,
+		"collstats.latencyStats.writes.ops":           true,
+		"collstats.latencyStats.writes.latency":       true,
+		"collstats.latencyStats.commands.ops":         true,
+		"collstats.latencyStats.commands.latency":     true,
+		"collstats.latencyStats.transactions.ops":     true,
+		"collstats.latencyStats.transactions.latency": true,
+		"indexstats.accesses.ops":                     true,
+	}
+
 	// Regular expressions used to make the metric name Prometheus-compatible
 	// This variables are global to compile the regexps only once.
 	specialCharsRe        = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
@@ -209,7 +223,7 @@ func makeRawMetric(prefix, name string, value interface{}, labels map[string]str
 	fqName, label := nameAndLabel(prefix, name)
 
 	metricType := prometheus.UntypedValue
-	if strings.HasSuffix(strings.ToLower(name), "count") {
+	if strings.HasSuffix(strings.ToLower(name), "count") || counterMetrics[prefix+name] {
 		metricType = prometheus.CounterValue
 	}
 
@@ -405,6 +419,7 @@ type conversion struct {
 	prefix                string
 	suffixLabel           string
 	suffixMapping         map[string]string
+	valueType             prometheus.ValueType // type of the old metric, if not the one of the current metric
 }
 
 func metricRenameAndLabel(rm *rawMetric, convs []conversion) []*rawMetric {
@@ -492,3 +507,42 @@ var specialConversions = []conversion{ //nolint:gochecknoglobals
 		},
 	},
 }
+
+// documentMetric describes a metric made from the value at path in a document.
+type documentMetric struct {
+	name      string
+	help      string
+	path      []string
+	valueType prometheus.ValueType
+	labels    map[string]string
+}
+
+// documentMetrics returns the metrics of the defs whose value is in doc, with the labels of
+// each def added to the given ones. The missing and non numeric values are skipped.
+func documentMetrics(doc bson.M, defs []documentMetric, labels map[string]string) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, len(defs))
+	for _, def := range defs {
+		f, err := asFloat64(walkTo(doc, def.path))
+		if err != nil || f == nil {
+			continue
+		}
+
+		l := make(map[string]string, len(labels)+len(def.labels))
+		for k, v := range labels {
+			l[k] = v
+		}
+		for k, v := range def.labels {
+			l[k] = v
+		}
+
+		d := prometheus.NewDesc(def.name, def.help, nil, l)
+		metric, err := prometheus.NewConstMetric(d, def.valueType, *f)
+		if err != nil {
+			continue
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
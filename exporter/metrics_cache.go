@@ -0,0 +1,160 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+var errNoFreshMetrics = errors.New("no cached metrics within the maximum staleness")
+
+// metricsCache serves the metrics of the previous scrape while gathering new ones in the
+// background (stale-while-revalidate), so the scrape latency doesn't depend on the latency
+// of the MongoDB commands. Cached metrics older than maxStaleness are never served. Cached
+// metrics younger than ttl are served without a refresh, so frequent scrapes don't run the
+// collectors more often than every ttl.
+type metricsCache struct {
+	maxStaleness time.Duration
+	ttl          time.Duration
+	gather       func(ctx context.Context, requestOpts Opts) ([]*dto.MetricFamily, error)
+	logger       *logrus.Logger
+	now          func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*metricsCacheEntry
+}
+
+// metricsCacheEntry holds the metrics gathered for one set of request options.
+type metricsCacheEntry struct {
+	families []*dto.MetricFamily
+	updated  time.Time
+
+	// done is closed when the running refresh ends, nil if there is none.
+	done chan struct{}
+}
+
+func newMetricsCache(maxStaleness, ttl time.Duration, logger *logrus.Logger,
+	gather func(ctx context.Context, requestOpts Opts) ([]*dto.MetricFamily, error),
+) *metricsCache {
+	return &metricsCache{
+		maxStaleness: maxStaleness,
+		ttl:          ttl,
+		gather:       gather,
+		logger:       logger,
+		now:          time.Now,
+		entries:      make(map[string]*metricsCacheEntry),
+	}
+}
+
+// get returns the cached metrics for key and their age. Unless they are younger than the TTL,
+// it starts a background refresh. If there are no cached metrics or they are older than the
+// maximum staleness, it waits for the refresh until ctx is done. Failed refreshes keep the
+// previous metrics.
+func (c *metricsCache) get(ctx context.Context, key string, requestOpts Opts, timeout time.Duration) ([]*dto.MetricFamily, time.Duration, error) {
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = new(metricsCacheEntry)
+		c.entries[key] = entry
+	}
+
+	families, age, fresh := c.cached(entry)
+	if fresh && age < c.ttl {
+		c.lock.Unlock()
+		return families, age, nil
+	}
+
+	if entry.done == nil {
+		entry.done = make(chan struct{})
+		go c.refresh(entry, requestOpts, timeout)
+	}
+
+	done := entry.done
+	c.lock.Unlock()
+
+	if fresh {
+		return families, age, nil
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if families, age, fresh = c.cached(entry); !fresh {
+		return nil, 0, errNoFreshMetrics
+	}
+
+	return families, age, nil
+}
+
+// cached returns the metrics of the entry, which must be locked, and whether they can be served.
+func (c *metricsCache) cached(entry *metricsCacheEntry) ([]*dto.MetricFamily, time.Duration, bool) {
+	if entry.families == nil {
+		return nil, 0, false
+	}
+
+	age := c.now().Sub(entry.updated)
+
+	return entry.families, age, age <= c.maxStaleness
+}
+
+func (c *metricsCache) refresh(entry *metricsCacheEntry, requestOpts Opts, timeout time.Duration) {
+	// The scrape which started the refresh doesn't wait for it, so it cannot use its context.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	families, err := c.gather(ctx, requestOpts)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err != nil {
+		c.logger.Warnf("Cannot refresh the cached metrics, keeping the previous ones: %s", err)
+	} else {
+		entry.families = families
+		entry.updated = c.now()
+	}
+
+	close(entry.done)
+	entry.done = nil
+}
+
+// cacheAgeGatherer returns the mongodb_exporter_cache_age_seconds metric for the served metrics.
+func cacheAgeGatherer(age time.Duration, constantLabels map[string]string) prometheus.Gatherer {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "mongodb_exporter_cache_age_seconds",
+		Help:        "Age of the cached metrics served by this scrape, in seconds.",
+		ConstLabels: constantLabels,
+	})
+	gauge.Set(age.Seconds())
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(gauge)
+
+	return registry
+}
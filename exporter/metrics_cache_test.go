@@ -0,0 +1,202 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeGather returns metric families named after the number of calls, blocking each call
+// until release is signaled. It fails while failing is set.
+type fakeGather struct {
+	calls   int32
+	running int32
+	failing int32
+	release chan struct{}
+}
+
+func (g *fakeGather) gather(_ context.Context, _ Opts) ([]*dto.MetricFamily, error) {
+	n := atomic.AddInt32(&g.calls, 1)
+	if atomic.AddInt32(&g.running, 1) > 1 {
+		panic("concurrent refreshes")
+	}
+	defer atomic.AddInt32(&g.running, -1)
+
+	<-g.release
+
+	if atomic.LoadInt32(&g.failing) == 1 {
+		return nil, errors.New("cannot connect")
+	}
+
+	return []*dto.MetricFamily{{Name: proto.String("scrape_" + string(rune('0'+n)))}}, nil
+}
+
+func TestMetricsCache(t *testing.T) {
+	t.Parallel()
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	g := &fakeGather{release: make(chan struct{})}
+	var clock int64 // seconds
+	c := newMetricsCache(10*time.Second, 0, logger, g.gather)
+	c.now = func() time.Time { return time.Unix(atomic.LoadInt64(&clock), 0) }
+
+	ctx := context.Background()
+
+	// Nothing is cached yet, all the concurrent scrapes wait for the same refresh.
+	var wg, started sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			families, age, err := c.get(ctx, "", Opts{}, time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, time.Duration(0), age)
+			if assert.Len(t, families, 1) {
+				assert.Equal(t, "scrape_1", families[0].GetName())
+			}
+		}()
+	}
+	started.Wait()
+	time.Sleep(50 * time.Millisecond) // let all the scrapes wait for the refresh
+	g.release <- struct{}{}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&g.calls))
+
+	// Cached metrics are served immediately while the next refresh runs in the background.
+	atomic.StoreInt64(&clock, 5)
+	for i := 0; i < 10; i++ {
+		families, age, err := c.get(ctx, "", Opts{}, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Second, age)
+		assert.Equal(t, "scrape_1", families[0].GetName())
+	}
+	g.release <- struct{}{}
+	require.Eventually(t, func() bool {
+		families, _, err := c.get(ctx, "", Opts{}, time.Second)
+		return err == nil && families[0].GetName() == "scrape_2"
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&g.running) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&g.calls))
+
+	// A failed refresh keeps the served metrics.
+	atomic.StoreInt32(&g.failing, 1)
+	g.release <- struct{}{}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&g.running) == 0 }, time.Second, time.Millisecond)
+	families, _, err := c.get(ctx, "", Opts{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "scrape_2", families[0].GetName())
+
+	// Metrics older than the maximum staleness are never served, the scrapes wait for the
+	// running refresh, bounded by the request context.
+	atomic.StoreInt64(&clock, 30)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, err = c.get(cctx, "", Opts{}, time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() { g.release <- struct{}{} }()
+	_, _, err = c.get(ctx, "", Opts{}, time.Second)
+	assert.ErrorIs(t, err, errNoFreshMetrics)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&g.calls))
+}
+
+func TestCacheAgeGatherer(t *testing.T) {
+	t.Parallel()
+
+	expected := strings.NewReader(`
+	# HELP mongodb_exporter_cache_age_seconds Age of the cached metrics served by this scrape, in seconds.
+	# TYPE mongodb_exporter_cache_age_seconds gauge
+	mongodb_exporter_cache_age_seconds 1.5` + "\n")
+	err := testutil.GatherAndCompare(cacheAgeGatherer(1500*time.Millisecond, nil), expected)
+	assert.NoError(t, err)
+}
+
+func TestMetricsCacheKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", metricsCacheKey(nil))
+	assert.Equal(t, "dbstats,diagnosticdata", metricsCacheKey([]string{"diagnosticdata", "dbstats", "diagnosticdata"}))
+}
+
+func TestMetricsCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	g := &fakeGather{release: make(chan struct{})}
+	var clock int64 // seconds
+	c := newMetricsCache(30*time.Second, 10*time.Second, logger, g.gather)
+	c.now = func() time.Time { return time.Unix(atomic.LoadInt64(&clock), 0) }
+
+	ctx := context.Background()
+
+	refreshing := func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return c.entries[""].done != nil
+	}
+
+	go func() { g.release <- struct{}{} }()
+	families, _, err := c.get(ctx, "", Opts{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "scrape_1", families[0].GetName())
+	require.Eventually(t, func() bool { return !refreshing() }, time.Second, time.Millisecond)
+
+	// Within the TTL, the cached metrics are served without a refresh.
+	atomic.StoreInt64(&clock, 9)
+	for i := 0; i < 10; i++ {
+		families, age, err := c.get(ctx, "", Opts{}, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, 9*time.Second, age)
+		assert.Equal(t, "scrape_1", families[0].GetName())
+	}
+	assert.False(t, refreshing())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&g.calls))
+
+	// After the TTL, they are still served but refreshed in the background.
+	atomic.StoreInt64(&clock, 10)
+	families, _, err = c.get(ctx, "", Opts{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "scrape_1", families[0].GetName())
+	assert.True(t, refreshing())
+
+	g.release <- struct{}{}
+	require.Eventually(t, func() bool { return !refreshing() }, time.Second, time.Millisecond)
+	families, age, err := c.get(ctx, "", Opts{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), age)
+	assert.Equal(t, "scrape_2", families[0].GetName())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&g.calls))
+}
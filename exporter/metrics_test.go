@@ -16,12 +16,15 @@
 package exporter
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/AlekSi/pointer"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -173,6 +176,39 @@ func TestMakeRawMetric(t *testing.T) {
 	}
 }
 
+func TestCounterMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := bson.M{
+		"latencyStats": bson.M{
+			"reads": bson.M{"ops": int64(120), "latency": int64(4500)},
+		},
+		"storageStats": bson.M{"size": int64(4096)},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_collstats_latencyStats_reads_latency collstats.latencyStats.reads.latency
+	# TYPE mongodb_collstats_latencyStats_reads_latency counter
+	mongodb_collstats_latencyStats_reads_latency{collection="orders"} 4500
+	# HELP mongodb_collstats_latencyStats_reads_ops collstats.latencyStats.reads.ops
+	# TYPE mongodb_collstats_latencyStats_reads_ops counter
+	mongodb_collstats_latencyStats_reads_ops{collection="orders"} 120
+	# HELP mongodb_collstats_storageStats_size collstats.storageStats.size
+	# TYPE mongodb_collstats_storageStats_size untyped
+	mongodb_collstats_storageStats_size{collection="orders"} 4096` + "\n")
+
+	metrics := makeMetrics("collstats", stats, map[string]string{"collection": "orders"}, false)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	expected = strings.NewReader(`
+	# HELP mongodb_indexstats_accesses_ops indexstats.accesses.ops
+	# TYPE mongodb_indexstats_accesses_ops counter
+	mongodb_indexstats_accesses_ops{key_name="_id_"} 37` + "\n")
+
+	metrics = makeMetrics("indexstats", bson.M{"accesses": bson.M{"ops": float64(37)}}, map[string]string{"key_name": "_id_"}, false)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+}
+
 func TestRawToCompatibleRawMetric(t *testing.T) {
 	testCases := []struct {
 		in   *rawMetric
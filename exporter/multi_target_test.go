@@ -26,6 +26,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -35,29 +36,27 @@ func TestMultiTarget(t *testing.T) {
 	opts := []*Opts{
 		{
 			URI:              fmt.Sprintf("mongodb://%s", net.JoinHostPort(hostname, tu.GetenvDefault("TEST_MONGODB_STANDALONE_PORT", "27017"))),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 		{
 			URI:              fmt.Sprintf("mongodb://%s", net.JoinHostPort(hostname, tu.GetenvDefault("TEST_MONGODB_S1_PRIMARY_PORT", "17001"))),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 		{
 			URI:              fmt.Sprintf("mongodb://%s", net.JoinHostPort(hostname, tu.GetenvDefault("TEST_MONGODB_S2_PRIMARY_PORT", "17004"))),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 		{
 			URI:              fmt.Sprintf("mongodb://%s", net.JoinHostPort(hostname, "12345")),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 	}
 	exporters := make([]*Exporter, len(opts))
 
 	for i, opt := range opts {
-		exporters[i] = New(opt)
+		e, err := New(opt)
+		require.NoError(t, err)
+		exporters[i] = e
 	}
 	log := logrus.New()
 	serverMap := buildServerMap(exporters, log)
@@ -82,25 +81,21 @@ func TestOverallHandler(t *testing.T) {
 		{
 			NodeName:         "standalone",
 			URI:              fmt.Sprintf("mongodb://127.0.0.1:%s", tu.GetenvDefault("TEST_MONGODB_STANDALONE_PORT", "27017")),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 		{
 			NodeName:         "s1",
 			URI:              fmt.Sprintf("mongodb://127.0.0.1:%s", tu.GetenvDefault("TEST_MONGODB_S1_PRIMARY_PORT", "17001")),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 		{
 			NodeName:         "s2",
 			URI:              fmt.Sprintf("mongodb://127.0.0.1:%s", tu.GetenvDefault("TEST_MONGODB_S2_PRIMARY_PORT", "17004")),
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 		{
 			NodeName:         "s3",
 			URI:              "mongodb://127.0.0.1:12345",
-			DirectConnect:    true,
 			ConnectTimeoutMS: 1000,
 		},
 	}
@@ -115,7 +110,9 @@ func TestOverallHandler(t *testing.T) {
 	logger := logrus.New()
 
 	for i, opt := range opts {
-		exporters[i] = New(opt)
+		e, err := New(opt)
+		require.NoError(t, err)
+		exporters[i] = e
 	}
 
 	rr := httptest.NewRecorder()
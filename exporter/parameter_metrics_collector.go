@@ -0,0 +1,181 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultParameterMetricsNames are the server parameters exported when no names are configured:
+// the storage engine tickets, named storageEngine* since MongoDB 7.0, and the intervals of
+// the checkpoints and the TTL monitor.
+//
+//nolint:gochecknoglobals
+var defaultParameterMetricsNames = []string{
+	"wiredTigerConcurrentReadTransactions",
+	"wiredTigerConcurrentWriteTransactions",
+	"storageEngineConcurrentReadTransactions",
+	"storageEngineConcurrentWriteTransactions",
+	"syncdelay",
+	"ttlMonitorSleepSecs",
+}
+
+type parameterMetricsCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	names        []string
+	topologyInfo labelsGetter
+}
+
+// newParameterMetricsCollector creates a collector for the runtime values of the named server
+// parameters and the options the server was started with.
+func newParameterMetricsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, names []string, topology labelsGetter) *parameterMetricsCollector {
+	if len(names) == 0 {
+		names = defaultParameterMetricsNames
+	}
+
+	return &parameterMetricsCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "parametermetrics"})),
+		names:        names,
+		topologyInfo: topology,
+	}
+}
+
+func (d *parameterMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *parameterMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *parameterMetricsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "parametermetrics")()
+
+	logger := d.base.logger
+	client := d.base.client
+	labels := d.topologyInfo.baseLabels()
+
+	// Parameters unknown to the server version are left out of the response.
+	cmd := bson.D{{Key: "getParameter", Value: 1}}
+	for _, name := range d.names {
+		cmd = append(cmd, bson.E{Key: name, Value: 1})
+	}
+
+	var params bson.M
+	if err := client.Database("admin").RunCommand(d.ctx, cmd).Decode(&params); err != nil {
+		logger.Errorf("cannot get server parameters: %s", err)
+	} else {
+		for _, metric := range parameterValueMetrics(params, d.names, labels) {
+			ch <- metric
+		}
+	}
+
+	var opts bson.M
+	if err := client.Database("admin").RunCommand(d.ctx, bson.D{{Key: "getCmdLineOpts", Value: 1}}).Decode(&opts); err != nil {
+		logger.Errorf("cannot get command line options: %s", err)
+		return
+	}
+
+	logger.Debug("getCmdLineOpts result")
+	debugResult(logger, opts)
+
+	if parsed, ok := opts["parsed"].(bson.M); ok {
+		for _, metric := range configMetrics(parsed, labels) {
+			ch <- metric
+		}
+	}
+}
+
+// parameterValueMetrics returns the numeric values of the named parameters in the getParameter
+// response. Parameters set as strings, like the ones in the configuration file, are parsed.
+func parameterValueMetrics(m bson.M, names []string, labels map[string]string) []prometheus.Metric {
+	desc := prometheus.NewDesc("mongodb_mongod_parameter", "Runtime value of the server parameter, from getParameter",
+		[]string{"name"}, labels)
+
+	var metrics []prometheus.Metric
+	for _, name := range names {
+		var value float64
+		switch v := m[name].(type) {
+		case nil:
+			continue
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			value = f
+		default:
+			f, err := asFloat64(v)
+			if err != nil || f == nil {
+				continue
+			}
+			value = *f
+		}
+
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, name))
+	}
+
+	return metrics
+}
+
+// configMetrics returns an info metric for each option of the parsed configuration in the
+// getCmdLineOpts response, with the path of the option, e.g. storage.wiredTiger.engineConfig.cacheSizeGB,
+// and its value. Passwords are redacted by the server, the options named password are skipped anyway.
+func configMetrics(parsed bson.M, labels map[string]string) []prometheus.Metric {
+	desc := prometheus.NewDesc("mongodb_mongod_config_info",
+		"Options of the configuration file and command line of the server, from getCmdLineOpts. The value is always 1",
+		[]string{"option", "value"}, labels)
+
+	var metrics []prometheus.Metric
+
+	var walk func(prefix string, m bson.M)
+	walk = func(prefix string, m bson.M) {
+		for key, value := range m {
+			option := prefix + key
+			if strings.Contains(strings.ToLower(key), "password") {
+				continue
+			}
+
+			switch v := value.(type) {
+			case bson.M:
+				walk(option+".", v)
+			case bson.A:
+				values := make([]string, 0, len(v))
+				for _, e := range v {
+					values = append(values, fmt.Sprint(e))
+				}
+				metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, option, strings.Join(values, ",")))
+			default:
+				metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, option, fmt.Sprint(v)))
+			}
+		}
+	}
+	walk("", parsed)
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*parameterMetricsCollector)(nil)
@@ -0,0 +1,86 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParameterValueMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"wiredTigerConcurrentReadTransactions":  int32(128),
+		"wiredTigerConcurrentWriteTransactions": "64",
+		"syncdelay":                             float64(60),
+		"wiredTigerEngineRuntimeConfig":         "cache_size=2G",
+		"ok":                                    float64(1),
+	}
+	names := []string{
+		"wiredTigerConcurrentReadTransactions",
+		"wiredTigerConcurrentWriteTransactions",
+		"syncdelay",
+		"wiredTigerEngineRuntimeConfig",
+		"ttlMonitorSleepSecs",
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_parameter Runtime value of the server parameter, from getParameter
+	# TYPE mongodb_mongod_parameter gauge
+	mongodb_mongod_parameter{name="syncdelay",rs_nm="rs"} 60
+	mongodb_mongod_parameter{name="wiredTigerConcurrentReadTransactions",rs_nm="rs"} 128
+	mongodb_mongod_parameter{name="wiredTigerConcurrentWriteTransactions",rs_nm="rs"} 64` + "\n")
+
+	metrics := parameterValueMetrics(m, names, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+}
+
+func TestConfigMetrics(t *testing.T) {
+	t.Parallel()
+
+	parsed := bson.M{
+		"net": bson.M{"port": int32(27017), "bindIp": "0.0.0.0"},
+		"storage": bson.M{
+			"wiredTiger": bson.M{"engineConfig": bson.M{"cacheSizeGB": float64(1.5)}},
+		},
+		"setParameter": bson.M{"wiredTigerConcurrentReadTransactions": "128"},
+		"security": bson.M{
+			"authorization": "enabled",
+			"ldap":          bson.M{"bind": bson.M{"queryPassword": "<password>"}},
+		},
+		"replication": bson.M{"replSetName": "rs"},
+		"systemLog":   bson.M{"component": bson.A{"query", "storage"}},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_config_info Options of the configuration file and command line of the server, from getCmdLineOpts. The value is always 1
+	# TYPE mongodb_mongod_config_info gauge
+	mongodb_mongod_config_info{option="net.bindIp",value="0.0.0.0"} 1
+	mongodb_mongod_config_info{option="net.port",value="27017"} 1
+	mongodb_mongod_config_info{option="replication.replSetName",value="rs"} 1
+	mongodb_mongod_config_info{option="security.authorization",value="enabled"} 1
+	mongodb_mongod_config_info{option="setParameter.wiredTigerConcurrentReadTransactions",value="128"} 1
+	mongodb_mongod_config_info{option="storage.wiredTiger.engineConfig.cacheSizeGB",value="1.5"} 1
+	mongodb_mongod_config_info{option="systemLog.component",value="query,storage"} 1` + "\n")
+
+	metrics := configMetrics(parsed, nil)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+}
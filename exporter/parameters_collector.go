@@ -0,0 +1,150 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// serverParameter is a getParameter value exposed as a gauge.
+type serverParameter struct {
+	name   string // name of the server parameter
+	metric string
+	help   string
+	scale  float64 // multiplies the value, e.g. to convert milliseconds to seconds
+}
+
+// serverParameters are the server parameters exported, limits affecting the behavior of the workload.
+//
+//nolint:gochecknoglobals
+var serverParameters = []serverParameter{
+	{
+		name:   "internalQueryMaxBlockingSortMemoryUsageBytes",
+		metric: "mongodb_parameter_internal_query_max_blocking_sort_memory_bytes",
+		help:   "Memory limit of blocking sorts, in bytes.",
+		scale:  1,
+	},
+	{
+		// Name of internalQueryMaxBlockingSortMemoryUsageBytes before MongoDB 4.4.
+		name:   "internalQueryExecMaxBlockingSortBytes",
+		metric: "mongodb_parameter_internal_query_max_blocking_sort_memory_bytes",
+		help:   "Memory limit of blocking sorts, in bytes.",
+		scale:  1,
+	},
+	{
+		name:   "internalDocumentSourceGroupMaxMemoryBytes",
+		metric: "mongodb_parameter_internal_document_source_group_max_memory_bytes",
+		help:   "Memory limit of the $group aggregation stage, in bytes.",
+		scale:  1,
+	},
+	{
+		name:   "maxBSONDepth",
+		metric: "mongodb_parameter_max_bson_depth",
+		help:   "Maximum nesting depth of BSON documents.",
+		scale:  1,
+	},
+	{
+		name:   "cursorTimeoutMillis",
+		metric: "mongodb_parameter_cursor_timeout_seconds",
+		help:   "Idle time after which the cursors are closed, in seconds.",
+		scale:  0.001,
+	},
+	{
+		name:   "transactionLifetimeLimitSeconds",
+		metric: "mongodb_parameter_transaction_lifetime_limit_seconds",
+		help:   "Lifetime of the multi-document transactions, in seconds.",
+		scale:  1,
+	},
+}
+
+type parametersCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	topologyInfo labelsGetter
+}
+
+// newParametersCollector creates a collector for the server parameters limiting the workload.
+func newParametersCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, topology labelsGetter) *parametersCollector {
+	return &parametersCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "parameters"})),
+		topologyInfo: topology,
+	}
+}
+
+func (d *parametersCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *parametersCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *parametersCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "parameters")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// Parameters unknown to the server version are left out of the response.
+	cmd := bson.D{{Key: "getParameter", Value: 1}}
+	for _, p := range serverParameters {
+		cmd = append(cmd, bson.E{Key: p.name, Value: 1})
+	}
+
+	var m bson.M
+	if err := client.Database("admin").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		logger.Errorf("cannot get server parameters: %s", err)
+		return
+	}
+
+	logger.Debug("getParameter result")
+	debugResult(logger, m)
+
+	for _, metric := range parametersMetrics(m, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// parametersMetrics returns the metrics of the server parameters in the getParameter response.
+func parametersMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	var metrics []prometheus.Metric
+	seen := make(map[string]bool)
+	for _, p := range serverParameters {
+		v, ok := m[p.name]
+		if !ok || seen[p.metric] {
+			continue
+		}
+
+		f, err := asFloat64(v)
+		if err != nil || f == nil {
+			continue
+		}
+		seen[p.metric] = true
+
+		desc := prometheus.NewDesc(p.metric, p.help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, *f*p.scale))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*parametersCollector)(nil)
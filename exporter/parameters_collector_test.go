@@ -0,0 +1,82 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestParametersCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	c := newParametersCollector(ctx, client, logrus.New(), labelsGetterMock{})
+
+	// maxBSONDepth has the same default in all the supported versions.
+	expected := strings.NewReader(`
+# HELP mongodb_parameter_max_bson_depth Maximum nesting depth of BSON documents.
+# TYPE mongodb_parameter_max_bson_depth gauge
+mongodb_parameter_max_bson_depth 200` + "\n")
+	err := testutil.CollectAndCompare(c, expected, "mongodb_parameter_max_bson_depth")
+	assert.NoError(t, err)
+}
+
+func TestParametersMetrics(t *testing.T) {
+	t.Parallel()
+
+	// Response of a server without internalDocumentSourceGroupMaxMemoryBytes and with both names
+	// of the blocking sort limit.
+	m := bson.M{
+		"internalQueryMaxBlockingSortMemoryUsageBytes": int64(104857600),
+		"internalQueryExecMaxBlockingSortBytes":        int32(33554432),
+		"maxBSONDepth":                                 int32(200),
+		"cursorTimeoutMillis":                          int64(600000),
+		"transactionLifetimeLimitSeconds":              int32(60),
+		"ok":                                           float64(1),
+	}
+
+	expected := strings.NewReader(`
+# HELP mongodb_parameter_cursor_timeout_seconds Idle time after which the cursors are closed, in seconds.
+# TYPE mongodb_parameter_cursor_timeout_seconds gauge
+mongodb_parameter_cursor_timeout_seconds{rs_nm="rs1"} 600
+# HELP mongodb_parameter_internal_query_max_blocking_sort_memory_bytes Memory limit of blocking sorts, in bytes.
+# TYPE mongodb_parameter_internal_query_max_blocking_sort_memory_bytes gauge
+mongodb_parameter_internal_query_max_blocking_sort_memory_bytes{rs_nm="rs1"} 1.048576e+08
+# HELP mongodb_parameter_max_bson_depth Maximum nesting depth of BSON documents.
+# TYPE mongodb_parameter_max_bson_depth gauge
+mongodb_parameter_max_bson_depth{rs_nm="rs1"} 200
+# HELP mongodb_parameter_transaction_lifetime_limit_seconds Lifetime of the multi-document transactions, in seconds.
+# TYPE mongodb_parameter_transaction_lifetime_limit_seconds gauge
+mongodb_parameter_transaction_lifetime_limit_seconds{rs_nm="rs1"} 60` + "\n")
+
+	metrics := parametersMetrics(m, map[string]string{"rs_nm": "rs1"})
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+
+	assert.Empty(t, parametersMetrics(bson.M{"ok": float64(1)}, nil))
+}
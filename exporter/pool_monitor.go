@@ -0,0 +1,79 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+//nolint:gochecknoglobals
+var poolCheckoutWaitDesc = prometheus.NewDesc("mongodb_exporter_pool_checkout_wait_seconds",
+	"Maximum time spent waiting to check out a connection from the exporter's connection pool since the previous scrape, in seconds.",
+	nil, nil)
+
+// poolMonitor tracks how long the exporter waits for connections of its own MongoDB client.
+// Slow checkouts mean the pool is starving under concurrent scrapes.
+type poolMonitor struct {
+	mu      sync.Mutex
+	maxWait time.Duration
+}
+
+func newPoolMonitor() *poolMonitor {
+	return &poolMonitor{}
+}
+
+// driverMonitor returns the monitor to be set in the client options, nil for a nil poolMonitor.
+func (p *poolMonitor) driverMonitor() *event.PoolMonitor {
+	if p == nil {
+		return nil
+	}
+
+	return &event.PoolMonitor{Event: p.handle}
+}
+
+func (p *poolMonitor) handle(evt *event.PoolEvent) {
+	// Both successful and failed checkouts report how long they waited.
+	if evt.Type != event.GetSucceeded && evt.Type != event.GetFailed {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if evt.Duration > p.maxWait {
+		p.maxWait = evt.Duration
+	}
+}
+
+func (p *poolMonitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolCheckoutWaitDesc
+}
+
+// Collect sends the maximum wait since the previous scrape and resets it.
+func (p *poolMonitor) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	maxWait := p.maxWait
+	p.maxWait = 0
+	p.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(poolCheckoutWaitDesc, prometheus.GaugeValue, maxWait.Seconds())
+}
+
+var _ prometheus.Collector = (*poolMonitor)(nil)
@@ -0,0 +1,81 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/event"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestPoolMonitorCollect(t *testing.T) {
+	t.Parallel()
+
+	p := newPoolMonitor()
+	p.handle(&event.PoolEvent{Type: event.GetStarted, Duration: time.Hour})
+	p.handle(&event.PoolEvent{Type: event.GetSucceeded, Duration: 250 * time.Millisecond})
+	p.handle(&event.PoolEvent{Type: event.GetFailed, Duration: 1500 * time.Millisecond})
+	p.handle(&event.PoolEvent{Type: event.GetSucceeded, Duration: time.Millisecond})
+
+	expected := strings.NewReader(`
+	# HELP mongodb_exporter_pool_checkout_wait_seconds Maximum time spent waiting to check out a connection from the exporter's connection pool since the previous scrape, in seconds.
+	# TYPE mongodb_exporter_pool_checkout_wait_seconds gauge
+	mongodb_exporter_pool_checkout_wait_seconds 1.5` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(p, expected))
+
+	// The maximum is reset on every scrape.
+	assert.Equal(t, float64(0), testutil.ToFloat64(p))
+}
+
+func TestPoolMonitorConstrainedPool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hostname := "127.0.0.1"
+	port := tu.GetenvDefault("TEST_MONGODB_STANDALONE_PORT", "27017")
+	opts := &Opts{
+		URI: fmt.Sprintf("mongodb://%s/admin?maxPoolSize=1", net.JoinHostPort(hostname, port)),
+	}
+
+	p := newPoolMonitor()
+	client, err := connect(ctx, opts, p.driverMonitor())
+	require.NoError(t, err)
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	// With a single connection, concurrent commands have to wait for each other.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, client.Ping(ctx, nil))
+		}()
+	}
+	wg.Wait()
+
+	assert.Greater(t, testutil.ToFloat64(p), float64(0))
+}
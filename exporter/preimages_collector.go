@@ -0,0 +1,124 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// preImagesCollection is the collection storing the change stream pre-images, since MongoDB 6.0.
+const preImagesCollection = "system.preimages"
+
+type preImagesCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	topologyInfo labelsGetter
+}
+
+// newPreImagesCollector creates a collector for the size of the change stream pre-images collection.
+func newPreImagesCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, topology labelsGetter) *preImagesCollector {
+	return &preImagesCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "preimages"})),
+		topologyInfo: topology,
+	}
+}
+
+func (d *preImagesCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *preImagesCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *preImagesCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "preimages")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// The collection is only created when a collection enables changeStreamPreAndPostImages.
+	names, err := client.Database("config").ListCollectionNames(d.ctx, bson.D{{Key: "name", Value: preImagesCollection}})
+	if err != nil {
+		logger.Errorf("cannot list the config collections: %s", err)
+		return
+	}
+	if len(names) == 0 {
+		logger.Debug("no change stream pre-images collection")
+		return
+	}
+
+	var m bson.M
+	cmd := bson.D{{Key: "collStats", Value: preImagesCollection}}
+	if err := client.Database("config").RunCommand(d.ctx, cmd).Decode(&m); err != nil {
+		logger.Errorf("cannot get the stats of the pre-images collection: %s", err)
+		return
+	}
+
+	logger.Debug("collStats config.system.preimages result")
+	debugResult(logger, m)
+
+	for _, metric := range preImagesMetrics(m, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// preImagesMetrics returns the size and the number of documents of the pre-images collection
+// in its collStats response.
+func preImagesMetrics(m bson.M, labels map[string]string) []prometheus.Metric {
+	values := []struct {
+		name string
+		help string
+		key  string
+	}{
+		{
+			name: "mongodb_preimages_collection_size_bytes",
+			help: "Uncompressed size of the change stream pre-images, in bytes",
+			key:  "size",
+		},
+		{
+			name: "mongodb_preimages_collection_storage_size_bytes",
+			help: "Storage allocated to the change stream pre-images collection, in bytes",
+			key:  "storageSize",
+		},
+		{
+			name: "mongodb_preimages_collection_documents",
+			help: "Number of change stream pre-images",
+			key:  "count",
+		},
+	}
+
+	metrics := make([]prometheus.Metric, 0, len(values))
+	for _, v := range values {
+		f, err := asFloat64(m[v.key])
+		if err != nil || f == nil {
+			continue
+		}
+
+		d := prometheus.NewDesc(v.name, v.help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, *f))
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*preImagesCollector)(nil)
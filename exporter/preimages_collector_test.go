@@ -0,0 +1,76 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestPreImagesCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	c := newPreImagesCollector(ctx, client, logrus.New(), labelsGetterMock{})
+
+	// The test instances have no collection with pre-images enabled.
+	err := testutil.CollectAndCompare(c, strings.NewReader(""),
+		"mongodb_preimages_collection_size_bytes", "mongodb_preimages_collection_documents")
+	assert.NoError(t, err)
+}
+
+func TestPreImagesMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"ns":             "config.system.preimages",
+		"size":           int64(52428800),
+		"count":          int32(1200),
+		"avgObjSize":     int32(43690),
+		"storageSize":    int64(20971520),
+		"nindexes":       int32(1),
+		"totalIndexSize": int64(36864),
+		"ok":             float64(1),
+	}
+
+	expected := strings.NewReader(`
+# HELP mongodb_preimages_collection_documents Number of change stream pre-images
+# TYPE mongodb_preimages_collection_documents gauge
+mongodb_preimages_collection_documents{rs_nm="rs1"} 1200
+# HELP mongodb_preimages_collection_size_bytes Uncompressed size of the change stream pre-images, in bytes
+# TYPE mongodb_preimages_collection_size_bytes gauge
+mongodb_preimages_collection_size_bytes{rs_nm="rs1"} 5.24288e+07
+# HELP mongodb_preimages_collection_storage_size_bytes Storage allocated to the change stream pre-images collection, in bytes
+# TYPE mongodb_preimages_collection_storage_size_bytes gauge
+mongodb_preimages_collection_storage_size_bytes{rs_nm="rs1"} 2.097152e+07` + "\n")
+
+	metrics := preImagesMetrics(m, map[string]string{"rs_nm": "rs1"})
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+
+	assert.Empty(t, preImagesMetrics(bson.M{"ok": float64(1)}, nil))
+}
@@ -0,0 +1,131 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type profileStatsCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	topologyInfo labelsGetter
+	window       time.Duration
+}
+
+// newProfileStatsCollector creates a collector for the profiling level and the slow queries
+// recorded by the profiler in each database.
+func newProfileStatsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger,
+	topology labelsGetter, window time.Duration,
+) *profileStatsCollector {
+	return &profileStatsCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "profilestats"})),
+		topologyInfo: topology,
+		window:       window,
+	}
+}
+
+func (d *profileStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *profileStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *profileStatsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "profile_stats")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	dbNames, err := databases(d.ctx, client, nil, nil)
+	if err != nil {
+		logger.Warnf("cannot get databases: %s", err)
+		return
+	}
+
+	since := primitive.NewDateTimeFromTime(time.Now().Add(-d.window))
+
+	for _, db := range dbNames {
+		labels := d.topologyInfo.baseLabels()
+		labels["db"] = db
+
+		// profile: -1 returns the current level without changing it.
+		var level struct {
+			Was int `bson:"was"`
+		}
+		if err := client.Database(db).RunCommand(d.ctx, bson.D{{Key: "profile", Value: -1}}).Decode(&level); err != nil {
+			logger.Warnf("cannot get profiling level for database %s: %s", db, err)
+			continue
+		}
+
+		ld := prometheus.NewDesc("mongodb_profile_level", "Database profiling level (0=off, 1=slow operations, 2=all)", nil, labels)
+		ch <- prometheus.MustNewConstMetric(ld, prometheus.GaugeValue, float64(level.Was))
+
+		if level.Was == 0 {
+			continue
+		}
+
+		names, err := client.Database(db).ListCollectionNames(d.ctx, bson.D{{Key: "name", Value: "system.profile"}})
+		if err != nil {
+			logger.Warnf("cannot check system.profile for database %s: %s", db, err)
+			continue
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: "ts", Value: bson.D{{Key: "$gte", Value: since}}}}}},
+			{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$op"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		}
+
+		cursor, err := client.Database(db).Collection("system.profile").Aggregate(d.ctx, pipeline)
+		if err != nil {
+			logger.Warnf("cannot aggregate system.profile for database %s: %s", db, err)
+			continue
+		}
+
+		var ops []struct {
+			Op    string `bson:"_id"`
+			Count int64  `bson:"count"`
+		}
+		if err := cursor.All(d.ctx, &ops); err != nil {
+			logger.Warnf("cannot decode system.profile aggregation for database %s: %s", db, err)
+			continue
+		}
+
+		logger.Debugf("system.profile counts for %s", db)
+		debugResult(logger, ops)
+
+		sd := prometheus.NewDesc("mongodb_profile_slow_query_total",
+			"Number of operations recorded by the profiler in the configured window", []string{"op"}, labels)
+		for _, op := range ops {
+			ch <- prometheus.MustNewConstMetric(sd, prometheus.GaugeValue, float64(op.Count), op.Op)
+		}
+	}
+}
+
+var _ prometheus.Collector = (*profileStatsCollector)(nil)
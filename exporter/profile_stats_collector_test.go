@@ -0,0 +1,71 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/percona/mongodb_exporter/internal/tu"
+)
+
+func TestProfileStatsCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	client := tu.DefaultTestClient(ctx, t)
+
+	database := client.Database("testdb_profile")
+	database.Drop(ctx) //nolint
+
+	defer func() {
+		_ = database.RunCommand(ctx, bson.M{"profile": 0})
+		err := database.Drop(ctx)
+		assert.NoError(t, err)
+	}()
+
+	// Profile everything so the insert is recorded.
+	err := database.RunCommand(ctx, bson.M{"profile": 2}).Err()
+	require.NoError(t, err)
+
+	_, err = database.Collection("test").InsertOne(ctx, bson.M{"f": 1})
+	require.NoError(t, err)
+
+	ti := labelsGetterMock{}
+
+	c := newProfileStatsCollector(ctx, client, logrus.New(), ti, time.Minute)
+
+	expected := strings.NewReader(`
+	# HELP mongodb_profile_slow_query_total Number of operations recorded by the profiler in the configured window
+	# TYPE mongodb_profile_slow_query_total gauge
+	mongodb_profile_slow_query_total{db="testdb_profile",op="insert"} 1` +
+		"\n")
+
+	filter := []string{
+		"mongodb_profile_slow_query_total",
+	}
+
+	err = testutil.CollectAndCompare(c, expected, filter...)
+	assert.NoError(t, err)
+}
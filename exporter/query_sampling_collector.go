@@ -0,0 +1,161 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// querySamplingMinVersion is the first major version sampling the queries of collections,
+// configured with configureQueryAnalyzer, to evaluate shard keys with analyzeShardKey.
+const querySamplingMinVersion = 7
+
+type querySamplingCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	buildInfo    buildInfo
+	topologyInfo labelsGetter
+}
+
+// newQuerySamplingCollector creates a collector for the query sampling of the namespaces under analysis.
+func newQuerySamplingCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, buildInfo buildInfo, topology labelsGetter) *querySamplingCollector {
+	return &querySamplingCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "querysampling"})),
+		buildInfo:    buildInfo,
+		topologyInfo: topology,
+	}
+}
+
+func (d *querySamplingCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *querySamplingCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *querySamplingCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "querysampling")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	if len(d.buildInfo.VersionArray) == 0 || d.buildInfo.VersionArray[0] < querySamplingMinVersion {
+		logger.Debugf("query sampling is not supported by MongoDB %s", d.buildInfo.Version)
+		return
+	}
+
+	// With localOps, mongos reports its own query analyzers instead of the ones of the shards.
+	pipeline := mongo.Pipeline{
+		{{Key: "$currentOp", Value: bson.D{{Key: "allUsers", Value: true}, {Key: "localOps", Value: true}}}},
+		{{Key: "$match", Value: bson.D{{Key: "desc", Value: "query analyzer"}}}},
+	}
+
+	cursor, err := client.Database("admin").Aggregate(d.ctx, pipeline)
+	if err != nil {
+		logger.Errorf("cannot get the query analyzers from $currentOp: %s", err)
+		return
+	}
+
+	var analyzers []bson.M
+	if err := cursor.All(d.ctx, &analyzers); err != nil {
+		logger.Errorf("cannot decode the query analyzers: %s", err)
+		return
+	}
+
+	logger.Debug("$currentOp query analyzer result")
+	debugResult(logger, analyzers)
+
+	for _, metric := range querySamplingMetrics(analyzers, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// querySamplingMetrics returns the sampling rate and the number of sampled queries of each
+// namespace in the query analyzer operations of $currentOp.
+func querySamplingMetrics(analyzers []bson.M, labels map[string]string) []prometheus.Metric {
+	active := prometheus.NewDesc("mongodb_query_sampling_active",
+		"Whether the queries of the namespace are sampled. The value is always 1", []string{"namespace"}, labels)
+
+	values := []struct {
+		name      string
+		help      string
+		key       string
+		valueType prometheus.ValueType
+	}{
+		{
+			name:      "mongodb_query_sampling_samples_per_second",
+			help:      "Number of queries of the namespace sampled per second, as configured with configureQueryAnalyzer",
+			key:       "samplesPerSecond",
+			valueType: prometheus.GaugeValue,
+		},
+		{
+			name:      "mongodb_query_sampling_sampled_reads_total",
+			help:      "Number of read queries of the namespace sampled since the sampling started",
+			key:       "sampledReadsCount",
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_query_sampling_sampled_writes_total",
+			help:      "Number of write queries of the namespace sampled since the sampling started",
+			key:       "sampledWritesCount",
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_query_sampling_sampled_reads_bytes_total",
+			help:      "Size of the read queries of the namespace sampled since the sampling started, in bytes",
+			key:       "sampledReadsBytes",
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_query_sampling_sampled_writes_bytes_total",
+			help:      "Size of the write queries of the namespace sampled since the sampling started, in bytes",
+			key:       "sampledWritesBytes",
+			valueType: prometheus.CounterValue,
+		},
+	}
+
+	var metrics []prometheus.Metric
+	for _, analyzer := range analyzers {
+		namespace, _ := analyzer["ns"].(string)
+		if namespace == "" {
+			continue
+		}
+
+		metrics = append(metrics, prometheus.MustNewConstMetric(active, prometheus.GaugeValue, 1, namespace))
+
+		// mongos doesn't report the sampled queries, they are stored by the shards.
+		for _, v := range values {
+			f, err := asFloat64(analyzer[v.key])
+			if err != nil || f == nil {
+				continue
+			}
+
+			d := prometheus.NewDesc(v.name, v.help, []string{"namespace"}, labels)
+			metrics = append(metrics, prometheus.MustNewConstMetric(d, v.valueType, *f, namespace))
+		}
+	}
+
+	return metrics
+}
+
+var _ prometheus.Collector = (*querySamplingCollector)(nil)
@@ -0,0 +1,89 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQuerySamplingMetrics(t *testing.T) {
+	t.Parallel()
+
+	analyzers := []bson.M{
+		{
+			"desc":               "query analyzer",
+			"ns":                 "shop.orders",
+			"collUuid":           primitive.Binary{Subtype: 4, Data: make([]byte, 16)},
+			"samplesPerSecond":   float64(5),
+			"startTime":          primitive.DateTime(1700000000000),
+			"sampledReadsCount":  int64(120),
+			"sampledWritesCount": int64(30),
+			"sampledReadsBytes":  int64(24000),
+			"sampledWritesBytes": int64(9100),
+		},
+		{
+			// mongos doesn't report the sampled queries.
+			"desc":             "query analyzer",
+			"ns":               "shop.customers",
+			"samplesPerSecond": float64(0.5),
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_query_sampling_active Whether the queries of the namespace are sampled. The value is always 1
+	# TYPE mongodb_query_sampling_active gauge
+	mongodb_query_sampling_active{namespace="shop.customers",rs_nm="rs"} 1
+	mongodb_query_sampling_active{namespace="shop.orders",rs_nm="rs"} 1
+	# HELP mongodb_query_sampling_sampled_reads_bytes_total Size of the read queries of the namespace sampled since the sampling started, in bytes
+	# TYPE mongodb_query_sampling_sampled_reads_bytes_total counter
+	mongodb_query_sampling_sampled_reads_bytes_total{namespace="shop.orders",rs_nm="rs"} 24000
+	# HELP mongodb_query_sampling_sampled_reads_total Number of read queries of the namespace sampled since the sampling started
+	# TYPE mongodb_query_sampling_sampled_reads_total counter
+	mongodb_query_sampling_sampled_reads_total{namespace="shop.orders",rs_nm="rs"} 120
+	# HELP mongodb_query_sampling_sampled_writes_bytes_total Size of the write queries of the namespace sampled since the sampling started, in bytes
+	# TYPE mongodb_query_sampling_sampled_writes_bytes_total counter
+	mongodb_query_sampling_sampled_writes_bytes_total{namespace="shop.orders",rs_nm="rs"} 9100
+	# HELP mongodb_query_sampling_sampled_writes_total Number of write queries of the namespace sampled since the sampling started
+	# TYPE mongodb_query_sampling_sampled_writes_total counter
+	mongodb_query_sampling_sampled_writes_total{namespace="shop.orders",rs_nm="rs"} 30
+	# HELP mongodb_query_sampling_samples_per_second Number of queries of the namespace sampled per second, as configured with configureQueryAnalyzer
+	# TYPE mongodb_query_sampling_samples_per_second gauge
+	mongodb_query_sampling_samples_per_second{namespace="shop.customers",rs_nm="rs"} 0.5
+	mongodb_query_sampling_samples_per_second{namespace="shop.orders",rs_nm="rs"} 5` + "\n")
+
+	metrics := querySamplingMetrics(analyzers, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	assert.Empty(t, querySamplingMetrics(nil, nil))
+}
+
+func TestQuerySamplingUnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	// Before MongoDB 7.0 the collector doesn't run $currentOp, the nil client is not used.
+	c := newQuerySamplingCollector(context.Background(), nil, logrus.New(),
+		buildInfo{Version: "6.0.14", VersionArray: []int{6, 0, 14, 0}}, labelsGetterMock{})
+
+	assert.Zero(t, testutil.CollectAndCount(c, "mongodb_query_sampling_active"))
+}
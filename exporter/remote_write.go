@@ -0,0 +1,203 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteTimeout bounds a remote write request when ctx has no deadline.
+const remoteWriteTimeout = 30 * time.Second
+
+// RemoteWrite runs the enabled collectors once and sends their metrics to a Prometheus
+// remote write endpoint, e.g. Grafana Mimir, without a Prometheus server scraping the
+// exporter. The metrics have the instance and topology labels, as in PushOnce. A node which
+// cannot be reached sends mongodb_up=0.
+func (e *Exporter) RemoteWrite(ctx context.Context, endpoint string) error {
+	registry, ti := e.scrapeRegistry(ctx, *e.opts)
+
+	var labels map[string]string
+	if ti != nil {
+		labels = ti.baseLabels()
+	}
+
+	families, err := NewGathererWrapper(registry, pushGrouping(e.opts.NodeName, labels)).Gather()
+	if err != nil {
+		return fmt.Errorf("cannot gather metrics: %w", err)
+	}
+
+	body := snappy.Encode(nil, remoteWriteRequest(families, time.Now()))
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, remoteWriteTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid remote write endpoint: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send metrics: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("cannot send metrics: remote write endpoint returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// remoteWriteSample is a sample of a time series, with the metric name in the __name__ label.
+type remoteWriteSample struct {
+	labels      map[string]string
+	value       float64
+	timestampMs int64
+}
+
+// remoteWriteSamples flattens the metric families into samples, as a Prometheus server would
+// store them: the histograms and summaries become the _bucket or quantile, _sum and _count
+// series. Samples without a timestamp are timestamped now. The labels with an empty value are
+// dropped.
+func remoteWriteSamples(families []*dto.MetricFamily, now time.Time) []remoteWriteSample {
+	var samples []remoteWriteSample
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			ts := now.UnixMilli()
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+
+			add := func(suffix string, value float64, extra ...string) {
+				labels := map[string]string{"__name__": mf.GetName() + suffix}
+				for _, l := range m.GetLabel() {
+					// Prometheus doesn't store empty labels, the series would not match.
+					if l.GetValue() != "" {
+						labels[l.GetName()] = l.GetValue()
+					}
+				}
+				for i := 0; i+1 < len(extra); i += 2 {
+					labels[extra[i]] = extra[i+1]
+				}
+				samples = append(samples, remoteWriteSample{labels: labels, value: value, timestampMs: ts})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add("", q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				inf := false
+				for _, b := range h.GetBucket() {
+					inf = inf || math.IsInf(b.GetUpperBound(), 1)
+					add("_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				if !inf {
+					add("_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				}
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+
+	return samples
+}
+
+// formatFloat formats the le and quantile labels as Prometheus does.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// remoteWriteRequest returns the metric families as a remote write WriteRequest protobuf
+// message, uncompressed. The message is small enough to be encoded by hand:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label        { string name = 1; string value = 2; }
+//	message Sample       { double value = 1; int64 timestamp = 2; }
+func remoteWriteRequest(families []*dto.MetricFamily, now time.Time) []byte {
+	var req []byte
+	for _, s := range remoteWriteSamples(families, now) {
+		// The labels of a series must be sorted by name.
+		names := make([]string, 0, len(s.labels))
+		for name := range s.labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var series []byte
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, s.labels[name])
+
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestampMs))
+
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, series)
+	}
+
+	return req
+}
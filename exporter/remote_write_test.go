@@ -0,0 +1,189 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes the samples of a remote write WriteRequest message.
+func decodeWriteRequest(t *testing.T, b []byte) []remoteWriteSample {
+	t.Helper()
+
+	// fields calls f with the number and the value of each field of the message.
+	fields := func(b []byte, f func(num protowire.Number, typ protowire.Type, v []byte)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			require.GreaterOrEqual(t, n, 0)
+			f(num, typ, b[:n])
+			b = b[n:]
+		}
+	}
+
+	var samples []remoteWriteSample
+	fields(b, func(_ protowire.Number, _ protowire.Type, v []byte) {
+		series, _ := protowire.ConsumeBytes(v)
+		s := remoteWriteSample{labels: map[string]string{}}
+		fields(series, func(num protowire.Number, _ protowire.Type, v []byte) {
+			msg, _ := protowire.ConsumeBytes(v)
+			switch num {
+			case 1:
+				var name, value string
+				fields(msg, func(num protowire.Number, _ protowire.Type, v []byte) {
+					str, _ := protowire.ConsumeString(v)
+					if num == 1 {
+						name = str
+					} else {
+						value = str
+					}
+				})
+				s.labels[name] = value
+			case 2:
+				fields(msg, func(num protowire.Number, _ protowire.Type, v []byte) {
+					if num == 1 {
+						bits, _ := protowire.ConsumeFixed64(v)
+						s.value = math.Float64frombits(bits)
+					} else {
+						ts, _ := protowire.ConsumeVarint(v)
+						s.timestampMs = int64(ts)
+					}
+				})
+			}
+		})
+		samples = append(samples, s)
+	})
+
+	return samples
+}
+
+func TestRemoteWrite(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock    sync.Mutex
+		headers http.Header
+		body    []byte
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		headers = r.Header.Clone()
+		compressed, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		body, err = snappy.Decode(nil, compressed)
+		assert.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	e := &Exporter{
+		logger: logger,
+		opts:   &Opts{Logger: logger, NodeName: "host1:27017"},
+		lock:   &sync.Mutex{},
+		connectFn: func(context.Context, *Opts, *event.PoolMonitor) (*mongo.Client, error) {
+			return nil, fmt.Errorf("connection refused")
+		},
+	}
+
+	// As for a scrape, mongodb_up=0 is sent if MongoDB is not reachable.
+	require.NoError(t, e.RemoteWrite(context.Background(), receiver.URL))
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Equal(t, "snappy", headers.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+
+	samples := decodeWriteRequest(t, body)
+	assert.Contains(t, samples, remoteWriteSample{
+		labels:      map[string]string{"__name__": "mongodb_up", "instance": "host1:27017"},
+		value:       0,
+		timestampMs: samples[0].timestampMs,
+	})
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	t.Cleanup(failing.Close)
+
+	err := e.RemoteWrite(context.Background(), failing.URL)
+	assert.ErrorContains(t, err, "out of order sample")
+}
+
+func TestRemoteWriteSamples(t *testing.T) {
+	t.Parallel()
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        "test_latency_seconds",
+		Help:        "Test histogram",
+		Buckets:     []float64{0.1, 1},
+		ConstLabels: prometheus.Labels{"rs_nm": "rs"},
+	})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(h)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	sample := func(name string, value float64, extra ...string) remoteWriteSample {
+		labels := map[string]string{"__name__": name, "rs_nm": "rs"}
+		for i := 0; i+1 < len(extra); i += 2 {
+			labels[extra[i]] = extra[i+1]
+		}
+
+		return remoteWriteSample{labels: labels, value: value, timestampMs: now.UnixMilli()}
+	}
+
+	want := []remoteWriteSample{
+		sample("test_latency_seconds_bucket", 1, "le", "0.1"),
+		sample("test_latency_seconds_bucket", 2, "le", "1"),
+		sample("test_latency_seconds_bucket", 3, "le", "+Inf"),
+		sample("test_latency_seconds_sum", 5.55),
+		sample("test_latency_seconds_count", 3),
+	}
+	assert.Equal(t, want, remoteWriteSamples(families, now))
+
+	// The encoded request has the same samples.
+	assert.Equal(t, want, decodeWriteRequest(t, remoteWriteRequest(families, now)))
+}
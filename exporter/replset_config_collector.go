@@ -17,12 +17,15 @@ package exporter
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
 )
 
 type replSetGetConfigCollector struct {
@@ -31,16 +34,18 @@ type replSetGetConfigCollector struct {
 
 	compatibleMode bool
 	topologyInfo   labelsGetter
+	commandRetries int
 }
 
 // newReplicationSetConfigCollector creates a collector for configuration of replication set.
-func newReplicationSetConfigCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter) *replSetGetConfigCollector {
+func newReplicationSetConfigCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter, commandRetries int) *replSetGetConfigCollector {
 	return &replSetGetConfigCollector{
 		ctx:  ctx,
-		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "replset_config"})),
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "replicasetconfig"})),
 
 		compatibleMode: compatible,
 		topologyInfo:   topology,
+		commandRetries: commandRetries,
 	}
 }
 
@@ -59,7 +64,7 @@ func (d *replSetGetConfigCollector) collect(ch chan<- prometheus.Metric) {
 	client := d.base.client
 
 	cmd := bson.D{{Key: "replSetGetConfig", Value: "1"}}
-	res := client.Database("admin").RunCommand(d.ctx, cmd)
+	res := runCommand(d.ctx, client.Database("admin"), cmd, d.commandRetries)
 
 	var m bson.M
 
@@ -89,6 +94,49 @@ func (d *replSetGetConfigCollector) collect(ch chan<- prometheus.Metric) {
 	for _, metric := range makeMetrics("rs_cfg", m, d.topologyInfo.baseLabels(), d.compatibleMode) {
 		ch <- metric
 	}
+
+	var rs proto.ReplicasetConfig
+	if err := res.Decode(&rs); err != nil {
+		logger.Errorf("cannot decode replSetGetConfig: %s", err)
+		return
+	}
+
+	for _, metric := range replSetConfigMetrics(rs.Config, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// replSetConfigMetrics returns the version and term of the replica set config, to find the
+// members a reconfig has not propagated to yet, and the votes and priority of each member.
+func replSetConfigMetrics(config proto.RSConfig, labels map[string]string) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, len(config.Members)+2) //nolint:mnd
+
+	d := prometheus.NewDesc("mongodb_mongod_replset_config_version",
+		"Version of the replica set config, incremented by every reconfig", nil, labels)
+	metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(config.Version)))
+
+	// The term is only stored in the config since MongoDB 4.4.
+	if config.Term != nil {
+		d := prometheus.NewDesc("mongodb_mongod_replset_config_term",
+			"Term of the primary which wrote the replica set config", nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(*config.Term)))
+	}
+
+	memberLabels := []string{"name", "member_id", "votes", "priority", "hidden", "arbiter_only"}
+	d = prometheus.NewDesc("mongodb_mongod_replset_member_configured",
+		"Members in the replica set config, with their votes and priority. The value is always 1", memberLabels, labels)
+	for _, m := range config.Members {
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1,
+			m.Host,
+			strconv.Itoa(int(m.ID)),
+			strconv.Itoa(int(m.Votes)),
+			strconv.FormatFloat(m.Priority, 'f', -1, 64),
+			strconv.FormatBool(m.Hidden),
+			strconv.FormatBool(m.ArbiterOnly),
+		))
+	}
+
+	return metrics
 }
 
 var _ prometheus.Collector = (*replSetGetConfigCollector)(nil)
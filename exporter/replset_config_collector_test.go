@@ -25,6 +25,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/percona/mongodb_exporter/internal/proto"
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
 
@@ -36,7 +37,7 @@ func TestReplsetConfigCollector(t *testing.T) {
 
 	ti := labelsGetterMock{}
 
-	c := newReplicationSetConfigCollector(ctx, client, logrus.New(), false, ti)
+	c := newReplicationSetConfigCollector(ctx, client, logrus.New(), false, ti, 0)
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
@@ -62,11 +63,51 @@ func TestReplsetConfigCollectorNoSharding(t *testing.T) {
 
 	ti := labelsGetterMock{}
 
-	c := newReplicationSetConfigCollector(ctx, client, logrus.New(), false, ti)
+	c := newReplicationSetConfigCollector(ctx, client, logrus.New(), false, ti, 0)
 
 	// Replication set metrics should not be generated for unsharded server
 	count := testutil.CollectAndCount(c)
 
-	metaMetricCount := 1
+	metaMetricCount := 3 // scrape time, success and errors
 	assert.Equal(t, metaMetricCount, count, "Mismatch in metric count for collector run on unsharded server")
 }
+
+func TestReplSetConfigMetrics(t *testing.T) {
+	t.Parallel()
+
+	term := int64(7)
+	config := proto.RSConfig{
+		ID:      "rs1",
+		Version: 12,
+		Term:    &term,
+		Members: []proto.Member{
+			{ID: 0, Host: "db1:27017", Votes: 1, Priority: 2},
+			{ID: 1, Host: "db2:27017", Votes: 1, Priority: 0.5},
+			{ID: 2, Host: "db3:27017", Votes: 0, Priority: 0, Hidden: true},
+			{ID: 3, Host: "arbiter:27017", Votes: 1, ArbiterOnly: true},
+		},
+	}
+
+	expected := strings.NewReader(`
+# HELP mongodb_mongod_replset_config_term Term of the primary which wrote the replica set config
+# TYPE mongodb_mongod_replset_config_term gauge
+mongodb_mongod_replset_config_term{rs_nm="rs1"} 7
+# HELP mongodb_mongod_replset_config_version Version of the replica set config, incremented by every reconfig
+# TYPE mongodb_mongod_replset_config_version gauge
+mongodb_mongod_replset_config_version{rs_nm="rs1"} 12
+# HELP mongodb_mongod_replset_member_configured Members in the replica set config, with their votes and priority. The value is always 1
+# TYPE mongodb_mongod_replset_member_configured gauge
+mongodb_mongod_replset_member_configured{arbiter_only="false",hidden="false",member_id="0",name="db1:27017",priority="2",rs_nm="rs1",votes="1"} 1
+mongodb_mongod_replset_member_configured{arbiter_only="false",hidden="false",member_id="1",name="db2:27017",priority="0.5",rs_nm="rs1",votes="1"} 1
+mongodb_mongod_replset_member_configured{arbiter_only="false",hidden="true",member_id="2",name="db3:27017",priority="0",rs_nm="rs1",votes="0"} 1
+mongodb_mongod_replset_member_configured{arbiter_only="true",hidden="false",member_id="3",name="arbiter:27017",priority="0",rs_nm="rs1",votes="1"} 1` + "\n")
+
+	metrics := replSetConfigMetrics(config, map[string]string{"rs_nm": "rs1"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	// Before MongoDB 4.4 the config has no term.
+	config.Term = nil
+	config.Members = nil
+	metrics = replSetConfigMetrics(config, nil)
+	assert.Equal(t, []string{"mongodb_mongod_replset_config_version"}, metricNames(metrics))
+}
@@ -17,16 +17,21 @@ package exporter
 
 import (
 	"context"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
 )
 
 const (
 	replicationNotEnabled        = 76
 	replicationNotYetInitialized = 94
+
+	memberStateSecondary = 2
 )
 
 type replSetGetStatusCollector struct {
@@ -35,16 +40,18 @@ type replSetGetStatusCollector struct {
 
 	compatibleMode bool
 	topologyInfo   labelsGetter
+	cache          *scrapeCache
 }
 
 // newReplicationSetStatusCollector creates a collector for statistics on replication set.
-func newReplicationSetStatusCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter) *replSetGetStatusCollector {
+func newReplicationSetStatusCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool, topology labelsGetter, cache *scrapeCache) *replSetGetStatusCollector {
 	return &replSetGetStatusCollector{
 		ctx:  ctx,
-		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "replset_status"})),
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "replicasetstatus"})),
 
 		compatibleMode: compatible,
 		topologyInfo:   topology,
+		cache:          cache,
 	}
 }
 
@@ -62,12 +69,8 @@ func (d *replSetGetStatusCollector) collect(ch chan<- prometheus.Metric) {
 	logger := d.base.logger
 	client := d.base.client
 
-	cmd := bson.D{{Key: "replSetGetStatus", Value: "1"}}
-	res := client.Database("admin").RunCommand(d.ctx, cmd)
-
-	var m bson.M
-
-	if err := res.Decode(&m); err != nil {
+	m, err := d.cache.command(d.ctx, "replSetGetStatus")
+	if err != nil {
 		if e, ok := err.(mongo.CommandError); ok {
 			if e.Code == replicationNotYetInitialized || e.Code == replicationNotEnabled {
 				return
@@ -84,6 +87,279 @@ func (d *replSetGetStatusCollector) collect(ch chan<- prometheus.Metric) {
 	for _, metric := range makeMetrics("", m, d.topologyInfo.baseLabels(), d.compatibleMode) {
 		ch <- metric
 	}
+
+	var status proto.ReplicaSetStatus
+	if err := decodeResult(m, &status); err != nil {
+		logger.Errorf("cannot decode replSetGetStatus members: %s", err)
+		return
+	}
+
+	for _, metric := range replicationLagMetrics(status, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+
+	for _, metric := range syncSourceMetrics(status, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+
+	for _, metric := range durableLagMetrics(status, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+
+	for _, metric := range initialSyncMetrics(status.InitialSyncStatus, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+
+	// Compare with the server clock, the exporter one might be skewed.
+	now := status.Date.Time()
+	if status.Date == 0 {
+		now = time.Now()
+	}
+
+	for _, metric := range heartbeatMetrics(status, d.topologyInfo.baseLabels(), now) {
+		ch <- metric
+	}
+
+	// The primary applies its own writes, apply stalls only happen on secondaries.
+	if status.MyState != memberStateSecondary {
+		return
+	}
+
+	newest, err := oplogTimestamp(d.ctx, client, -1)
+	if err != nil {
+		logger.Errorf("cannot get the newest oplog entry: %s", err)
+		return
+	}
+
+	if stall, ok := oplogApplyStall(newest, status); ok {
+		desc := prometheus.NewDesc("mongodb_oplog_apply_stall_seconds",
+			"Time between the newest entry in the oplog of this secondary and the last applied one, in seconds.",
+			nil, d.topologyInfo.baseLabels())
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, stall.Seconds())
+	}
+}
+
+// oplogApplyStall returns how far the last applied optime of this member is behind the
+// newest entry written to its own oplog. Unlike the replication lag, it doesn't include the
+// time spent fetching the entries from the sync source, so it isolates stalls of the
+// apply side. It returns false if the applied optime of this member is unknown.
+func oplogApplyStall(newest primitive.Timestamp, status proto.ReplicaSetStatus) (time.Duration, bool) {
+	for _, m := range status.Members {
+		if !m.Self {
+			continue
+		}
+
+		applied, ok := m.Optime["appliedOpTime"]
+		if !ok || applied.Ts.IsZero() {
+			return 0, false
+		}
+
+		if newest.T <= applied.Ts.T {
+			return 0, true
+		}
+
+		return time.Duration(newest.T-applied.Ts.T) * time.Second, true
+	}
+
+	return 0, false
+}
+
+type memberLag struct {
+	name  string
+	state string
+	lag   time.Duration
+}
+
+// replicationLags returns the lag of each member, computed as the difference between the
+// optimeDate of the primary and the one of the member. Without a primary there is nothing
+// to compare with so it returns nil. Arbiters don't have an optime and are skipped.
+func replicationLags(status proto.ReplicaSetStatus) []memberLag {
+	var primary *proto.Members
+	for i, m := range status.Members {
+		if m.StateStr == "PRIMARY" && m.OptimeDate != 0 {
+			primary = &status.Members[i]
+			break
+		}
+	}
+	if primary == nil {
+		return nil
+	}
+
+	lags := make([]memberLag, 0, len(status.Members))
+	for _, m := range status.Members {
+		if m.StateStr == "ARBITER" || m.OptimeDate == 0 {
+			continue
+		}
+
+		lag := primary.OptimeDate.Time().Sub(m.OptimeDate.Time())
+		if lag < 0 {
+			lag = 0
+		}
+		lags = append(lags, memberLag{name: m.Name, state: m.StateStr, lag: lag})
+	}
+
+	return lags
+}
+
+// replicationLagMetrics returns the replication lag of each member in seconds.
+func replicationLagMetrics(status proto.ReplicaSetStatus, labels map[string]string) []prometheus.Metric {
+	lags := replicationLags(status)
+	metrics := make([]prometheus.Metric, 0, len(lags))
+	for _, m := range lags {
+		l := make(map[string]string, len(labels)+2)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["name"] = m.name
+		l["state"] = m.state
+
+		d := prometheus.NewDesc("mongodb_mongod_replset_member_replication_lag_seconds",
+			"Replication lag of the member behind the primary, in seconds.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, m.lag.Seconds()))
+	}
+
+	return metrics
+}
+
+// syncSourceMetrics returns the member each member replicates from, as reported by this
+// member, to see the replication chains. The members without a sync source, such as the
+// primary and the arbiters, are skipped.
+func syncSourceMetrics(status proto.ReplicaSetStatus, labels map[string]string) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, len(status.Members))
+	for _, m := range status.Members {
+		if m.SyncSourceHost == "" {
+			continue
+		}
+
+		l := make(map[string]string, len(labels)+2)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["name"] = m.Name
+		l["sync_source"] = m.SyncSourceHost
+
+		d := prometheus.NewDesc("mongodb_rs_member_sync_source_info",
+			"The member replicates from the sync_source member. The value is always 1.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1))
+	}
+
+	return metrics
+}
+
+// durableLagMetrics returns how far the durable optime of each member is behind its applied
+// one, in seconds: the writes applied by the member but not yet journaled, which delay the
+// w:majority acknowledgments. Only the members reporting a durable optime have the metric.
+func durableLagMetrics(status proto.ReplicaSetStatus, labels map[string]string) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, len(status.Members))
+	for _, m := range status.Members {
+		if m.OptimeDurableDate == 0 || m.OptimeDate == 0 {
+			continue
+		}
+
+		lag := m.OptimeDate.Time().Sub(m.OptimeDurableDate.Time())
+		if lag < 0 {
+			lag = 0
+		}
+
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["name"] = m.Name
+
+		d := prometheus.NewDesc("mongodb_rs_member_durable_lag_seconds",
+			"Time between the last applied and the last durable optime of the member, in seconds.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, lag.Seconds()))
+	}
+
+	return metrics
+}
+
+// heartbeatMetrics returns when the last heartbeat from each member was received and how long
+// ago it was. A member whose heartbeats are not received is partially partitioned, even if it
+// reports itself as healthy. The member running the command has no heartbeats and is skipped.
+func heartbeatMetrics(status proto.ReplicaSetStatus, labels map[string]string, now time.Time) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, 2*len(status.Members))
+	for _, m := range status.Members {
+		if m.Self || m.LastHeartbeatRecv == 0 {
+			continue
+		}
+
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["name"] = m.Name
+
+		recv := m.LastHeartbeatRecv.Time()
+		staleness := now.Sub(recv)
+		if staleness < 0 {
+			staleness = 0
+		}
+
+		d := prometheus.NewDesc("mongodb_rs_member_last_heartbeat_recv_seconds",
+			"Unix time of the last heartbeat received from the member.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(recv.UnixNano())/1e9))
+
+		d = prometheus.NewDesc("mongodb_rs_member_last_heartbeat_recv_staleness_seconds",
+			"Time since the last heartbeat received from the member, in seconds.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, staleness.Seconds()))
+	}
+
+	return metrics
+}
+
+// Phases of an initial sync: the data is cloned first, then the oplog entries written
+// meanwhile are applied.
+const (
+	initialSyncPhaseCloning       = "cloning"
+	initialSyncPhaseApplyingOplog = "applying_oplog"
+)
+
+// initialSyncMetrics returns the progress and the current phase of the initial sync of this
+// member. The status is only reported during an initial sync, without it there are no metrics.
+func initialSyncMetrics(status *proto.InitialSyncStatus, labels map[string]string) []prometheus.Metric {
+	if status == nil {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+
+	// The sizes are only reported since MongoDB 4.4.
+	if status.ApproxTotalDataSize > 0 {
+		progress := status.ApproxTotalBytesCopied / status.ApproxTotalDataSize
+		if progress > 1 {
+			progress = 1
+		}
+
+		d := prometheus.NewDesc("mongodb_rs_initial_sync_progress_ratio",
+			"Approximate ratio of the data cloned by the running initial sync, from 0 to 1.", nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, progress))
+	}
+
+	phase := initialSyncPhaseCloning
+	if !status.InitialSyncOplogEnd.IsZero() {
+		phase = initialSyncPhaseApplyingOplog
+	}
+
+	for _, p := range []string{initialSyncPhaseCloning, initialSyncPhaseApplyingOplog} {
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["phase"] = p
+
+		value := float64(0)
+		if p == phase {
+			value = 1
+		}
+
+		d := prometheus.NewDesc("mongodb_rs_initial_sync_phase",
+			"Whether the running initial sync is in the phase.", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, value))
+	}
+
+	return metrics
 }
 
 var _ prometheus.Collector = (*replSetGetStatusCollector)(nil)
@@ -24,7 +24,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/percona/mongodb_exporter/internal/proto"
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
 
@@ -36,7 +40,7 @@ func TestReplsetStatusCollector(t *testing.T) {
 
 	ti := labelsGetterMock{}
 
-	c := newReplicationSetStatusCollector(ctx, client, logrus.New(), false, ti)
+	c := newReplicationSetStatusCollector(ctx, client, logrus.New(), false, ti, newScrapeCache(client, 0))
 
 	// The last \n at the end of this string is important
 	expected := strings.NewReader(`
@@ -66,11 +70,246 @@ func TestReplsetStatusCollectorNoSharding(t *testing.T) {
 
 	ti := labelsGetterMock{}
 
-	c := newReplicationSetStatusCollector(ctx, client, logrus.New(), false, ti)
+	c := newReplicationSetStatusCollector(ctx, client, logrus.New(), false, ti, newScrapeCache(client, 0))
 
 	// Replication set metrics should not be generated for unsharded server
 	count := testutil.CollectAndCount(c)
 
-	metaMetricCount := 1
+	metaMetricCount := 3 // scrape time, success and errors
 	assert.Equal(t, metaMetricCount, count, "Mismatch in metric count for collector run on unsharded server")
 }
+
+func TestReplicationLagMetrics(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	primary := proto.Members{Name: "rs1:27017", StateStr: "PRIMARY", OptimeDate: primitive.NewDateTimeFromTime(now)}
+	secondary := proto.Members{Name: "rs2:27017", StateStr: "SECONDARY", OptimeDate: primitive.NewDateTimeFromTime(now.Add(-5 * time.Second))}
+	arbiter := proto.Members{Name: "rs3:27017", StateStr: "ARBITER"}
+
+	t.Run("lag per member", func(t *testing.T) {
+		t.Parallel()
+
+		status := proto.ReplicaSetStatus{Members: []proto.Members{primary, secondary, arbiter}}
+		metrics := replicationLagMetrics(status, map[string]string{"rs_nm": "rs"})
+
+		expected := strings.NewReader(`
+	# HELP mongodb_mongod_replset_member_replication_lag_seconds Replication lag of the member behind the primary, in seconds.
+	# TYPE mongodb_mongod_replset_member_replication_lag_seconds gauge
+	mongodb_mongod_replset_member_replication_lag_seconds{name="rs1:27017",rs_nm="rs",state="PRIMARY"} 0
+	mongodb_mongod_replset_member_replication_lag_seconds{name="rs2:27017",rs_nm="rs",state="SECONDARY"} 5` + "\n")
+
+		err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+		assert.NoError(t, err)
+	})
+
+	t.Run("no primary", func(t *testing.T) {
+		t.Parallel()
+
+		status := proto.ReplicaSetStatus{Members: []proto.Members{secondary, arbiter}}
+		assert.Empty(t, replicationLagMetrics(status, nil))
+	})
+}
+
+func TestOplogApplyStall(t *testing.T) {
+	t.Parallel()
+
+	newest := primitive.Timestamp{T: 1700000030, I: 1}
+	self := func(applied primitive.Timestamp) proto.Members {
+		return proto.Members{
+			Name:   "rs2:27017",
+			Self:   true,
+			Optime: map[string]proto.Optime{"appliedOpTime": {Ts: applied, T: 1}},
+		}
+	}
+	primary := proto.Members{Name: "rs1:27017", StateStr: "PRIMARY"}
+
+	testCases := []struct {
+		name    string
+		members []proto.Members
+		stall   time.Duration
+		ok      bool
+	}{
+		{
+			name:    "behind",
+			members: []proto.Members{primary, self(primitive.Timestamp{T: 1700000000, I: 3})},
+			stall:   30 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "up to date",
+			members: []proto.Members{primary, self(newest)},
+			ok:      true,
+		},
+		{
+			name:    "applied ahead of the sampled entry",
+			members: []proto.Members{primary, self(primitive.Timestamp{T: 1700000031})},
+			ok:      true,
+		},
+		{
+			name:    "unknown applied optime",
+			members: []proto.Members{primary, {Name: "rs2:27017", Self: true}},
+		},
+		{
+			name:    "self not in members",
+			members: []proto.Members{primary},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stall, ok := oplogApplyStall(newest, proto.ReplicaSetStatus{MyState: memberStateSecondary, Members: tc.members})
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.stall, stall)
+		})
+	}
+}
+
+func TestHeartbeatMetrics(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	status := proto.ReplicaSetStatus{
+		Members: []proto.Members{
+			{Name: "rs1:27017", Self: true},
+			{Name: "rs2:27017", LastHeartbeatRecv: primitive.NewDateTimeFromTime(now.Add(-2 * time.Second))},
+			{Name: "rs3:27017", LastHeartbeatRecv: primitive.NewDateTimeFromTime(now.Add(-45 * time.Second))},
+			{Name: "rs4:27017"}, // never received
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_rs_member_last_heartbeat_recv_seconds Unix time of the last heartbeat received from the member.
+	# TYPE mongodb_rs_member_last_heartbeat_recv_seconds gauge
+	mongodb_rs_member_last_heartbeat_recv_seconds{name="rs2:27017",rs_nm="rs"} 1.704110398e+09
+	mongodb_rs_member_last_heartbeat_recv_seconds{name="rs3:27017",rs_nm="rs"} 1.704110355e+09
+	# HELP mongodb_rs_member_last_heartbeat_recv_staleness_seconds Time since the last heartbeat received from the member, in seconds.
+	# TYPE mongodb_rs_member_last_heartbeat_recv_staleness_seconds gauge
+	mongodb_rs_member_last_heartbeat_recv_staleness_seconds{name="rs2:27017",rs_nm="rs"} 2
+	mongodb_rs_member_last_heartbeat_recv_staleness_seconds{name="rs3:27017",rs_nm="rs"} 45` + "\n")
+
+	metrics := heartbeatMetrics(status, map[string]string{"rs_nm": "rs"}, now)
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
+
+func TestSyncSourceMetrics(t *testing.T) {
+	t.Parallel()
+
+	// rs3 replicates from the secondary rs2, a replication chain.
+	m := bson.M{
+		"set": "rs",
+		"members": bson.A{
+			bson.M{"name": "rs1:27017", "stateStr": "PRIMARY", "syncSourceHost": ""},
+			bson.M{"name": "rs2:27017", "stateStr": "SECONDARY", "syncSourceHost": "rs1:27017"},
+			bson.M{"name": "rs3:27017", "stateStr": "SECONDARY", "syncSourceHost": "rs2:27017"},
+			bson.M{"name": "rs4:27017", "stateStr": "ARBITER"},
+		},
+	}
+
+	var status proto.ReplicaSetStatus
+	require.NoError(t, decodeResult(m, &status))
+
+	expected := strings.NewReader(`
+	# HELP mongodb_rs_member_sync_source_info The member replicates from the sync_source member. The value is always 1.
+	# TYPE mongodb_rs_member_sync_source_info gauge
+	mongodb_rs_member_sync_source_info{name="rs2:27017",rs_nm="rs",sync_source="rs1:27017"} 1
+	mongodb_rs_member_sync_source_info{name="rs3:27017",rs_nm="rs",sync_source="rs2:27017"} 1` + "\n")
+
+	metrics := syncSourceMetrics(status, map[string]string{"rs_nm": "rs"})
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
+
+func TestDurableLagMetrics(t *testing.T) {
+	t.Parallel()
+
+	applied := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := bson.M{
+		"set": "rs",
+		"members": bson.A{
+			// The member running the command doesn't report its durable optime.
+			bson.M{"name": "rs1:27017", "self": true, "optimeDate": primitive.NewDateTimeFromTime(applied)},
+			bson.M{
+				"name":              "rs2:27017",
+				"optimeDate":        primitive.NewDateTimeFromTime(applied),
+				"optimeDurableDate": primitive.NewDateTimeFromTime(applied),
+			},
+			bson.M{
+				"name":              "rs3:27017",
+				"optimeDate":        primitive.NewDateTimeFromTime(applied),
+				"optimeDurableDate": primitive.NewDateTimeFromTime(applied.Add(-1500 * time.Millisecond)),
+			},
+			bson.M{"name": "rs4:27017", "stateStr": "ARBITER"},
+		},
+	}
+
+	var status proto.ReplicaSetStatus
+	require.NoError(t, decodeResult(m, &status))
+
+	expected := strings.NewReader(`
+	# HELP mongodb_rs_member_durable_lag_seconds Time between the last applied and the last durable optime of the member, in seconds.
+	# TYPE mongodb_rs_member_durable_lag_seconds gauge
+	mongodb_rs_member_durable_lag_seconds{name="rs2:27017",rs_nm="rs"} 0
+	mongodb_rs_member_durable_lag_seconds{name="rs3:27017",rs_nm="rs"} 1.5` + "\n")
+
+	metrics := durableLagMetrics(status, map[string]string{"rs_nm": "rs"})
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
+
+func TestInitialSyncMetrics(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, initialSyncMetrics(nil, map[string]string{"rs_nm": "rs"}))
+
+	// replSetGetStatus of a member cloning the data of its initial sync.
+	m := bson.M{
+		"set":     "rs",
+		"myState": int32(5),
+		"initialSyncStatus": bson.M{
+			"failedInitialSyncAttempts":     int32(0),
+			"totalInitialSyncElapsedMillis": int64(62000),
+			"approxTotalDataSize":           int64(400),
+			"approxTotalBytesCopied":        int64(100),
+			"initialSyncOplogStart":         primitive.Timestamp{T: 1700000000, I: 1},
+			"databases":                     bson.M{"databasesCloned": int32(1)},
+		},
+	}
+
+	var status proto.ReplicaSetStatus
+	assert.NoError(t, decodeResult(m, &status))
+
+	expected := strings.NewReader(`
+	# HELP mongodb_rs_initial_sync_phase Whether the running initial sync is in the phase.
+	# TYPE mongodb_rs_initial_sync_phase gauge
+	mongodb_rs_initial_sync_phase{phase="applying_oplog",rs_nm="rs"} 0
+	mongodb_rs_initial_sync_phase{phase="cloning",rs_nm="rs"} 1
+	# HELP mongodb_rs_initial_sync_progress_ratio Approximate ratio of the data cloned by the running initial sync, from 0 to 1.
+	# TYPE mongodb_rs_initial_sync_progress_ratio gauge
+	mongodb_rs_initial_sync_progress_ratio{rs_nm="rs"} 0.25` + "\n")
+
+	metrics := initialSyncMetrics(status.InitialSyncStatus, map[string]string{"rs_nm": "rs"})
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+
+	// Once the data is cloned, the oplog entries written meanwhile are applied.
+	status.InitialSyncStatus.ApproxTotalBytesCopied = 400
+	status.InitialSyncStatus.InitialSyncOplogEnd = primitive.Timestamp{T: 1700000100, I: 1}
+
+	expected = strings.NewReader(`
+	# HELP mongodb_rs_initial_sync_phase Whether the running initial sync is in the phase.
+	# TYPE mongodb_rs_initial_sync_phase gauge
+	mongodb_rs_initial_sync_phase{phase="applying_oplog",rs_nm="rs"} 1
+	mongodb_rs_initial_sync_phase{phase="cloning",rs_nm="rs"} 0
+	# HELP mongodb_rs_initial_sync_progress_ratio Approximate ratio of the data cloned by the running initial sync, from 0 to 1.
+	# TYPE mongodb_rs_initial_sync_progress_ratio gauge
+	mongodb_rs_initial_sync_progress_ratio{rs_nm="rs"} 1` + "\n")
+
+	metrics = initialSyncMetrics(status.InitialSyncStatus, map[string]string{"rs_nm": "rs"})
+	err = testutil.CollectAndCompare(metricsCollector(metrics), expected)
+	assert.NoError(t, err)
+}
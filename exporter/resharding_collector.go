@@ -0,0 +1,176 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// reshardingMinVersion is the first major version able to reshard a collection with reshardCollection.
+const reshardingMinVersion = 5
+
+type reshardingCollector struct {
+	ctx          context.Context
+	base         *baseCollector
+	buildInfo    buildInfo
+	topologyInfo labelsGetter
+}
+
+// newReshardingCollector creates a collector for the progress of the resharding operations.
+func newReshardingCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, buildInfo buildInfo, topology labelsGetter) *reshardingCollector {
+	return &reshardingCollector{
+		ctx:          ctx,
+		base:         newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "resharding"})),
+		buildInfo:    buildInfo,
+		topologyInfo: topology,
+	}
+}
+
+func (d *reshardingCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *reshardingCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *reshardingCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "resharding")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	if len(d.buildInfo.VersionArray) == 0 || d.buildInfo.VersionArray[0] < reshardingMinVersion {
+		logger.Debugf("resharding is not supported by MongoDB %s", d.buildInfo.Version)
+		return
+	}
+
+	// On mongos, $currentOp reports the resharding services of all the shards.
+	pipeline := mongo.Pipeline{
+		{{Key: "$currentOp", Value: bson.D{{Key: "allUsers", Value: true}, {Key: "localOps", Value: false}}}},
+		{{Key: "$match", Value: bson.D{{Key: "desc", Value: primitive.Regex{Pattern: "^Resharding"}}}}},
+	}
+
+	cursor, err := client.Database("admin").Aggregate(d.ctx, pipeline)
+	if err != nil {
+		logger.Errorf("cannot get the resharding operations from $currentOp: %s", err)
+		return
+	}
+
+	var ops []bson.M
+	if err := cursor.All(d.ctx, &ops); err != nil {
+		logger.Errorf("cannot decode the resharding operations: %s", err)
+		return
+	}
+
+	logger.Debug("$currentOp resharding result")
+	debugResult(logger, ops)
+
+	for _, metric := range reshardingMetrics(ops, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// reshardingRole returns the role of a resharding service from its $currentOp description,
+// e.g. "ReshardingRecipientService 3b2f..." is a recipient, and the field holding its state.
+func reshardingRole(desc string) (string, string) {
+	switch {
+	case strings.HasPrefix(desc, "ReshardingCoordinator"):
+		return "coordinator", "coordinatorState"
+	case strings.HasPrefix(desc, "ReshardingDonor"):
+		return "donor", "donorState"
+	case strings.HasPrefix(desc, "ReshardingRecipient"):
+		return "recipient", "recipientState"
+	default:
+		return "", ""
+	}
+}
+
+// reshardingMetrics returns the state of each resharding service in the $currentOp operations
+// and, for the recipients, the progress of the copy and the estimated remaining time.
+func reshardingMetrics(ops []bson.M, labels map[string]string) []prometheus.Metric {
+	state := prometheus.NewDesc("mongodb_resharding_operation_state",
+		"State of the resharding service of the shard for the namespace. The value is always 1",
+		[]string{"namespace", "shard", "role", "state"}, labels)
+	progress := prometheus.NewDesc("mongodb_resharding_operation_progress_ratio",
+		"Ratio of the data of the namespace copied by the recipient shard, from 0 to 1",
+		[]string{"namespace", "shard"}, labels)
+	remaining := prometheus.NewDesc("mongodb_resharding_operation_remaining_seconds",
+		"Estimated time until the recipient shard completes the resharding of the namespace, in seconds",
+		[]string{"namespace", "shard"}, labels)
+
+	var metrics []prometheus.Metric
+	for _, op := range ops {
+		namespace, _ := op["ns"].(string)
+		desc, _ := op["desc"].(string)
+		role, stateKey := reshardingRole(desc)
+		if namespace == "" || role == "" {
+			continue
+		}
+		shard, _ := op["shard"].(string)
+
+		if s, ok := op[stateKey].(string); ok {
+			metrics = append(metrics, prometheus.MustNewConstMetric(state, prometheus.GaugeValue, 1, namespace, shard, role, s))
+		}
+
+		if role != "recipient" {
+			continue
+		}
+
+		if ratio, ok := reshardingProgress(op); ok {
+			metrics = append(metrics, prometheus.MustNewConstMetric(progress, prometheus.GaugeValue, ratio, namespace, shard))
+		}
+
+		if f, err := asFloat64(op["remainingOperationTimeEstimatedSecs"]); err == nil && f != nil {
+			metrics = append(metrics, prometheus.MustNewConstMetric(remaining, prometheus.GaugeValue, *f, namespace, shard))
+		}
+	}
+
+	return metrics
+}
+
+// reshardingProgress returns the ratio of the bytes copied by a recipient, or of the documents
+// if the size to copy is unknown. It returns false until the recipient knows what to copy.
+func reshardingProgress(op bson.M) (float64, bool) {
+	for _, keys := range [][2]string{{"bytesCopied", "approxBytesToCopy"}, {"documentsCopied", "approxDocumentsToCopy"}} {
+		copied, err := asFloat64(op[keys[0]])
+		if err != nil || copied == nil {
+			continue
+		}
+		total, err := asFloat64(op[keys[1]])
+		if err != nil || total == nil || *total <= 0 {
+			continue
+		}
+
+		// The totals are estimates, the copy can exceed them.
+		if *copied >= *total {
+			return 1, true
+		}
+
+		return *copied / *total, true
+	}
+
+	return 0, false
+}
+
+var _ prometheus.Collector = (*reshardingCollector)(nil)
@@ -0,0 +1,115 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReshardingMetrics(t *testing.T) {
+	t.Parallel()
+
+	// $currentOp on mongos while shop.orders is resharded from shard0 to shard1.
+	ops := []bson.M{
+		{
+			"shard":            "config",
+			"desc":             "ReshardingCoordinatorService 3b2f7c4e-1f0a-4d2b-9a51-6c3e2d1f0a9b",
+			"ns":               "shop.orders",
+			"coordinatorState": "cloning",
+		},
+		{
+			"shard":      "shard0",
+			"desc":       "ReshardingDonorService 3b2f7c4e-1f0a-4d2b-9a51-6c3e2d1f0a9b",
+			"ns":         "shop.orders",
+			"donorState": "donating-initial-data",
+		},
+		{
+			"shard":                               "shard1",
+			"desc":                                "ReshardingRecipientService 3b2f7c4e-1f0a-4d2b-9a51-6c3e2d1f0a9b",
+			"ns":                                  "shop.orders",
+			"recipientState":                      "cloning",
+			"approxDocumentsToCopy":               int64(1000),
+			"documentsCopied":                     int64(500),
+			"approxBytesToCopy":                   int64(4000),
+			"bytesCopied":                         int64(1000),
+			"remainingOperationTimeEstimatedSecs": int64(90),
+		},
+		{
+			// Before cloning, the recipient doesn't know what to copy.
+			"shard":          "shard2",
+			"desc":           "ReshardingRecipientService 3b2f7c4e-1f0a-4d2b-9a51-6c3e2d1f0a9b",
+			"ns":             "shop.orders",
+			"recipientState": "created-collection",
+		},
+		{
+			"desc": "conn42",
+			"ns":   "shop.orders",
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_resharding_operation_progress_ratio Ratio of the data of the namespace copied by the recipient shard, from 0 to 1
+	# TYPE mongodb_resharding_operation_progress_ratio gauge
+	mongodb_resharding_operation_progress_ratio{namespace="shop.orders",rs_nm="rs",shard="shard1"} 0.25
+	# HELP mongodb_resharding_operation_remaining_seconds Estimated time until the recipient shard completes the resharding of the namespace, in seconds
+	# TYPE mongodb_resharding_operation_remaining_seconds gauge
+	mongodb_resharding_operation_remaining_seconds{namespace="shop.orders",rs_nm="rs",shard="shard1"} 90
+	# HELP mongodb_resharding_operation_state State of the resharding service of the shard for the namespace. The value is always 1
+	# TYPE mongodb_resharding_operation_state gauge
+	mongodb_resharding_operation_state{namespace="shop.orders",role="coordinator",rs_nm="rs",shard="config",state="cloning"} 1
+	mongodb_resharding_operation_state{namespace="shop.orders",role="donor",rs_nm="rs",shard="shard0",state="donating-initial-data"} 1
+	mongodb_resharding_operation_state{namespace="shop.orders",role="recipient",rs_nm="rs",shard="shard1",state="cloning"} 1
+	mongodb_resharding_operation_state{namespace="shop.orders",role="recipient",rs_nm="rs",shard="shard2",state="created-collection"} 1` + "\n")
+
+	metrics := reshardingMetrics(ops, map[string]string{"rs_nm": "rs"})
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+
+	assert.Empty(t, reshardingMetrics(nil, nil))
+}
+
+func TestReshardingProgress(t *testing.T) {
+	t.Parallel()
+
+	// Without the size to copy, the documents are used.
+	ratio, ok := reshardingProgress(bson.M{"documentsCopied": int64(30), "approxDocumentsToCopy": int64(120)})
+	assert.True(t, ok)
+	assert.Equal(t, 0.25, ratio)
+
+	// The totals are estimates, the ratio is capped.
+	ratio, ok = reshardingProgress(bson.M{"bytesCopied": int64(5000), "approxBytesToCopy": int64(4000)})
+	assert.True(t, ok)
+	assert.Equal(t, float64(1), ratio)
+
+	_, ok = reshardingProgress(bson.M{"bytesCopied": int64(0), "approxBytesToCopy": int64(0)})
+	assert.False(t, ok)
+}
+
+func TestReshardingUnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	// Before MongoDB 5.0 the collector doesn't run $currentOp, the nil client is not used.
+	c := newReshardingCollector(context.Background(), nil, logrus.New(),
+		buildInfo{Version: "4.4.29", VersionArray: []int{4, 4, 29, 0}}, labelsGetterMock{})
+
+	assert.Zero(t, testutil.CollectAndCount(c, "mongodb_resharding_operation_state"))
+}
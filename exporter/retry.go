@@ -0,0 +1,83 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const retryBaseDelay = 100 * time.Millisecond
+
+// Server error codes that are expected to go away by themselves, usually after a
+// replica set election. See https://www.mongodb.com/docs/manual/reference/error-codes/
+//
+//nolint:gochecknoglobals
+var transientErrorCodes = map[int32]struct{}{
+	6:     {}, // HostUnreachable
+	7:     {}, // HostNotFound
+	89:    {}, // NetworkTimeout
+	91:    {}, // ShutdownInProgress
+	189:   {}, // PrimarySteppedDown
+	9001:  {}, // SocketException
+	10107: {}, // NotWritablePrimary
+	11600: {}, // InterruptedAtShutdown
+	11602: {}, // InterruptedDueToReplStateChange
+	13435: {}, // NotPrimaryNoSecondaryOk
+	13436: {}, // NotPrimaryOrSecondary
+}
+
+func isTransientError(err error) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+
+	_, ok := transientErrorCodes[cmdErr.Code]
+
+	return ok
+}
+
+// withRetries calls fn and calls it again, up to retries more times, while it fails with a
+// transient error. It gives up as soon as the context is done and returns the last error.
+func withRetries(ctx context.Context, retries int, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt <= retries && isTransientError(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * retryBaseDelay):
+		}
+
+		err = fn()
+	}
+
+	return err
+}
+
+// runCommand runs an admin or database command retrying it on transient errors.
+func runCommand(ctx context.Context, db *mongo.Database, cmd interface{}, retries int) *mongo.SingleResult {
+	var res *mongo.SingleResult
+	_ = withRetries(ctx, retries, func() error {
+		res = db.RunCommand(ctx, cmd)
+		return res.Err()
+	})
+
+	return res
+}
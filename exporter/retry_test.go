@@ -0,0 +1,71 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWithRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	transient := mongo.CommandError{Code: 11602, Name: "InterruptedDueToReplStateChange"}
+
+	t.Run("transient error is retried", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		err := withRetries(ctx, 2, func() error {
+			calls++
+			if calls == 1 {
+				return transient
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		err := withRetries(ctx, 2, func() error {
+			calls++
+			return transient
+		})
+		assert.ErrorAs(t, err, &mongo.CommandError{})
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		err := withRetries(ctx, 2, func() error {
+			calls++
+			return errors.New("unauthorized")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
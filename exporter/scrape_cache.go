@@ -0,0 +1,100 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
+)
+
+// scrapeCache keeps the results of admin commands shared by several collectors, so each
+// command runs at most once per scrape. A new cache must be created for every scrape.
+type scrapeCache struct {
+	run func(ctx context.Context, name string) (bson.M, error)
+
+	lock    sync.Mutex
+	results map[string]*cachedCommand
+}
+
+type cachedCommand struct {
+	once   sync.Once
+	result bson.M
+	err    error
+}
+
+// newScrapeCache creates a cache running the admin commands against the given client.
+func newScrapeCache(client *mongo.Client, commandRetries int) *scrapeCache {
+	return &scrapeCache{
+		run: func(ctx context.Context, name string) (bson.M, error) {
+			var m bson.M
+			cmd := bson.D{{Key: name, Value: "1"}}
+			if err := runCommand(ctx, client.Database("admin"), cmd, commandRetries).Decode(&m); err != nil {
+				return nil, err
+			}
+
+			return m, nil
+		},
+		results: make(map[string]*cachedCommand),
+	}
+}
+
+// command returns the result of the admin command, running it only on the first call.
+// Errors are cached too, a failing command is not retried within the same scrape.
+func (c *scrapeCache) command(ctx context.Context, name string) (bson.M, error) {
+	c.lock.Lock()
+	cmd, ok := c.results[name]
+	if !ok {
+		cmd = new(cachedCommand)
+		c.results[name] = cmd
+	}
+	c.lock.Unlock()
+
+	cmd.once.Do(func() {
+		cmd.result, cmd.err = c.run(ctx, name)
+	})
+
+	return cmd.result, cmd.err
+}
+
+// decodeResult decodes a cached command result into the given struct.
+func decodeResult(m bson.M, v interface{}) error {
+	b, err := bson.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(b, v)
+}
+
+// replSetStatus returns the replSetGetStatus result of the scrape.
+func (c *scrapeCache) replSetStatus(ctx context.Context) (*proto.ReplicaSetStatus, error) {
+	m, err := c.command(ctx, "replSetGetStatus")
+	if err != nil {
+		return nil, err
+	}
+
+	var status proto.ReplicaSetStatus
+	if err := decodeResult(m, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
@@ -0,0 +1,83 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestScrapeCache(t *testing.T) {
+	t.Parallel()
+
+	var calls sync.Map
+	errFailing := errors.New("command failed")
+	cache := &scrapeCache{
+		run: func(_ context.Context, name string) (bson.M, error) {
+			n, _ := calls.LoadOrStore(name, new(int32))
+			atomic.AddInt32(n.(*int32), 1)
+			if name == "replSetGetStatus" {
+				return nil, errFailing
+			}
+
+			return bson.M{"data": bson.M{"name": name}}, nil
+		},
+		results: make(map[string]*cachedCommand),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, err := cache.command(context.Background(), "getDiagnosticData")
+			assert.NoError(t, err)
+			assert.Equal(t, bson.M{"data": bson.M{"name": "getDiagnosticData"}}, m)
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.command(context.Background(), "replSetGetStatus")
+		assert.ErrorIs(t, err, errFailing)
+	}
+
+	for _, name := range []string{"getDiagnosticData", "replSetGetStatus"} {
+		n, ok := calls.Load(name)
+		if assert.True(t, ok, name) {
+			assert.Equal(t, int32(1), atomic.LoadInt32(n.(*int32)), name)
+		}
+	}
+}
+
+func TestDecodeResult(t *testing.T) {
+	t.Parallel()
+
+	var status struct {
+		Set     string  `bson:"set"`
+		MyState float64 `bson:"myState"`
+	}
+	err := decodeResult(bson.M{"set": "rs1", "myState": int32(2)}, &status)
+	assert.NoError(t, err)
+	assert.Equal(t, "rs1", status.Set)
+	assert.Equal(t, float64(2), status.MyState)
+}
@@ -0,0 +1,162 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// CheckResult is the result of one of the commands run by SelfTest.
+type CheckResult struct {
+	Command string
+	OK      bool
+	Error   string
+}
+
+// selfTestCheck is a command needed by an enabled collector.
+type selfTestCheck struct {
+	command string
+	run     func(ctx context.Context, client *mongo.Client) error
+}
+
+// NodeName returns the name of the node scraped by the exporter.
+func (e *Exporter) NodeName() string {
+	return e.opts.NodeName
+}
+
+// SelfTest runs once each command needed by the enabled collectors and reports which ones
+// fail, so missing privileges can be found before Prometheus scrapes the exporter.
+func (e *Exporter) SelfTest(ctx context.Context) []CheckResult {
+	client, err := e.getClient(ctx)
+	if err != nil {
+		return []CheckResult{newCheckResult("connect", err)}
+	}
+
+	if !e.opts.GlobalConnPool {
+		defer func() {
+			if err := client.Disconnect(ctx); err != nil {
+				e.logger.Errorf("Cannot disconnect client: %v", err)
+			}
+		}()
+	}
+
+	results := []CheckResult{newCheckResult("ping", client.Ping(ctx, readpref.PrimaryPreferred()))}
+
+	nodeType, err := getNodeType(ctx, client)
+	results = append(results, newCheckResult("isMaster", err))
+
+	return append(results, runChecks(ctx, client, selfTestChecks(e.opts, nodeType))...)
+}
+
+// selfTestChecks returns the commands run by the collectors enabled in opts on a node of the
+// given type. PBM connects with its own client and is not checked.
+func selfTestChecks(opts *Opts, nodeType mongoDBNodeType) []selfTestCheck {
+	var checks []selfTestCheck
+
+	add := func(enabled bool, command string, run func(ctx context.Context, client *mongo.Client) error) {
+		if enabled {
+			checks = append(checks, selfTestCheck{command: command, run: run})
+		}
+	}
+
+	mongos := nodeType == typeMongos
+
+	add(true, "buildInfo", adminCommand(bson.D{{Key: "buildInfo", Value: 1}}))
+	add(opts.EnableDiagnosticData, "getDiagnosticData", adminCommand(bson.D{{Key: "getDiagnosticData", Value: 1}}))
+	add(opts.EnableDiagnosticData || opts.EnableShardingStatistics, "serverStatus",
+		adminCommand(bson.D{{Key: "serverStatus", Value: 1}}))
+	add(opts.EnableDBStats || opts.EnableDatabaseAccess || opts.EnableShards, "listDatabases",
+		func(ctx context.Context, client *mongo.Client) error {
+			_, err := client.ListDatabaseNames(ctx, bson.D{})
+			return err
+		})
+	add(opts.EnableDBStats, "dbStats", adminCommand(bson.D{{Key: "dbStats", Value: 1}}))
+	add(opts.EnableTopMetrics && !mongos, "top", adminCommand(bson.D{{Key: "top", Value: 1}}))
+	add(((opts.EnableCurrentopMetrics || opts.EnableFsyncLockMetrics) && !mongos) || opts.EnableQuerySampling || (opts.EnableResharding && mongos), "currentOp", adminCommand(bson.D{{Key: "currentOp", Value: 1}}))
+	add((opts.EnableProfile || opts.EnableProfileStats) && !mongos, "profile",
+		adminCommand(bson.D{{Key: "profile", Value: -1}}))
+	add((opts.EnableReplicasetStatus || opts.EnableClusterHealth) && !mongos, "replSetGetStatus",
+		adminCommand(bson.D{{Key: "replSetGetStatus", Value: 1}}))
+	add(opts.EnableReplicasetConfig && !mongos, "replSetGetConfig",
+		adminCommand(bson.D{{Key: "replSetGetConfig", Value: 1}}))
+	add(opts.EnableShards && mongos, "balancerStatus", adminCommand(bson.D{{Key: "balancerStatus", Value: 1}}))
+	add(opts.EnableShards && mongos, "find config.chunks", func(ctx context.Context, client *mongo.Client) error {
+		_, err := client.Database("config").Collection("chunks").CountDocuments(ctx, bson.M{})
+		return err
+	})
+	add(opts.EnablePreImages && !mongos, "listCollections config", func(ctx context.Context, client *mongo.Client) error {
+		_, err := client.Database("config").ListCollectionNames(ctx, bson.D{{Key: "name", Value: preImagesCollection}})
+		return err
+	})
+	add(opts.EnableFCV, "getParameter featureCompatibilityVersion",
+		adminCommand(bson.D{{Key: "getParameter", Value: 1}, {Key: "featureCompatibilityVersion", Value: 1}}))
+	add(opts.EnableParameters, "getParameter *", adminCommand(bson.D{{Key: "getParameter", Value: "*"}}))
+	add(opts.EnableParameterMetrics, "getCmdLineOpts", adminCommand(bson.D{{Key: "getCmdLineOpts", Value: 1}}))
+
+	// $collStats and $indexStats are checked on the first configured collection, the privileges
+	// of the others can differ.
+	if opts.EnableCollStats && len(opts.CollStatsNamespaces) > 0 {
+		database, collection := splitNamespace(opts.CollStatsNamespaces[0])
+		add(collection != "", "$collStats "+opts.CollStatsNamespaces[0], aggregate(database, collection,
+			bson.D{{Key: "$collStats", Value: bson.M{"storageStats": bson.M{}}}}))
+	}
+	if opts.EnableIndexStats && len(opts.IndexStatsCollections) > 0 {
+		database, collection := splitNamespace(opts.IndexStatsCollections[0])
+		add(collection != "", "$indexStats "+opts.IndexStatsCollections[0], aggregate(database, collection,
+			bson.D{{Key: "$indexStats", Value: bson.M{}}}))
+	}
+
+	return checks
+}
+
+// runChecks runs the checks one after the other, a failed check doesn't stop the others.
+func runChecks(ctx context.Context, client *mongo.Client, checks []selfTestCheck) []CheckResult {
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, newCheckResult(check.command, check.run(ctx, client)))
+	}
+
+	return results
+}
+
+func adminCommand(cmd bson.D) func(ctx context.Context, client *mongo.Client) error {
+	return func(ctx context.Context, client *mongo.Client) error {
+		return client.Database("admin").RunCommand(ctx, cmd).Err()
+	}
+}
+
+func aggregate(database, collection string, stage bson.D) func(ctx context.Context, client *mongo.Client) error {
+	return func(ctx context.Context, client *mongo.Client) error {
+		cursor, err := client.Database(database).Collection(collection).Aggregate(ctx, mongo.Pipeline{stage})
+		if err != nil {
+			return err
+		}
+
+		return cursor.Close(ctx)
+	}
+}
+
+func newCheckResult(command string, err error) CheckResult {
+	if err != nil {
+		return CheckResult{Command: command, Error: err.Error()}
+	}
+
+	return CheckResult{Command: command, OK: true}
+}
@@ -0,0 +1,91 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSelfTestChecks(t *testing.T) {
+	t.Parallel()
+
+	commands := func(checks []selfTestCheck) []string {
+		names := make([]string, 0, len(checks))
+		for _, c := range checks {
+			names = append(names, c.command)
+		}
+		return names
+	}
+
+	opts := &Opts{
+		EnableReplicasetStatus: true,
+		EnableClusterHealth:    true,
+		EnableShards:           true,
+		EnableCollStats:        true,
+		CollStatsNamespaces:    []string{"db1.col1", "db2.col2"},
+	}
+
+	// replSetGetStatus is needed by two collectors but checked once.
+	assert.Equal(t, []string{"buildInfo", "listDatabases", "replSetGetStatus", "$collStats db1.col1"},
+		commands(selfTestChecks(opts, typeMongod)))
+	assert.Equal(t, []string{"buildInfo", "listDatabases", "balancerStatus", "find config.chunks", "$collStats db1.col1"},
+		commands(selfTestChecks(opts, typeMongos)))
+}
+
+func TestSelfTest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("connection error", func(t *testing.T) {
+		t.Parallel()
+
+		e := &Exporter{
+			logger: logrus.New(),
+			opts:   &Opts{},
+			connectFn: func(context.Context, *Opts, *event.PoolMonitor) (*mongo.Client, error) {
+				return nil, errors.New("connection refused")
+			},
+		}
+
+		assert.Equal(t, []CheckResult{{Command: "connect", Error: "connection refused"}}, e.SelfTest(ctx))
+	})
+
+	t.Run("failed checks don't stop the others", func(t *testing.T) {
+		t.Parallel()
+
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+		assert.NoError(t, err)
+
+		checks := []selfTestCheck{
+			{command: "replSetGetStatus", run: func(context.Context, *mongo.Client) error { return errors.New("not authorized") }},
+			{command: "top", run: func(context.Context, *mongo.Client) error { return nil }},
+		}
+
+		assert.Equal(t, []CheckResult{
+			{Command: "replSetGetStatus", Error: "not authorized"},
+			{Command: "top", OK: true},
+		}, runChecks(ctx, client, checks))
+	})
+}
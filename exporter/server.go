@@ -17,9 +17,13 @@ package exporter
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +35,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 // ServerMap stores http handlers for each host
 type ServerMap map[string]http.Handler
 
@@ -42,35 +48,64 @@ type ServerOpts struct {
 	WebListenAddress       string
 	TLSConfigPath          string
 	DisableDefaultRegistry bool
+
+	// Path serving the collstats and indexstats metrics of the namespace in the ns parameter.
+	// Not served if empty.
+	CollectionPath string
+
+	// Path listing the metrics renamed in compatible mode. Not served if empty.
+	MappingsPath string
+
+	// Exporter version shown on the landing page.
+	Version string
+
+	// Serve the metrics of all the exporters on Path, as OverallTargetPath does, instead of the
+	// metrics of the first one.
+	AggregateTargets bool
 }
 
 // Runs the main web-server
 func RunWebServer(opts *ServerOpts, exporters []*Exporter, log *logrus.Logger) {
-	mux := http.DefaultServeMux
+	if err := RunWebServerContext(context.Background(), opts, exporters, log); err != nil {
+		log.Errorf("error starting server: %v", err)
+		os.Exit(1)
+	}
+}
 
+// RunWebServerContext runs the main web-server until ctx is done. Then it shuts down the
+// server, waiting up to shutdownTimeout for in-flight scrapes, and closes the exporters.
+func RunWebServerContext(ctx context.Context, opts *ServerOpts, exporters []*Exporter, log *logrus.Logger) error {
 	if len(exporters) == 0 {
 		panic("No exporters were built. You must specify --mongodb.uri command argument or MONGODB_URI environment variable")
 	}
 
+	mux := http.NewServeMux()
+	// Keep serving the profiling handlers registered by net/http/pprof, even when the metrics
+	// are served at the root path. The other paths fall back to http.DefaultServeMux through the
+	// landing page handler.
+	mux.Handle("/debug/pprof/", http.DefaultServeMux)
+
 	serverMap := buildServerMap(exporters, log)
 
-	defaultExporter := exporters[0]
-	mux.Handle(opts.Path, defaultExporter.Handler())
+	if opts.AggregateTargets {
+		mux.HandleFunc(opts.Path, OverallTargetsHandler(exporters, log))
+	} else {
+		defaultExporter := exporters[0]
+		mux.Handle(opts.Path, defaultExporter.Handler())
+	}
 	mux.HandleFunc(opts.MultiTargetPath, multiTargetHandler(serverMap))
 	mux.HandleFunc(opts.OverallTargetPath, OverallTargetsHandler(exporters, log))
+	if opts.MappingsPath != "" {
+		mux.HandleFunc(opts.MappingsPath, compatibleMappingsHandler(log))
+	}
+	if opts.CollectionPath != "" {
+		mux.Handle(opts.CollectionPath, collectionTargetHandler(exporters, log))
+	}
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		_, err := w.Write([]byte(`<html>
-            <head><title>MongoDB Exporter</title></head>
-            <body>
-            <h1>MongoDB Exporter</h1>
-            <p><a href='/metrics'>Metrics</a></p>
-            </body>
-            </html>`))
-		if err != nil {
-			log.Errorf("error writing response: %v", err)
-		}
-	})
+	// The metrics can be served at the root path too, there is no landing page then.
+	if opts.Path != "/" {
+		mux.HandleFunc("/", landingPageHandler(opts, log))
+	}
 
 	server := &http.Server{
 		ReadHeaderTimeout: 2 * time.Second,
@@ -82,11 +117,94 @@ func RunWebServer(opts *ServerOpts, exporters []*Exporter, log *logrus.Logger) {
 	}
 	logLevel := &promslog.AllowedLevel{}
 	_ = logLevel.Set(log.Level.String())
-	if err := web.ListenAndServe(server, flags, promslog.New(&promslog.Config{ //nolint:exhaustivestruct
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("error shutting down server: %v", err)
+		}
+	}()
+
+	err := web.ListenAndServe(server, flags, promslog.New(&promslog.Config{ //nolint:exhaustivestruct
 		Level: logLevel,
-	})); err != nil {
-		log.Errorf("error starting server: %v", err)
-		os.Exit(1)
+	}))
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, e := range exporters {
+		if err := e.Close(closeCtx); err != nil {
+			log.Errorf("Cannot disconnect client: %v", err)
+		}
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+var landingPage = template.Must(template.New("landing").Parse(`<html>
+<head><title>MongoDB Exporter</title></head>
+<body>
+<h1>MongoDB Exporter</h1>
+{{if .Version}}<p>Version: {{.Version}}</p>
+{{end}}<ul>
+<li><a href="{{.Path}}">Metrics</a></li>
+<li><a href="{{.OverallTargetPath}}">Metrics of all the targets</a></li>
+<li><a href="{{.MultiTargetPath}}?target=">Metrics of one target</a> (set the target parameter)</li>
+{{if .CollectionPath}}<li><a href="{{.CollectionPath}}?ns=">Metrics of one collection</a> (set the ns parameter)</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// landingPageHandler serves the links to the metrics paths at /. The other paths are delegated to
+// http.DefaultServeMux, to keep the handlers registered there by the programs embedding the exporter.
+func landingPageHandler(opts *ServerOpts, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.DefaultServeMux.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := landingPage.Execute(w, opts); err != nil {
+			log.Errorf("error writing response: %v", err)
+		}
+	}
+}
+
+// compatibleMappingsHandler lists the metrics renamed in compatible mode, one "name legacy_name"
+// pair per line, to help migrating the dashboards. It is not linked from the landing page.
+func compatibleMappingsHandler(log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		mappings := CompatibleMetricMappings()
+		names := make([]string, 0, len(mappings))
+		for name := range mappings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, name := range names {
+			if _, err := fmt.Fprintf(w, "%s %s\n", name, mappings[name]); err != nil {
+				log.Errorf("error writing response: %v", err)
+				return
+			}
+		}
 	}
 }
 
@@ -108,8 +226,20 @@ func multiTargetHandler(serverMap ServerMap) http.HandlerFunc {
 	}
 }
 
+// defaultRegistryDisabled returns true if all the exporters skip the Go and process metrics of
+// the default registry, which are served once for all the targets.
+func defaultRegistryDisabled(exporters []*Exporter) bool {
+	for _, e := range exporters {
+		if !e.opts.DisableDefaultRegistry {
+			return false
+		}
+	}
+
+	return len(exporters) > 0
+}
+
 // OverallTargetsHandler is a handler to scrape all the targets in one request.
-// Adds instance label to each metric.
+// Adds instance and cluster ID labels to each metric.
 func OverallTargetsHandler(exporters []*Exporter, logger *logrus.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		seconds, err := strconv.Atoi(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"))
@@ -120,9 +250,15 @@ func OverallTargetsHandler(exporters []*Exporter, logger *logrus.Logger) http.Ha
 		}
 
 		var gatherers prometheus.Gatherers
-		gatherers = append(gatherers, prometheus.DefaultGatherer)
+		if !defaultRegistryDisabled(exporters) {
+			gatherers = append(gatherers, prometheus.DefaultGatherer)
+		}
 
 		filters := r.URL.Query()["collect[]"]
+		if err := CheckRequestFilters(filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		for _, e := range exporters {
 			ctx, cancel := context.WithTimeout(r.Context(), time.Duration(seconds-e.opts.TimeoutOffset)*time.Second)
@@ -130,39 +266,19 @@ func OverallTargetsHandler(exporters []*Exporter, logger *logrus.Logger) http.Ha
 
 			requestOpts := GetRequestOpts(filters, e.opts)
 
-			client, err := e.getClient(ctx)
-			if err != nil {
-				e.logger.Errorf("Cannot connect to MongoDB: %v", err)
-			}
-
-			// Close client after usage.
-			if !e.opts.GlobalConnPool {
-				defer func() {
-					if client != nil {
-						err := client.Disconnect(ctx)
-						if err != nil {
-							logger.Errorf("Cannot disconnect client: %v", err)
-						}
-					}
-				}()
-			}
-
-			var registry *prometheus.Registry
-			var ti *topologyInfo
-			if client != nil {
-				// Topology can change between requests, so we need to get it every time.
-				ti = newTopologyInfo(ctx, client, e.logger)
-				registry = e.makeRegistry(ctx, client, ti, requestOpts)
-			} else {
-				registry = prometheus.NewRegistry()
-				gc := newGeneralCollector(ctx, client, "", e.opts.Logger)
-				registry.MustRegister(gc)
-			}
+			// A target which cannot be reached only has mongodb_up=0, the others are not affected.
+			registry, ti := e.scrapeRegistry(ctx, requestOpts)
 
 			hostlabels := prometheus.Labels{}
 			if e.opts.NodeName != "" {
 				hostlabels["instance"] = e.opts.NodeName
 			}
+			// Targets of different clusters can have the same node name, e.g. localhost tunnels.
+			if ti != nil {
+				if cid := ti.baseLabels()[labelClusterID]; cid != "" {
+					hostlabels[labelClusterID] = cid
+				}
+			}
 
 			gw := NewGathererWrapper(registry, hostlabels)
 			gatherers = append(gatherers, gw)
@@ -178,11 +294,26 @@ func OverallTargetsHandler(exporters []*Exporter, logger *logrus.Logger) http.Ha
 	}
 }
 
+// collectionTargetHandler serves the collection metrics of the only exporter or, if there are
+// several, of the one selected by the target parameter as for the multi-target path.
+func collectionTargetHandler(exporters []*Exporter, log *logrus.Logger) http.Handler {
+	if len(exporters) == 1 {
+		return exporters[0].CollectionHandler()
+	}
+
+	return multiTargetHandler(buildHandlerMap(exporters, (*Exporter).CollectionHandler, log))
+}
+
 func buildServerMap(exporters []*Exporter, log *logrus.Logger) ServerMap {
+	return buildHandlerMap(exporters, (*Exporter).Handler, log)
+}
+
+// buildHandlerMap maps the host of each exporter to the handler returned by handler.
+func buildHandlerMap(exporters []*Exporter, handler func(*Exporter) http.Handler, log *logrus.Logger) ServerMap {
 	servers := make(ServerMap, len(exporters))
 	for _, e := range exporters {
 		if url, err := url.Parse(e.opts.URI); err == nil {
-			servers[url.Host] = e.Handler()
+			servers[url.Host] = handler(e)
 		} else {
 			log.Errorf("Unable to parse addr %s as url: %s", e.opts.URI, err)
 		}
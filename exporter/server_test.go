@@ -0,0 +1,82 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLandingPageHandler(t *testing.T) {
+	t.Parallel()
+
+	opts := &ServerOpts{
+		Path:              "/custom-metrics",
+		MultiTargetPath:   "/scrape",
+		OverallTargetPath: "/scrapeall",
+		Version:           "v1.2.3",
+	}
+	h := landingPageHandler(opts, logrus.New())
+
+	assert.HTTPStatusCode(t, h, http.MethodGet, "/", nil, http.StatusOK)
+	assert.HTTPBodyContains(t, h, http.MethodGet, "/", nil, `<a href="/custom-metrics">Metrics</a>`)
+	assert.HTTPBodyContains(t, h, http.MethodGet, "/", nil, `<a href="/scrapeall">`)
+	assert.HTTPBodyContains(t, h, http.MethodGet, "/", nil, "Version: v1.2.3")
+
+	assert.HTTPStatusCode(t, h, http.MethodGet, "/unknown", nil, http.StatusNotFound)
+
+	http.HandleFunc("/landing-page-test", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("default mux"))
+	})
+	assert.HTTPBodyContains(t, h, http.MethodGet, "/landing-page-test", nil, "default mux")
+
+	opts.Version = ""
+	assert.HTTPBodyNotContains(t, h, http.MethodGet, "/", nil, "Version")
+}
+
+func TestCompatibleMappingsHandler(t *testing.T) {
+	t.Parallel()
+
+	h := compatibleMappingsHandler(logrus.New())
+
+	assert.HTTPStatusCode(t, h, http.MethodGet, "/mappings", nil, http.StatusOK)
+	assert.HTTPBodyContains(t, h, http.MethodGet, "/mappings", nil, "mongodb_ss_asserts mongodb_asserts_total\n")
+}
+
+func TestCollectionTargetHandler(t *testing.T) {
+	t.Parallel()
+
+	exporters := make([]*Exporter, 2)
+	for i, uri := range []string{"mongodb://127.0.0.1:1", "mongodb://127.0.0.1:2"} {
+		e, err := New(&Opts{URI: uri, Logger: logrus.New()})
+		require.NoError(t, err)
+		exporters[i] = e
+	}
+
+	// The namespace is checked once the target is found.
+	h := collectionTargetHandler(exporters[:1], logrus.New())
+	assert.HTTPStatusCode(t, h.ServeHTTP, http.MethodGet, "/collection", nil, http.StatusBadRequest)
+
+	h = collectionTargetHandler(exporters, logrus.New())
+	assert.HTTPStatusCode(t, h.ServeHTTP, http.MethodGet, "/collection", nil, http.StatusNotFound)
+	assert.HTTPStatusCode(t, h.ServeHTTP, http.MethodGet, "/collection", url.Values{"target": {"127.0.0.1:2"}}, http.StatusBadRequest)
+	assert.HTTPStatusCode(t, h.ServeHTTP, http.MethodGet, "/collection", url.Values{"target": {"127.0.0.1:3"}}, http.StatusNotFound)
+}
@@ -0,0 +1,101 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const clusterRoleShardServer = "shardsvr"
+
+type shardingStatisticsCollector struct {
+	ctx  context.Context
+	base *baseCollector
+
+	topologyInfo labelsGetter
+}
+
+// newShardingStatisticsCollector creates a collector for serverStatus.shardingStatistics on shard members.
+func newShardingStatisticsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, topology labelsGetter) *shardingStatisticsCollector {
+	return &shardingStatisticsCollector{
+		ctx:  ctx,
+		base: newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "shardingstatistics"})),
+
+		topologyInfo: topology,
+	}
+}
+
+func (d *shardingStatisticsCollector) Describe(ch chan<- *prometheus.Desc) {
+	d.base.Describe(d.ctx, ch, d.collect)
+}
+
+func (d *shardingStatisticsCollector) Collect(ch chan<- prometheus.Metric) {
+	d.base.Collect(ch)
+}
+
+func (d *shardingStatisticsCollector) collect(ch chan<- prometheus.Metric) {
+	defer measureCollectTime(ch, "mongodb", "sharding_statistics")()
+
+	logger := d.base.logger
+	client := d.base.client
+
+	// shardingStatistics is also reported by config servers but the range deleter
+	// and catalog cache stats we are interested in only make sense on shards.
+	role, err := getClusterRole(d.ctx, client, logger)
+	if err != nil {
+		logger.Errorf("cannot get cluster role: %s", err)
+		return
+	}
+	if role != clusterRoleShardServer {
+		logger.Debugf("node cluster role is %q, skipping sharding statistics", role)
+		return
+	}
+
+	cmd := bson.D{{Key: "serverStatus", Value: 1}}
+	res := client.Database("admin").RunCommand(d.ctx, cmd)
+
+	var m bson.M
+	if err := res.Decode(&m); err != nil {
+		logger.Errorf("cannot get serverStatus: %s", err)
+		return
+	}
+
+	stats, ok := m["shardingStatistics"].(bson.M)
+	if !ok {
+		logger.Debug("serverStatus has no shardingStatistics section")
+		return
+	}
+
+	logger.Debug("shardingStatistics result:")
+	debugResult(logger, stats)
+
+	for _, metric := range shardingStatisticsMetrics(stats, d.topologyInfo.baseLabels()) {
+		ch <- metric
+	}
+}
+
+// shardingStatisticsMetrics converts the serverStatus.shardingStatistics document, including
+// nested sections like catalogCache, to mongodb_sharding_statistics_* metrics.
+func shardingStatisticsMetrics(stats bson.M, labels map[string]string) []prometheus.Metric {
+	return makeMetrics("sharding_statistics", stats, labels, false)
+}
+
+var _ prometheus.Collector = (*shardingStatisticsCollector)(nil)
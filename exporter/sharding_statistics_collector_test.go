@@ -0,0 +1,58 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestShardingStatisticsMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := bson.M{
+		"countStaleConfigErrors":                 int64(3),
+		"countDonorMoveChunkStarted":             int64(1),
+		"totalCriticalSectionTimeMillis":         int64(12),
+		"rangeDeleterTasks":                      int32(5),
+		"unfinishedMigrationFromPreviousPrimary": int64(0),
+		"catalogCache": bson.M{
+			"numDatabaseEntries":     int64(2),
+			"numCollectionEntries":   int64(4),
+			"countStaleConfigErrors": int64(0),
+			"operationsBlockedByRefresh": bson.M{
+				"countAllOperations": int64(7),
+			},
+		},
+	}
+
+	metrics := shardingStatisticsMetrics(stats, map[string]string{"rs_nm": "rs1"})
+
+	want := []string{
+		"mongodb_sharding_statistics_catalogCache_countStaleConfigErrors",
+		"mongodb_sharding_statistics_catalogCache_numCollectionEntries",
+		"mongodb_sharding_statistics_catalogCache_numDatabaseEntries",
+		"mongodb_sharding_statistics_catalogCache_operationsBlockedByRefresh_countAllOperations",
+		"mongodb_sharding_statistics_countDonorMoveChunkStarted",
+		"mongodb_sharding_statistics_countStaleConfigErrors",
+		"mongodb_sharding_statistics_rangeDeleterTasks",
+		"mongodb_sharding_statistics_totalCriticalSectionTimeMillis",
+		"mongodb_sharding_statistics_unfinishedMigrationFromPreviousPrimary",
+	}
+	assert.Equal(t, want, metricNames(metrics))
+}
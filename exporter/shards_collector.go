@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -26,20 +27,30 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/percona/mongodb_exporter/internal/proto"
+	"github.com/percona/mongodb_exporter/internal/util"
 )
 
 type shardsCollector struct {
 	ctx        context.Context
 	base       *baseCollector
 	compatible bool
+
+	// Epochs of the sharded collections seen in the previous scrapes of the exporter. Without
+	// tracker, the epoch changes are not exported.
+	epochs *epochTracker
 }
 
 // newShardsCollector creates collector collecting metrics about chunks for shards Mongo.
-func newShardsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatibleMode bool) *shardsCollector {
+func newShardsCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatibleMode bool,
+	epochs *epochTracker,
+) *shardsCollector {
 	return &shardsCollector{
 		ctx:        ctx,
 		base:       newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "shards"})),
 		compatible: compatibleMode,
+		epochs:     epochs,
 	}
 }
 
@@ -74,6 +85,14 @@ func (d *shardsCollector) collect(ch chan<- prometheus.Metric) {
 		metrics = append(metrics, ms...)
 	}
 
+	var clusterID string
+	if cv, err := util.ConfigVersion(ctx, client); err != nil {
+		logger.Warnf("cannot get config.version: %s", err)
+	} else {
+		metrics = append(metrics, configVersionMetrics(cv)...)
+		clusterID = cv.ClusterID.Hex()
+	}
+
 	for _, metric := range metrics {
 		ch <- metric
 	}
@@ -82,6 +101,7 @@ func (d *shardsCollector) collect(ch chan<- prometheus.Metric) {
 	if err != nil {
 		logger.Errorf("cannot get database names: %s", err)
 	}
+	seen := make(map[string]bool)
 	for _, database := range databaseNames {
 		collections := d.getCollectionsForDBName(database)
 		for _, row := range collections {
@@ -108,8 +128,39 @@ func (d *shardsCollector) collect(ch chan<- prometheus.Metric) {
 					ch <- metric
 				}
 			}
+
+			if dropped, ok := row["dropped"].(bool); ok && dropped {
+				continue
+			}
+
+			collection := strings.Replace(rowID, fmt.Sprintf("%s.", database), "", 1)
+			seen[database+"."+collection] = true
+			for _, metric := range collectionVersionMetrics(row, clusterID, database, collection, d.epochs) {
+				ch <- metric
+			}
+
+			sizes := d.getSizePerShard(database, collection)
+			for shard, size := range sizes {
+				labels := map[string]string{"database": database, "collection": collection, "shard": shard}
+				desc := prometheus.NewDesc("mongodb_sharded_collection_size_bytes",
+					"Data size of the sharded collection on each shard", nil, labels)
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, size)
+			}
+
+			for shard, ratio := range shardDataRatios(sizes) {
+				labels := map[string]string{"database": database, "collection": collection, "shard": shard}
+				desc := prometheus.NewDesc("mongodb_sharded_collection_shard_data_ratio",
+					"Fraction of the data of the sharded collection stored on each shard", nil, labels)
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, ratio)
+			}
 		}
 	}
+
+	// The dropped collections are forgotten, they start again from zero changes if they are
+	// sharded again. Nothing is forgotten when the collections could not be listed.
+	if err == nil && d.epochs != nil {
+		d.epochs.retain(clusterID, seen)
+	}
 }
 
 func (d *shardsCollector) getInfoForChunk(c primitive.M, database, rowID string) (map[string]string, int32, bool) {
@@ -207,6 +258,95 @@ func (d *shardsCollector) getChunksForCollection(row primitive.M) []bson.M {
 	return chunks
 }
 
+// getSizePerShard returns the data size of the collection on each shard. Through mongos,
+// $collStats returns one document per shard, so unlike counting config.chunks it doesn't
+// depend on the chunks schema (namespace based before 5.0, uuid based after).
+func (d *shardsCollector) getSizePerShard(database, collection string) map[string]float64 {
+	client := d.base.client
+	logger := d.base.logger
+
+	aggregation := bson.A{
+		bson.M{"$collStats": bson.M{"storageStats": bson.M{}}},
+		bson.M{"$project": bson.M{"shard": 1, "storageStats.size": 1}},
+	}
+
+	cur, err := client.Database(database).Collection(collection).Aggregate(d.ctx, aggregation)
+	if err != nil {
+		logger.Errorf("cannot get $collStats cursor for collection %s.%s: %s", database, collection, err)
+		return nil
+	}
+
+	var stats []bson.M
+	if err := cur.All(d.ctx, &stats); err != nil {
+		logger.Errorf("cannot decode $collStats for collection %s.%s: %s", database, collection, err)
+		return nil
+	}
+
+	return sizePerShard(stats)
+}
+
+// sizePerShard sums storageStats.size of $collStats results by shard.
+func sizePerShard(stats []bson.M) map[string]float64 {
+	sizes := make(map[string]float64, len(stats))
+	for _, s := range stats {
+		shard, ok := s["shard"].(string)
+		if !ok {
+			continue
+		}
+
+		size, err := asFloat64(walkTo(s, []string{"storageStats", "size"}))
+		if err != nil || size == nil {
+			continue
+		}
+
+		sizes[shard] += *size
+	}
+
+	return sizes
+}
+
+// shardDataRatios returns the fraction of the total size on each shard, so the ratios sum to 1.
+// An empty collection has no distribution and nil is returned.
+func shardDataRatios(sizes map[string]float64) map[string]float64 {
+	var total float64
+	for _, size := range sizes {
+		total += size
+	}
+
+	if total <= 0 {
+		return nil
+	}
+
+	ratios := make(map[string]float64, len(sizes))
+	for shard, size := range sizes {
+		ratios[shard] = size / total
+	}
+
+	return ratios
+}
+
+// configVersionMetrics returns the current and minimum compatible versions of the sharding
+// metadata. MongoDB 5.0 and later don't store them in config.version anymore.
+func configVersionMetrics(cv *proto.ConfigVersion) []prometheus.Metric {
+	versions := map[string]int32{
+		"current":        cv.CurrentVersion,
+		"min_compatible": cv.MinCompatibleVersion,
+	}
+
+	metrics := make([]prometheus.Metric, 0, len(versions))
+	for typ, version := range versions {
+		if version == 0 {
+			continue
+		}
+
+		d := prometheus.NewDesc("mongodb_config_metadata_version", "Version of the sharding metadata in config.version",
+			nil, map[string]string{"type": typ})
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(version)))
+	}
+
+	return metrics
+}
+
 func chunksTotal(ctx context.Context, client *mongo.Client) (prometheus.Metric, error) { //nolint:ireturn
 	n, err := client.Database("config").Collection("chunks").CountDocuments(ctx, bson.M{})
 	if err != nil {
@@ -262,4 +402,78 @@ func chunksTotalPerShard(ctx context.Context, client *mongo.Client) ([]prometheu
 	return metrics, nil
 }
 
+// epochTracker counts the changes of the epochs of the sharded collections across scrapes.
+// The epoch changes when a collection is dropped and sharded again or its shard key is
+// refined, which makes the routing tables cached by the mongos stale. Each exporter has its
+// own tracker, the collections are identified by their cluster and namespace.
+type epochTracker struct {
+	lock    sync.Mutex
+	epochs  map[epochKey]primitive.ObjectID
+	changes map[epochKey]float64
+}
+
+type epochKey struct {
+	cluster   string
+	namespace string
+}
+
+func newEpochTracker() *epochTracker {
+	return &epochTracker{
+		epochs:  make(map[epochKey]primitive.ObjectID),
+		changes: make(map[epochKey]float64),
+	}
+}
+
+// observe records the current epoch of the namespace of the cluster and returns the number
+// of changes since the exporter first saw it.
+func (t *epochTracker) observe(cluster, namespace string, epoch primitive.ObjectID) float64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	key := epochKey{cluster: cluster, namespace: namespace}
+	if last, ok := t.epochs[key]; ok && last != epoch {
+		t.changes[key]++
+	}
+	t.epochs[key] = epoch
+
+	return t.changes[key]
+}
+
+// retain forgets the namespaces of the cluster which are not in seen.
+func (t *epochTracker) retain(cluster string, seen map[string]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for key := range t.epochs {
+		if key.cluster == cluster && !seen[key.namespace] {
+			delete(t.epochs, key)
+			delete(t.changes, key)
+		}
+	}
+}
+
+// collectionVersionMetrics returns the epoch changes of the sharded collection and the
+// timestamp of its current version, from its config.collections document. The timestamp is
+// only stored since MongoDB 5.0. The epoch changes need a tracker.
+func collectionVersionMetrics(row bson.M, cluster, database, collection string, epochs *epochTracker) []prometheus.Metric {
+	labels := map[string]string{"database": database, "collection": collection}
+
+	var metrics []prometheus.Metric
+
+	if epoch, ok := row["lastmodEpoch"].(primitive.ObjectID); ok && epochs != nil {
+		changes := epochs.observe(cluster, database+"."+collection, epoch)
+		d := prometheus.NewDesc("mongodb_sharded_collection_epoch_changes_total",
+			"Number of changes of the epoch of the sharded collection seen by the exporter", nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, changes))
+	}
+
+	if ts, ok := row["timestamp"].(primitive.Timestamp); ok && !ts.IsZero() {
+		d := prometheus.NewDesc("mongodb_sharded_collection_version_timestamp_seconds",
+			"Unix time of the current version of the sharded collection, changed with its epoch", nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(ts.T)))
+	}
+
+	return metrics
+}
+
 var _ prometheus.Collector = (*shardsCollector)(nil)
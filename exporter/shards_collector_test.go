@@ -18,13 +18,19 @@ package exporter
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/percona/mongodb_exporter/internal/proto"
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
 
@@ -35,7 +41,7 @@ func TestShardsCollector(t *testing.T) {
 	defer cancel()
 
 	client := tu.DefaultTestClientMongoS(ctx, t)
-	c := newShardsCollector(ctx, client, logrus.New(), false)
+	c := newShardsCollector(ctx, client, logrus.New(), false, newEpochTracker())
 
 	reg := prometheus.NewPedanticRegistry()
 	if err := reg.Register(c); err != nil {
@@ -67,3 +73,123 @@ func TestShardsCollector(t *testing.T) {
 		assert.Contains(t, res, v)
 	}
 }
+
+func TestSizePerShard(t *testing.T) {
+	t.Parallel()
+
+	stats := []bson.M{
+		{"shard": "rs1", "storageStats": bson.M{"size": int32(1024)}},
+		{"shard": "rs2", "storageStats": bson.M{"size": int64(4096)}},
+		{"shard": "rs2", "storageStats": bson.M{"size": float64(1024)}},
+		{"shard": "rs3"},
+		{"storageStats": bson.M{"size": int32(10)}},
+	}
+
+	assert.Equal(t, map[string]float64{"rs1": 1024, "rs2": 5120}, sizePerShard(stats))
+}
+
+func TestShardDataRatios(t *testing.T) {
+	t.Parallel()
+
+	ratios := shardDataRatios(map[string]float64{"rs1": 6000, "rs2": 3000, "rs3": 1000})
+	assert.InDelta(t, 0.6, ratios["rs1"], 1e-9)
+	assert.InDelta(t, 0.3, ratios["rs2"], 1e-9)
+	assert.InDelta(t, 0.1, ratios["rs3"], 1e-9)
+
+	var sum float64
+	for _, r := range ratios {
+		sum += r
+	}
+	assert.InDelta(t, 1, sum, 1e-9)
+
+	assert.Nil(t, shardDataRatios(map[string]float64{"rs1": 0, "rs2": 0}))
+	assert.Nil(t, shardDataRatios(nil))
+}
+
+func TestConfigVersionMetrics(t *testing.T) {
+	t.Parallel()
+
+	doc, err := bson.Marshal(bson.M{
+		"_id":                  int32(1),
+		"minCompatibleVersion": int32(5),
+		"currentVersion":       int32(6),
+		"clusterId":            primitive.NewObjectID(),
+	})
+	require.NoError(t, err)
+
+	var cv proto.ConfigVersion
+	require.NoError(t, bson.Unmarshal(doc, &cv))
+
+	expected := strings.NewReader(`
+	# HELP mongodb_config_metadata_version Version of the sharding metadata in config.version
+	# TYPE mongodb_config_metadata_version gauge
+	mongodb_config_metadata_version{type="current"} 6
+	mongodb_config_metadata_version{type="min_compatible"} 5` + "\n")
+	err = testutil.CollectAndCompare(metricsCollector(configVersionMetrics(&cv)), expected)
+	assert.NoError(t, err)
+
+	// 5.0+ only has the cluster ID.
+	assert.Empty(t, configVersionMetrics(&proto.ConfigVersion{ID: 1, ClusterID: primitive.NewObjectID()}))
+}
+
+func TestCollectionVersionMetrics(t *testing.T) {
+	t.Parallel()
+
+	epochs := newEpochTracker()
+	epoch := primitive.NewObjectID()
+	row := bson.M{
+		"_id":          "testdb.testcol",
+		"lastmodEpoch": epoch,
+		"lastmod":      primitive.NewDateTimeFromTime(time.Unix(1700000000, 0)),
+		"timestamp":    primitive.Timestamp{T: 1700000000, I: 3},
+		"uuid":         primitive.Binary{Subtype: 4, Data: make([]byte, 16)},
+		"key":          bson.M{"_id": "hashed"},
+	}
+
+	expected := func(changes string) *strings.Reader {
+		return strings.NewReader(`
+	# HELP mongodb_sharded_collection_epoch_changes_total Number of changes of the epoch of the sharded collection seen by the exporter
+	# TYPE mongodb_sharded_collection_epoch_changes_total counter
+	mongodb_sharded_collection_epoch_changes_total{collection="testcol",database="testdb"} ` + changes + `
+	# HELP mongodb_sharded_collection_version_timestamp_seconds Unix time of the current version of the sharded collection, changed with its epoch
+	# TYPE mongodb_sharded_collection_version_timestamp_seconds gauge
+	mongodb_sharded_collection_version_timestamp_seconds{collection="testcol",database="testdb"} 1.7e+09` + "\n")
+	}
+
+	metrics := collectionVersionMetrics(row, "cluster1", "testdb", "testcol", epochs)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected("0")))
+
+	// Same epoch in the next scrape.
+	metrics = collectionVersionMetrics(row, "cluster1", "testdb", "testcol", epochs)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected("0")))
+
+	// The collection was dropped and sharded again.
+	row["lastmodEpoch"] = primitive.NewObjectID()
+	metrics = collectionVersionMetrics(row, "cluster1", "testdb", "testcol", epochs)
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected("1")))
+
+	// Before 5.0 there is no timestamp.
+	metrics = collectionVersionMetrics(bson.M{"_id": "testdb.other", "lastmodEpoch": epoch}, "cluster1", "testdb", "other", epochs)
+	assert.Equal(t, []string{"mongodb_sharded_collection_epoch_changes_total"}, metricNames(metrics))
+}
+
+func TestEpochTracker(t *testing.T) {
+	t.Parallel()
+
+	epochs := newEpochTracker()
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+
+	epochs.observe("cluster1", "testdb.testcol", first)
+	assert.Equal(t, float64(1), epochs.observe("cluster1", "testdb.testcol", second))
+
+	// The same namespace in another cluster is another collection.
+	assert.Equal(t, float64(0), epochs.observe("cluster2", "testdb.testcol", first))
+	assert.Equal(t, float64(0), epochs.observe("cluster2", "testdb.testcol", first))
+
+	// The collections not seen in a scrape of their cluster are forgotten.
+	epochs.observe("cluster1", "testdb.other", first)
+	epochs.retain("cluster1", map[string]bool{"testdb.other": true})
+	assert.Equal(t, float64(0), epochs.observe("cluster1", "testdb.testcol", first))
+	assert.Equal(t, float64(0), epochs.observe("cluster1", "testdb.other", first))
+	assert.Equal(t, float64(1), epochs.observe("cluster2", "testdb.testcol", second))
+}
@@ -0,0 +1,166 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TargetTLSFiles is the set of TLS files used to connect to the targets matching Target.
+type TargetTLSFiles struct {
+	// Target is a glob pattern matched against the host name, with or without the port, of
+	// each host of the target URI, e.g. "*.prod.example.com".
+	Target             string `yaml:"target"`
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// TargetTLSConfigs resolves the TLS config to connect to a target, so one exporter can scrape
+// clusters with a different PKI. The files are read and validated once, when loading them.
+type TargetTLSConfigs struct {
+	targets []targetTLSConfig
+}
+
+type targetTLSConfig struct {
+	pattern string
+	config  *tls.Config
+}
+
+// LoadTargetTLSConfigs reads the per target TLS files from a YAML file like:
+//
+//	targets:
+//	  - target: "*.prod.example.com"
+//	    ca_file: /etc/mongodb/prod-ca.pem
+//	    cert_file: /etc/mongodb/prod-client.pem
+//	    key_file: /etc/mongodb/prod-client.key
+func LoadTargetTLSConfigs(file string) (*TargetTLSConfigs, error) {
+	b, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the targets TLS config: %w", err)
+	}
+
+	var config struct {
+		Targets []TargetTLSFiles `yaml:"targets"`
+	}
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("cannot parse the targets TLS config %s: %w", file, err)
+	}
+
+	return NewTargetTLSConfigs(config.Targets)
+}
+
+// NewTargetTLSConfigs builds the TLS configs of the targets. The first matching target wins.
+func NewTargetTLSConfigs(targets []TargetTLSFiles) (*TargetTLSConfigs, error) {
+	configs := &TargetTLSConfigs{}
+
+	for _, target := range targets {
+		if target.Target == "" {
+			return nil, errors.New("target TLS config without target pattern")
+		}
+		if _, err := path.Match(target.Target, ""); err != nil {
+			return nil, fmt.Errorf("invalid target pattern %q: %w", target.Target, err)
+		}
+
+		config, err := target.tlsConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS config for target %q: %w", target.Target, err)
+		}
+
+		configs.targets = append(configs.targets, targetTLSConfig{pattern: target.Target, config: config})
+	}
+
+	return configs, nil
+}
+
+func (t TargetTLSFiles) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if t.CAFile != "" {
+		ca, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CAFile)
+		}
+	}
+
+	switch {
+	case t.CertFile != "":
+		keyFile := t.KeyFile
+		// Like tlsCertificateKeyFile in the URI, the key can be in the certificate file.
+		if keyFile == "" {
+			keyFile = t.CertFile
+		}
+
+		cert, err := tls.LoadX509KeyPair(t.CertFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	case t.KeyFile != "":
+		return nil, errors.New("key file without certificate file")
+	}
+
+	return config, nil
+}
+
+// resolve returns the TLS config of the first target matching any of the hosts, nil if none.
+func (c *TargetTLSConfigs) resolve(hosts []string) *tls.Config {
+	if c == nil {
+		return nil
+	}
+
+	for _, target := range c.targets {
+		for _, host := range hosts {
+			if matchTargetHost(target.pattern, host) {
+				return target.config.Clone()
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchTargetHost(pattern, host string) bool {
+	if ok, _ := path.Match(pattern, host); ok {
+		return true
+	}
+
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return false
+	}
+
+	ok, _ := path.Match(pattern, hostname)
+
+	return ok
+}
@@ -0,0 +1,133 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert writes a self-signed certificate and its key, returning their file names.
+func writeTestCert(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestTargetTLSConfigs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	prodCert, prodKey := writeTestCert(t, dir, "prod")
+	stagingCert, _ := writeTestCert(t, dir, "staging")
+
+	configFile := filepath.Join(dir, "targets-tls.yml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+targets:
+  - target: "*.prod.example.com"
+    ca_file: `+prodCert+`
+    cert_file: `+prodCert+`
+    key_file: `+prodKey+`
+  - target: "staging-*:27018"
+    ca_file: `+stagingCert+`
+    insecure_skip_verify: true
+`), 0o600))
+
+	configs, err := LoadTargetTLSConfigs(configFile)
+	require.NoError(t, err)
+
+	prod := configs.resolve([]string{"db1.prod.example.com:27017"})
+	require.NotNil(t, prod)
+	assert.Len(t, prod.Certificates, 1)
+	assert.False(t, prod.InsecureSkipVerify)
+
+	staging := configs.resolve([]string{"localhost:27017", "staging-2:27018"})
+	require.NotNil(t, staging)
+	assert.Empty(t, staging.Certificates)
+	assert.True(t, staging.InsecureSkipVerify)
+	assert.False(t, prod.RootCAs.Equal(staging.RootCAs))
+
+	assert.Nil(t, configs.resolve([]string{"staging-2:27017"}))
+	assert.Nil(t, (*TargetTLSConfigs)(nil).resolve([]string{"db1.prod.example.com:27017"}))
+
+	// The resolved configs are copies, changing them doesn't affect the other targets.
+	prod.ServerName = "db1.prod.example.com"
+	assert.Empty(t, configs.resolve([]string{"db2.prod.example.com:27017"}).ServerName)
+
+	opts, err := clientOptions(&Opts{URI: "mongodb://db1.prod.example.com:27017", TargetTLS: configs}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, opts.TLSConfig)
+	assert.Len(t, opts.TLSConfig.Certificates, 1)
+
+	opts, err = clientOptions(&Opts{URI: "mongodb://127.0.0.1:27017", TargetTLS: configs}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, opts.TLSConfig)
+}
+
+func TestTargetTLSConfigsInvalid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cert, _ := writeTestCert(t, dir, "prod")
+
+	for name, targets := range map[string][]TargetTLSFiles{
+		"no pattern":       {{CAFile: cert}},
+		"bad pattern":      {{Target: "[db", CAFile: cert}},
+		"missing CA":       {{Target: "*", CAFile: filepath.Join(dir, "missing.pem")}},
+		"key without cert": {{Target: "*", KeyFile: cert}},
+		"cert without key": {{Target: "*", CertFile: cert}},
+	} {
+		_, err := NewTargetTLSConfigs(targets)
+		assert.Error(t, err, name)
+	}
+
+	_, err := LoadTargetTLSConfigs(filepath.Join(dir, "missing.yml"))
+	assert.Error(t, err)
+}
@@ -32,18 +32,20 @@ type topCollector struct {
 
 	compatibleMode bool
 	topologyInfo   labelsGetter
+	commandRetries int
 }
 
 var ErrInvalidOrMissingTotalsEntry = fmt.Errorf("invalid or misssing totals entry in top results")
 
 func newTopCollector(ctx context.Context, client *mongo.Client, logger *logrus.Logger, compatible bool,
-	topology labelsGetter,
+	topology labelsGetter, commandRetries int,
 ) *topCollector {
 	return &topCollector{
 		ctx:            ctx,
-		base:           newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "top"})),
+		base:           newBaseCollector(client, logger.WithFields(logrus.Fields{"collector": "topmetrics"})),
 		compatibleMode: false, // there are no compatible metrics for this collector.
 		topologyInfo:   topology,
+		commandRetries: commandRetries,
 	}
 }
 
@@ -62,7 +64,7 @@ func (d *topCollector) collect(ch chan<- prometheus.Metric) {
 	client := d.base.client
 
 	cmd := bson.D{{Key: "top", Value: "1"}}
-	res := client.Database("admin").RunCommand(d.ctx, cmd)
+	res := runCommand(d.ctx, client.Database("admin"), cmd, d.commandRetries)
 
 	var m primitive.M
 	if err := res.Decode(&m); err != nil {
@@ -153,5 +155,52 @@ func (d *topCollector) collect(ch chan<- prometheus.Metric) {
 		for _, metric := range makeMetrics("top", mm, labels, d.compatibleMode) {
 			ch <- metric
 		}
+
+		for _, metric := range topNamespaceMetrics(mm, labels) {
+			ch <- metric
+		}
+	}
+}
+
+// topNamespaceMetrics returns the lock times in seconds and the operation counts of a
+// namespace in the top output. top reports the times in microseconds.
+func topNamespaceMetrics(stats primitive.M, labels map[string]string) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0)
+
+	times := []struct {
+		name string
+		help string
+		key  string
+	}{
+		{"mongodb_top_total_time_seconds", "Total time spent on the collection, in seconds", "total"},
+		{"mongodb_top_read_lock_time_seconds", "Time spent holding read locks on the collection, in seconds", "readLock"},
+		{"mongodb_top_write_lock_time_seconds", "Time spent holding write locks on the collection, in seconds", "writeLock"},
+	}
+	for _, t := range times {
+		v, err := asFloat64(walkTo(stats, []string{t.key, "time"}))
+		if err != nil || v == nil {
+			continue
+		}
+
+		d := prometheus.NewDesc(t.name, t.help, nil, labels)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *v/1e6))
 	}
+
+	for _, op := range []string{"queries", "insert", "update", "remove"} {
+		v, err := asFloat64(walkTo(stats, []string{op, "count"}))
+		if err != nil || v == nil {
+			continue
+		}
+
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["op"] = op
+
+		d := prometheus.NewDesc("mongodb_top_operations_total", "Number of operations on the collection", nil, l)
+		metrics = append(metrics, prometheus.MustNewConstMetric(d, prometheus.CounterValue, *v))
+	}
+
+	return metrics
 }
@@ -17,12 +17,14 @@ package exporter
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -35,7 +37,7 @@ func TestTopCollector(t *testing.T) {
 
 	ti := labelsGetterMock{}
 
-	c := newTopCollector(ctx, client, logrus.New(), false, ti)
+	c := newTopCollector(ctx, client, logrus.New(), false, ti, 0)
 
 	// Filter metrics for 2 reasons:
 	// 1. The result is huge
@@ -72,3 +74,39 @@ func TestTopCollector(t *testing.T) {
 	*/
 	assert.True(t, count > 0)
 }
+
+func TestTopNamespaceMetrics(t *testing.T) {
+	t.Parallel()
+
+	stats := primitive.M{
+		"total":     primitive.M{"time": int64(2500000), "count": int64(30)},
+		"readLock":  primitive.M{"time": int64(1500000), "count": int64(20)},
+		"writeLock": primitive.M{"time": int64(1000000), "count": int64(10)},
+		"queries":   primitive.M{"time": int64(1200000), "count": int64(15)},
+		"insert":    primitive.M{"time": int64(400000), "count": int64(4)},
+		"update":    primitive.M{"time": int64(500000), "count": int64(5)},
+		"remove":    primitive.M{"time": int64(100000), "count": int64(1)},
+		"getmore":   primitive.M{"time": int64(0), "count": int64(0)},
+	}
+	labels := map[string]string{"database": "testdb", "collection": "testcol"}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_top_operations_total Number of operations on the collection
+	# TYPE mongodb_top_operations_total counter
+	mongodb_top_operations_total{collection="testcol",database="testdb",op="insert"} 4
+	mongodb_top_operations_total{collection="testcol",database="testdb",op="queries"} 15
+	mongodb_top_operations_total{collection="testcol",database="testdb",op="remove"} 1
+	mongodb_top_operations_total{collection="testcol",database="testdb",op="update"} 5
+	# HELP mongodb_top_read_lock_time_seconds Time spent holding read locks on the collection, in seconds
+	# TYPE mongodb_top_read_lock_time_seconds counter
+	mongodb_top_read_lock_time_seconds{collection="testcol",database="testdb"} 1.5
+	# HELP mongodb_top_total_time_seconds Total time spent on the collection, in seconds
+	# TYPE mongodb_top_total_time_seconds counter
+	mongodb_top_total_time_seconds{collection="testcol",database="testdb"} 2.5
+	# HELP mongodb_top_write_lock_time_seconds Time spent holding write locks on the collection, in seconds
+	# TYPE mongodb_top_write_lock_time_seconds counter
+	mongodb_top_write_lock_time_seconds{collection="testcol",database="testdb"} 1` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(topNamespaceMetrics(stats, labels)), expected)
+	assert.NoError(t, err)
+}
@@ -60,17 +60,21 @@ type topologyInfo struct {
 	logger *logrus.Entry
 	rw     sync.RWMutex
 	labels map[string]string
+
+	// replSetGetStatus is shared with the collectors of the scrape.
+	cache *scrapeCache
 }
 
 // ErrCannotGetTopologyLabels Cannot read topology labels.
 var ErrCannotGetTopologyLabels = fmt.Errorf("cannot get topology labels")
 
-func newTopologyInfo(ctx context.Context, client *mongo.Client, logger *logrus.Logger) *topologyInfo {
+func newTopologyInfo(ctx context.Context, client *mongo.Client, logger *logrus.Logger, cache *scrapeCache) *topologyInfo {
 	ti := &topologyInfo{
 		client: client,
 		logger: logger.WithFields(logrus.Fields{"component": "topology_info"}),
 		labels: make(map[string]string),
 		rw:     sync.RWMutex{},
+		cache:  cache,
 	}
 
 	err := ti.loadLabels(ctx)
@@ -103,6 +107,9 @@ func (t *topologyInfo) loadLabels(ctx context.Context) error {
 
 	t.labels = make(map[string]string)
 
+	ctx, cancel := util.WithTimeout(ctx, util.DefaultCommandTimeout)
+	defer cancel()
+
 	role, err := getClusterRole(ctx, t.client, t.logger)
 	if err != nil {
 		return errors.Wrap(err, "cannot get node type for topology info")
@@ -129,9 +136,8 @@ func (t *topologyInfo) loadLabels(ctx context.Context) error {
 	t.labels[labelClusterID] = cid
 
 	// Standalone instances or mongos instances won't have a replicaset state
-	_, state, err := util.MyState(ctx, t.client)
-	if err == nil {
-		t.labels[labelReplicasetState] = fmt.Sprintf("%d", state)
+	if status, err := t.cache.replSetStatus(ctx); err == nil {
+		t.labels[labelReplicasetState] = fmt.Sprintf("%d", int(status.MyState))
 	}
 
 	return nil
@@ -17,13 +17,19 @@ package exporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -89,7 +95,7 @@ func TestTopologyLabels(t *testing.T) {
 			require.NoError(t, err)
 
 			client := tu.TestClient(ctx, port, t)
-			ti := newTopologyInfo(ctx, client, logrus.New())
+			ti := newTopologyInfo(ctx, client, logrus.New(), newScrapeCache(client, 0))
 			bl := ti.baseLabels()
 			assert.Equal(t, tc.want[labelReplicasetName], bl[labelReplicasetName], tc.containerName)
 			assert.Equal(t, tc.want[labelReplicasetState], bl[labelReplicasetState], tc.containerName)
@@ -142,3 +148,75 @@ func TestGetClusterRole(t *testing.T) {
 		assert.Equal(t, tc.want, nodeType, fmt.Sprintf("container name: %s, port: %s", tc.containerName, port))
 	}
 }
+
+func TestConstantLabels(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkConstantLabels(map[string]string{"environment": "prod", "team": "payments"}))
+	assert.Error(t, checkConstantLabels(map[string]string{"rs_nm": "rs"}))
+	assert.Error(t, checkConstantLabels(map[string]string{"instance": "host1"}))
+	assert.Error(t, checkConstantLabels(map[string]string{"my-env": "prod"}))
+	assert.Error(t, checkConstantLabels(map[string]string{"__name__": "x"}))
+
+	// mongodb_up and mongodb_instance_node_type have these labels.
+	assert.Error(t, checkConstantLabels(map[string]string{"cluster_role": "x"}))
+	assert.Error(t, checkConstantLabels(map[string]string{"type": "x"}))
+
+	// The collectors metrics have these labels.
+	for _, name := range []string{"collector", "database", "collection", "shard"} {
+		assert.Error(t, checkConstantLabels(map[string]string{name: "x"}), name)
+	}
+
+	_, err := New(&Opts{URI: "mongodb://127.0.0.1:12345", ConstantLabels: map[string]string{"cl_id": "x"}})
+	assert.Error(t, err)
+
+	_, err = New(&Opts{URI: "mongodb://127.0.0.1:12345", ConstantLabels: map[string]string{"collector": "x"}})
+	assert.Error(t, err)
+
+	// The labels are added to every metric, including the ones without the topology labels.
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	cache := &scrapeCache{
+		run: func(_ context.Context, name string) (bson.M, error) {
+			if name == "getDiagnosticData" {
+				return bson.M{"data": bson.M{"serverStatus": bson.M{"uptime": int64(10)}}}, nil
+			}
+
+			return nil, errors.New("not available")
+		},
+		results: make(map[string]*cachedCommand),
+	}
+
+	opts := &Opts{
+		Logger:               logger,
+		CompatibleMode:       true,
+		EnableDiagnosticData: true,
+		ConstantLabels:       map[string]string{"environment": "prod"},
+	}
+	e := &Exporter{logger: logger, opts: opts, lock: &sync.Mutex{}}
+
+	mfs, err := e.makeRegistry(ctx, client, labelsGetterMock{}, cache, *opts).Gather()
+	require.NoError(t, err)
+	require.NotEmpty(t, mfs)
+
+	names := make([]string, 0, len(mfs))
+	for _, mf := range mfs {
+		names = append(names, mf.GetName())
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			assert.Equal(t, "prod", labels["environment"], mf.GetName())
+		}
+	}
+	assert.Contains(t, names, "mongodb_up")
+	assert.Contains(t, names, "mongodb_mongod_replset_my_state")
+	assert.Contains(t, names, "collector_scrape_time_ms")
+}
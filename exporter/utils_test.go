@@ -16,11 +16,16 @@
 package exporter
 
 import (
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+var fqNameRe = regexp.MustCompile(`fqName: "([^"]+)"`) //nolint:gochecknoglobals
+
 func filterMetrics(metrics []*helpers.Metric, filters []string) []*helpers.Metric {
 	res := make([]*helpers.Metric, 0, len(metrics))
 
@@ -74,3 +79,30 @@ func filterMetricsWithLabels(metrics []*helpers.Metric, filters []string, labels
 	}
 	return res
 }
+
+// metricNames returns the sorted fully qualified names of the metrics.
+func metricNames(metrics []prometheus.Metric) []string {
+	names := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		if match := fqNameRe.FindStringSubmatch(m.Desc().String()); match != nil {
+			names = append(names, match[1])
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// metricsCollector is a prometheus.Collector returning a fixed list of metrics,
+// useful to compare the output of metric building functions with testutil.
+type metricsCollector []prometheus.Metric
+
+func (c metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
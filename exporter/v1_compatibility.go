@@ -110,6 +110,9 @@ func newToOldMetric(rm *rawMetric, c conversion) *rawMetric {
 		ln:     make([]string, 0, len(rm.ln)),
 		lv:     make([]string, 0, len(rm.lv)),
 	}
+	if c.valueType != 0 {
+		oldMetric.vt = c.valueType
+	}
 
 	for _, val := range rm.lv {
 		if newLabelVal, ok := c.labelValueConversions[val]; ok {
@@ -167,6 +170,9 @@ func createOldMetricFromNew(rm *rawMetric, c conversion) *rawMetric {
 		ln:     []string{c.suffixLabel},
 		lv:     []string{suffix},
 	}
+	if c.valueType != 0 {
+		oldMetric.vt = c.valueType
+	}
 
 	return oldMetric
 }
@@ -189,6 +195,80 @@ func cacheEvictedTotalMetric(m bson.M) (prometheus.Metric, error) {
 	return metric, nil
 }
 
+// wiredTigerCacheMetrics returns the WiredTiger cache metrics that have no v1 conversion from
+// the serverStatus field names. Nothing is returned if the storage engine is not WiredTiger.
+func wiredTigerCacheMetrics(m bson.M) []prometheus.Metric {
+	cache, ok := walkTo(m, []string{"serverStatus", "wiredTiger", "cache"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	return documentMetrics(cache, []documentMetric{
+		{
+			name:      "mongodb_mongod_wiredtiger_cache_dirty_bytes",
+			help:      "Size in bytes of the dirty data in the WiredTiger cache",
+			path:      []string{"tracked dirty bytes in the cache"},
+			valueType: prometheus.GaugeValue,
+		},
+		{
+			name:      "mongodb_mongod_wiredtiger_cache_evicted_pages_total",
+			help:      "Pages evicted from the WiredTiger cache",
+			path:      []string{"modified pages evicted"},
+			valueType: prometheus.CounterValue,
+			labels:    map[string]string{"type": "modified"},
+		},
+		{
+			name:      "mongodb_mongod_wiredtiger_cache_evicted_pages_total",
+			help:      "Pages evicted from the WiredTiger cache",
+			path:      []string{"unmodified pages evicted"},
+			valueType: prometheus.CounterValue,
+			labels:    map[string]string{"type": "unmodified"},
+		},
+	}, nil)
+}
+
+// transactionsMetrics returns the metrics of serverStatus.transactions. Nothing is returned if
+// the section is absent, like on standalone instances and old versions.
+func transactionsMetrics(m bson.M) []prometheus.Metric {
+	txn, ok := walkTo(m, []string{"serverStatus", "transactions"}).(bson.M)
+	if !ok {
+		return nil
+	}
+
+	return documentMetrics(txn, []documentMetric{
+		{
+			name:      "mongodb_mongod_transactions_active",
+			help:      "Number of transactions currently running an operation",
+			path:      []string{"currentActive"},
+			valueType: prometheus.GaugeValue,
+		},
+		{
+			name:      "mongodb_mongod_transactions_total_started",
+			help:      "Total number of transactions started",
+			path:      []string{"totalStarted"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_mongod_transactions_total_committed",
+			help:      "Total number of transactions committed",
+			path:      []string{"totalCommitted"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_mongod_transactions_total_aborted",
+			help:      "Total number of transactions aborted",
+			path:      []string{"totalAborted"},
+			valueType: prometheus.CounterValue,
+		},
+		{
+			name:      "mongodb_mongod_transactions_retried_commands_total",
+			help:      "Total number of retried writes received after the write was already committed",
+			path:      []string{"retriedCommandsCount"},
+			valueType: prometheus.CounterValue,
+		},
+	}, nil)
+}
+
 func sumMetrics(m bson.M, paths [][]string) (float64, error) {
 	var total float64
 
@@ -209,6 +289,33 @@ func sumMetrics(m bson.M, paths [][]string) (float64, error) {
 	return total, nil
 }
 
+// CompatibleMetricMappings returns the legacy names of the metrics added in compatible mode, by
+// the name of the metric they are made from, as the conversions applied to the metrics. The
+// metrics sharing a prefix map to the legacy name with the label made from their suffix, e.g.
+// mongodb_ss_globalLock_activeClients_readers to mongodb_mongod_global_lock_client{type="reader"}.
+// The legacy names of a metric converted several times are separated by commas.
+func CompatibleMetricMappings() map[string]string {
+	mappings := make(map[string]string, len(conversions))
+	add := func(name, legacy string) {
+		if prev, ok := mappings[name]; ok {
+			legacy = prev + "," + legacy
+		}
+		mappings[name] = legacy
+	}
+
+	for _, c := range conversions {
+		if c.newName != "" {
+			add(c.newName, c.oldName)
+			continue
+		}
+		for suffix, value := range c.suffixMapping {
+			add(c.prefix+"_"+suffix, fmt.Sprintf("%s{%s=%q}", c.oldName, c.suffixLabel, value))
+		}
+	}
+
+	return mappings
+}
+
 // Converts new metric to the old metric style and append it to the response slice.
 func appendCompatibleMetric(res []prometheus.Metric, rm *rawMetric) []prometheus.Metric {
 	compatibleMetrics := metricRenameAndLabel(rm, conversions)
@@ -424,6 +531,7 @@ var conversions = []conversion{
 		oldName:          "mongodb_mongod_op_counters_repl_total",
 		newName:          "mongodb_ss_opcountersRepl",
 		labelConversions: map[string]string{"legacy_op_type": "type"},
+		valueType:        prometheus.CounterValue,
 	},
 	{
 		oldName:          "mongodb_op_counters_total",
@@ -791,7 +899,7 @@ var specialMetricDefinitions = []specialMetric{
 	},
 }
 
-func specialMetrics(ctx context.Context, client *mongo.Client, m bson.M, nodeType mongoDBNodeType, l *logrus.Entry) []prometheus.Metric {
+func specialMetrics(ctx context.Context, client *mongo.Client, cache *scrapeCache, m bson.M, nodeType mongoDBNodeType, l *logrus.Entry) []prometheus.Metric {
 	metrics := make([]prometheus.Metric, 0)
 
 	for _, def := range specialMetricDefinitions {
@@ -811,6 +919,9 @@ func specialMetrics(ctx context.Context, client *mongo.Client, m bson.M, nodeTyp
 		metrics = append(metrics, metric)
 	}
 
+	metrics = append(metrics, wiredTigerCacheMetrics(m)...)
+	metrics = append(metrics, transactionsMetrics(m)...)
+
 	if nodeType == typeMongod || nodeType == typeArbiter {
 		if engine, err := storageEngine(m); err != nil {
 			l.Errorf("cannot retrieve engine type: %s", err)
@@ -820,7 +931,7 @@ func specialMetrics(ctx context.Context, client *mongo.Client, m bson.M, nodeTyp
 	}
 
 	if nodeType != typeArbiter {
-		metrics = append(metrics, myState(ctx, client))
+		metrics = append(metrics, myState(ctx, cache))
 		if replSetGetStatus, ok := m["replSetGetStatus"].(bson.M); ok {
 			if rm := replSetMetrics(replSetGetStatus, l); rm != nil {
 				metrics = append(metrics, rm...)
@@ -899,10 +1010,14 @@ func serverVersion(bi buildInfo) prometheus.Metric { //nolint:ireturn
 	return metric
 }
 
-func myState(ctx context.Context, client *mongo.Client) prometheus.Metric {
-	id, state, err := util.MyState(ctx, client)
-	if err != nil {
-		state = UnknownState
+func myState(ctx context.Context, cache *scrapeCache) prometheus.Metric {
+	ctx, cancel := util.WithTimeout(ctx, util.DefaultCommandTimeout)
+	defer cancel()
+
+	var id string
+	state := UnknownState
+	if status, err := cache.replSetStatus(ctx); err == nil {
+		id, state = status.Set, int(status.MyState)
 	}
 
 	name := "mongodb_mongod_replset_my_state"
@@ -947,43 +1062,43 @@ func arbiterMetrics(ctx context.Context, client *mongo.Client, l *logrus.Entry)
 }
 
 func oplogStatus(ctx context.Context, client *mongo.Client) ([]prometheus.Metric, error) {
-	oplogRS := client.Database("local").Collection("oplog.rs")
-	type oplogRSResult struct {
-		Timestamp primitive.Timestamp `bson:"ts"`
-	}
-	var head, tail oplogRSResult
-	headRes := oplogRS.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{
-		"$natural": -1,
-	}))
-	if headRes.Err() != nil {
-		return nil, headRes.Err()
-	}
-
-	if err := headRes.Decode(&head); err != nil {
+	head, err := oplogTimestamp(ctx, client, -1)
+	if err != nil {
 		return nil, err
 	}
-	tailRes := oplogRS.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{
-		"$natural": 1,
-	}))
-	if tailRes.Err() != nil {
-		return nil, tailRes.Err()
-	}
 
-	if err := tailRes.Decode(&tail); err != nil {
+	tail, err := oplogTimestamp(ctx, client, 1)
+	if err != nil {
 		return nil, err
 	}
 
 	headDesc := prometheus.NewDesc("mongodb_mongod_replset_oplog_head_timestamp",
 		"The timestamp of the newest change in the oplog", nil, nil)
-	headMetric := prometheus.MustNewConstMetric(headDesc, prometheus.GaugeValue, float64(head.Timestamp.T))
+	headMetric := prometheus.MustNewConstMetric(headDesc, prometheus.GaugeValue, float64(head.T))
 
 	tailDesc := prometheus.NewDesc("mongodb_mongod_replset_oplog_tail_timestamp",
 		"The timestamp of the oldest change in the oplog", nil, nil)
-	tailMetric := prometheus.MustNewConstMetric(tailDesc, prometheus.GaugeValue, float64(tail.Timestamp.T))
+	tailMetric := prometheus.MustNewConstMetric(tailDesc, prometheus.GaugeValue, float64(tail.T))
 
 	return []prometheus.Metric{headMetric, tailMetric}, nil
 }
 
+// oplogTimestamp returns the timestamp of the newest (order -1) or the oldest (order 1)
+// entry in the oplog.
+func oplogTimestamp(ctx context.Context, client *mongo.Client, order int) (primitive.Timestamp, error) {
+	var entry struct {
+		Timestamp primitive.Timestamp `bson:"ts"`
+	}
+
+	res := client.Database("local").Collection("oplog.rs").FindOne(ctx, bson.M{},
+		options.FindOne().SetSort(bson.M{"$natural": order}))
+	if err := res.Decode(&entry); err != nil {
+		return primitive.Timestamp{}, err
+	}
+
+	return entry.Timestamp, nil
+}
+
 func replSetMetrics(d bson.M, l *logrus.Entry) []prometheus.Metric {
 	var repl proto.ReplicaSetStatus
 	b, err := bson.Marshal(d)
@@ -1069,7 +1184,7 @@ func replSetMetrics(d bson.M, l *logrus.Entry) []prometheus.Metric {
 	return metrics
 }
 
-func mongosMetrics(ctx context.Context, client *mongo.Client, l *logrus.Entry) []prometheus.Metric {
+func mongosMetrics(ctx context.Context, client *mongo.Client, changelogWindow time.Duration, l *logrus.Entry) []prometheus.Metric {
 	metrics := make([]prometheus.Metric, 0)
 
 	if metric, err := databasesTotalPartitioned(ctx, client); err != nil {
@@ -1102,12 +1217,7 @@ func mongosMetrics(ctx context.Context, client *mongo.Client, l *logrus.Entry) [
 		metrics = append(metrics, metric)
 	}
 
-	ms, err := changelog10m(ctx, client, l)
-	if err != nil {
-		l.Errorf("cannot create metric for changelog: %s", err)
-	} else {
-		metrics = append(metrics, ms...)
-	}
+	metrics = append(metrics, shardingChangelogMetrics(ctx, client, changelogWindow, l)...)
 
 	metrics = append(metrics, dbstatsMetrics(ctx, client, l)...)
 
@@ -1257,11 +1367,36 @@ type ShardingChangelogStats struct {
 	Items *[]ShardingChangelogSummary
 }
 
-func changelog10m(ctx context.Context, client *mongo.Client, l *logrus.Entry) ([]prometheus.Metric, error) {
+// defaultShardingChangelogWindow is the time window of the sharding changelog events of the
+// v1 exporter, reported in mongodb_mongos_sharding_changelog_10min_total.
+const defaultShardingChangelogWindow = 10 * time.Minute
+
+// shardingChangelogMetrics returns the sharding changelog events and
+// mongodb_sharding_changelog_scrape_error, set to 1 when they cannot be read.
+func shardingChangelogMetrics(ctx context.Context, client *mongo.Client, window time.Duration, l *logrus.Entry) []prometheus.Metric {
+	scrapeError := 0.0
+
+	metrics, err := shardingChangelog(ctx, client, window, l)
+	if err != nil {
+		l.Errorf("cannot create metric for changelog: %s", err)
+		scrapeError = 1
+	}
+
+	d := prometheus.NewDesc("mongodb_sharding_changelog_scrape_error",
+		"Whether the sharding changelog events could not be read in the last scrape", nil, nil)
+
+	return append(metrics, prometheus.MustNewConstMetric(d, prometheus.GaugeValue, scrapeError))
+}
+
+func shardingChangelog(ctx context.Context, client *mongo.Client, window time.Duration, l *logrus.Entry) ([]prometheus.Metric, error) {
 	var metrics []prometheus.Metric
 
+	if window <= 0 {
+		window = defaultShardingChangelogWindow
+	}
+
 	coll := client.Database("config").Collection("changelog")
-	match := bson.M{"time": bson.M{"$gt": time.Now().Add(-10 * time.Minute)}}
+	match := bson.M{"time": bson.M{"$gt": time.Now().Add(-window)}}
 	group := bson.M{"_id": bson.M{"event": "$what", "note": "$details.note"}, "count": bson.M{"$sum": 1}}
 
 	c, err := coll.Aggregate(ctx, []bson.M{{"$match": match}, {"$group": group}})
@@ -1278,16 +1413,7 @@ func changelog10m(ctx context.Context, client *mongo.Client, l *logrus.Entry) ([
 			continue
 		}
 
-		name := "mongodb_mongos_sharding_changelog_10min_total"
-		help := "mongodb_mongos_sharding_changelog_10min_total"
-
-		labelValue := s.ID.Event
-		if s.ID.Note != "" {
-			labelValue += "." + s.ID.Note
-		}
-
-		d := prometheus.NewDesc(name, help, nil, map[string]string{"event": labelValue})
-		metric, err := prometheus.NewConstMetric(d, prometheus.GaugeValue, s.Count)
+		metric, err := shardingChangelogMetric(s, window)
 		if err != nil {
 			continue
 		}
@@ -1302,6 +1428,34 @@ func changelog10m(ctx context.Context, client *mongo.Client, l *logrus.Entry) ([
 	return metrics, nil
 }
 
+// shardingChangelogMetric returns the number of sharding changelog events of a type in the time
+// window. The default window keeps the v1 metric name, other windows are reported in
+// mongodb_mongos_sharding_changelog_total with a window label.
+func shardingChangelogMetric(s *ShardingChangelogSummary, window time.Duration) (prometheus.Metric, error) {
+	if s.ID == nil {
+		return nil, errors.Wrap(errUnexpectedDataType, "sharding changelog summary without _id")
+	}
+
+	labelValue := s.ID.Event
+	if s.ID.Note != "" {
+		labelValue += "." + s.ID.Note
+	}
+	labels := map[string]string{"event": labelValue}
+
+	name := "mongodb_mongos_sharding_changelog_10min_total"
+	help := "mongodb_mongos_sharding_changelog_10min_total"
+
+	if window != defaultShardingChangelogWindow {
+		name = "mongodb_mongos_sharding_changelog_total"
+		help = "Sharding changelog events in the time window"
+		labels["window"] = window.String()
+	}
+
+	d := prometheus.NewDesc(name, help, nil, labels)
+
+	return prometheus.NewConstMetric(d, prometheus.GaugeValue, s.Count)
+}
+
 // DatabaseStatList contains stats from all databases.
 type databaseStatList struct {
 	Members []databaseStatus
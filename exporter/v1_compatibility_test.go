@@ -18,6 +18,8 @@ package exporter
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -26,11 +28,14 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/percona/mongodb_exporter/internal/tu"
 )
@@ -243,6 +248,65 @@ func TestMongosMetrics(t *testing.T) {
 	})
 }
 
+func TestShardingChangelogMetric(t *testing.T) {
+	t.Parallel()
+
+	summary := &ShardingChangelogSummary{
+		ID:    &ShardingChangelogSummaryID{Event: "moveChunk.from", Note: "success"},
+		Count: 4,
+	}
+
+	// The default window keeps the v1 metric.
+	metric, err := shardingChangelogMetric(summary, defaultShardingChangelogWindow)
+	require.NoError(t, err)
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongos_sharding_changelog_10min_total mongodb_mongos_sharding_changelog_10min_total
+	# TYPE mongodb_mongos_sharding_changelog_10min_total gauge
+	mongodb_mongos_sharding_changelog_10min_total{event="moveChunk.from.success"} 4` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected))
+
+	metric, err = shardingChangelogMetric(&ShardingChangelogSummary{
+		ID:    &ShardingChangelogSummaryID{Event: "split"},
+		Count: 2,
+	}, time.Hour)
+	require.NoError(t, err)
+
+	expected = strings.NewReader(`
+	# HELP mongodb_mongos_sharding_changelog_total Sharding changelog events in the time window
+	# TYPE mongodb_mongos_sharding_changelog_total gauge
+	mongodb_mongos_sharding_changelog_total{event="split",window="1h0m0s"} 2` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector([]prometheus.Metric{metric}), expected))
+
+	_, err = shardingChangelogMetric(&ShardingChangelogSummary{Count: 1}, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestShardingChangelogMetricsError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// The aggregation fails fast on server selection.
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=10"))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	var metrics []prometheus.Metric
+	assert.NotPanics(t, func() {
+		metrics = shardingChangelogMetrics(ctx, client, time.Hour, logrus.NewEntry(logger))
+	})
+
+	expected := strings.NewReader(`
+	# HELP mongodb_sharding_changelog_scrape_error Whether the sharding changelog events could not be read in the last scrape
+	# TYPE mongodb_sharding_changelog_scrape_error gauge
+	mongodb_sharding_changelog_scrape_error 1` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(metrics), expected))
+}
+
 // myState should always return a metric. If there is no connection, the value
 // should be the MongoDB unknown state = 6
 func TestMyState(t *testing.T) {
@@ -281,7 +345,7 @@ func TestMyState(t *testing.T) {
 			client := tu.TestClient(ctx, port, t)
 			var m dto.Metric
 
-			metric := myState(ctx, client)
+			metric := myState(ctx, newScrapeCache(client, 0))
 			err = metric.Write(&m)
 			assert.NoError(t, err)
 			assert.Contains(t, testCase.allowedStates, *m.Gauge.Value)
@@ -289,7 +353,7 @@ func TestMyState(t *testing.T) {
 			err = client.Disconnect(ctx)
 			assert.NoError(t, err)
 
-			metric = myState(ctx, client)
+			metric = myState(ctx, newScrapeCache(client, 0))
 			err = metric.Write(&m)
 			assert.NoError(t, err)
 			assert.Equal(t, float64(UnknownState), *m.Gauge.Value)
@@ -329,3 +393,192 @@ func TestArbiterMetrics(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestWiredTigerCacheCompatibleMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"wiredTiger": bson.M{
+				"cache": bson.M{
+					"bytes currently in the cache":     int64(4096),
+					"maximum bytes configured":         int64(8192),
+					"tracked dirty bytes in the cache": int64(512),
+					"bytes read into cache":            int64(100),
+					"bytes written from cache":         int64(200),
+					"pages read into cache":            int64(10),
+					"pages written from cache":         int64(20),
+					"modified pages evicted":           int64(3),
+					"unmodified pages evicted":         int64(7),
+				},
+			},
+		},
+	}
+
+	metrics := makeMetrics("", m, nil, true)
+	metrics = append(metrics, wiredTigerCacheMetrics(m)...)
+	evicted, err := cacheEvictedTotalMetric(m)
+	require.NoError(t, err)
+	metrics = append(metrics, evicted)
+
+	names := metricNames(metrics)
+	for _, want := range []string{
+		"mongodb_mongod_wiredtiger_cache_bytes",
+		"mongodb_mongod_wiredtiger_cache_max_bytes",
+		"mongodb_mongod_wiredtiger_cache_dirty_bytes",
+		"mongodb_mongod_wiredtiger_cache_bytes_total",
+		"mongodb_mongod_wiredtiger_cache_pages_total",
+		"mongodb_mongod_wiredtiger_cache_evicted_total",
+		"mongodb_mongod_wiredtiger_cache_evicted_pages_total",
+	} {
+		assert.Contains(t, names, want)
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_wiredtiger_cache_evicted_pages_total Pages evicted from the WiredTiger cache
+	# TYPE mongodb_mongod_wiredtiger_cache_evicted_pages_total counter
+	mongodb_mongod_wiredtiger_cache_evicted_pages_total{type="modified"} 3
+	mongodb_mongod_wiredtiger_cache_evicted_pages_total{type="unmodified"} 7` + "\n")
+	assert.NoError(t, testutil.CollectAndCompare(metricsCollector(wiredTigerCacheMetrics(m)), expected,
+		"mongodb_mongod_wiredtiger_cache_evicted_pages_total"))
+
+	assert.Empty(t, wiredTigerCacheMetrics(bson.M{"serverStatus": bson.M{}}))
+}
+
+func TestTransactionsMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"transactions": bson.M{
+				"retriedCommandsCount":   int64(2),
+				"retriedStatementsCount": int64(3),
+				"currentActive":          int64(1),
+				"currentInactive":        int64(0),
+				"totalAborted":           int64(5),
+				"totalCommitted":         int64(40),
+				"totalStarted":           int64(46),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_transactions_active Number of transactions currently running an operation
+	# TYPE mongodb_mongod_transactions_active gauge
+	mongodb_mongod_transactions_active 1
+	# HELP mongodb_mongod_transactions_retried_commands_total Total number of retried writes received after the write was already committed
+	# TYPE mongodb_mongod_transactions_retried_commands_total counter
+	mongodb_mongod_transactions_retried_commands_total 2
+	# HELP mongodb_mongod_transactions_total_aborted Total number of transactions aborted
+	# TYPE mongodb_mongod_transactions_total_aborted counter
+	mongodb_mongod_transactions_total_aborted 5
+	# HELP mongodb_mongod_transactions_total_committed Total number of transactions committed
+	# TYPE mongodb_mongod_transactions_total_committed counter
+	mongodb_mongod_transactions_total_committed 40
+	# HELP mongodb_mongod_transactions_total_started Total number of transactions started
+	# TYPE mongodb_mongod_transactions_total_started counter
+	mongodb_mongod_transactions_total_started 46` + "\n")
+
+	err := testutil.CollectAndCompare(metricsCollector(transactionsMetrics(m)), expected)
+	assert.NoError(t, err)
+
+	assert.Empty(t, transactionsMetrics(bson.M{"serverStatus": bson.M{"uptime": int64(1)}}))
+}
+
+func TestOpCountersReplCompatibleMetric(t *testing.T) {
+	t.Parallel()
+
+	m := bson.M{
+		"serverStatus": bson.M{
+			"opcountersRepl": bson.M{
+				"insert":  int64(2083),
+				"query":   int64(0),
+				"update":  int64(1559),
+				"delete":  int64(12),
+				"getmore": int64(0),
+				"command": int64(310),
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_op_counters_repl_total serverStatus.opcountersRepl
+	# TYPE mongodb_mongod_op_counters_repl_total counter
+	mongodb_mongod_op_counters_repl_total{type="command"} 310
+	mongodb_mongod_op_counters_repl_total{type="delete"} 12
+	mongodb_mongod_op_counters_repl_total{type="getmore"} 0
+	mongodb_mongod_op_counters_repl_total{type="insert"} 2083
+	mongodb_mongod_op_counters_repl_total{type="query"} 0
+	mongodb_mongod_op_counters_repl_total{type="update"} 1559` + "\n")
+
+	metrics := makeMetrics("", m, nil, true)
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected, "mongodb_mongod_op_counters_repl_total")
+	assert.NoError(t, err)
+}
+
+func TestGlobalLockCompatibleMetrics(t *testing.T) {
+	t.Parallel()
+
+	// The totals are not always the sum of the readers and writers.
+	m := bson.M{
+		"serverStatus": bson.M{
+			"globalLock": bson.M{
+				"totalTime": int64(1200000),
+				"currentQueue": bson.M{
+					"total":   int32(4),
+					"readers": int32(1),
+					"writers": int32(2),
+				},
+				"activeClients": bson.M{
+					"total":   int32(17),
+					"readers": int32(5),
+					"writers": int32(7),
+				},
+			},
+		},
+	}
+
+	expected := strings.NewReader(`
+	# HELP mongodb_mongod_global_lock_client mongodb_mongod_global_lock_client
+	# TYPE mongodb_mongod_global_lock_client untyped
+	mongodb_mongod_global_lock_client{type="reader"} 5
+	mongodb_mongod_global_lock_client{type="total"} 17
+	mongodb_mongod_global_lock_client{type="writer"} 7
+	# HELP mongodb_mongod_global_lock_current_queue serverStatus.globalLock.currentQueue
+	# TYPE mongodb_mongod_global_lock_current_queue untyped
+	mongodb_mongod_global_lock_current_queue{type="reader"} 1
+	mongodb_mongod_global_lock_current_queue{type="total"} 4
+	mongodb_mongod_global_lock_current_queue{type="writer"} 2` + "\n")
+
+	metrics := makeMetrics("", m, nil, true)
+	err := testutil.CollectAndCompare(metricsCollector(metrics), expected,
+		"mongodb_mongod_global_lock_client", "mongodb_mongod_global_lock_current_queue")
+	assert.NoError(t, err)
+}
+
+func TestCompatibleMetricMappings(t *testing.T) {
+	t.Parallel()
+
+	mappings := CompatibleMetricMappings()
+
+	assert.Equal(t, "mongodb_asserts_total", mappings["mongodb_ss_asserts"])
+	assert.Equal(t, "mongodb_mongod_instance_uptime_seconds,mongodb_instance_uptime_seconds", mappings["mongodb_ss_uptime"])
+	assert.Equal(t, `mongodb_mongod_global_lock_client{type="reader"}`, mappings["mongodb_ss_globalLock_activeClients_readers"])
+
+	// The mappings are the names of the metrics converted in compatible mode.
+	for name, legacy := range mappings {
+		converted := metricRenameAndLabel(&rawMetric{fqName: name}, conversions)
+		require.NotEmpty(t, converted, name)
+
+		names := make([]string, 0, len(converted))
+		for _, rm := range converted {
+			// The metric has no labels, only the ones made from a prefix are labeled with the suffix.
+			if len(rm.ln) > 0 {
+				names = append(names, fmt.Sprintf("%s{%s=%q}", rm.fqName, rm.ln[0], rm.lv[0]))
+				continue
+			}
+			names = append(names, rm.fqName)
+		}
+		assert.Equal(t, legacy, strings.Join(names, ","), name)
+	}
+}
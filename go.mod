@@ -7,6 +7,7 @@ toolchain go1.23.2
 require (
 	github.com/AlekSi/pointer v1.2.0
 	github.com/alecthomas/kong v1.7.0
+	github.com/golang/snappy v0.0.4
 	github.com/percona/exporter_shared v0.7.6
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.20.5
@@ -22,7 +23,11 @@ require github.com/foxcpp/go-mockdns v1.1.0
 
 require github.com/hashicorp/go-version v1.7.0
 
-require github.com/percona/percona-backup-mongodb v1.8.1-0.20241022111827-8d3ad8a6eb7a
+require (
+	github.com/percona/percona-backup-mongodb v1.8.1-0.20241022111827-8d3ad8a6eb7a
+	google.golang.org/protobuf v1.36.1
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.10.0 // indirect
@@ -34,7 +39,6 @@ require (
 	github.com/coreos/go-systemd/v22 v22.5.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/go-ini/ini v1.67.0 // indirect
-	github.com/golang/snappy v0.0.4 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/jessevdk/go-flags v1.5.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
@@ -69,7 +73,5 @@ require (
 	golang.org/x/term v0.27.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
-	google.golang.org/protobuf v1.36.1 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -20,4 +20,6 @@ type MasterDoc struct {
 	Hosts       interface{} `bson:"hosts"`
 	Msg         string      `bson:"msg"`
 	ArbiterOnly bool        `bson:"arbiterOnly"`
+	IsMaster    bool        `bson:"ismaster"`
+	Secondary   bool        `bson:"secondary"`
 }
@@ -35,6 +35,7 @@ type StorageEngine struct {
 type Members struct {
 	Optime               map[string]Optime   `bson:"optimes"`              // See Optime struct
 	OptimeDate           primitive.DateTime  `bson:"optimeDate"`           // The last entry from the oplog that this member applied.
+	OptimeDurableDate    primitive.DateTime  `bson:"optimeDurableDate"`    // The last entry from the oplog that this member wrote to its journal. 3.4+
 	InfoMessage          string              `bson:"infoMessage"`          // A message
 	ID                   int64               `bson:"_id"`                  // Server ID
 	Name                 string              `bson:"name"`                 // server name
@@ -49,6 +50,7 @@ type Members struct {
 	LastHeartbeat        primitive.DateTime  `bson:"lastHeartbeat"`        // Reflects the last time the server that processed the replSetGetStatus command received a response from a heartbeat that it sent to this member.
 	LastHeartbeatRecv    primitive.DateTime  `bson:"lastHeartbeatRecv"`    // Reflects the last time the server that processed the replSetGetStatus command received a heartbeat request from this member.
 	LastHeartbeatMessage string              `bson:"lastHeartbeatMessage"` // Contains a string representation of that message.
+	SyncSourceHost       string              `bson:"syncSourceHost"`       // The member this member replicates from, empty for the primary. 4.0+
 	PingMs               *float64            `bson:"pingMs,omitempty"`     // Represents the number of milliseconds (ms) that a round-trip packet takes to travel between the remote member and the local instance.
 	Set                  string              `bson:"-"`
 	StorageEngine        StorageEngine
@@ -63,6 +65,19 @@ type ReplicaSetStatus struct {
 	Members                 []Members          `bson:"members"`                 //
 	Ok                      float64            `bson:"ok"`                      //
 	Set                     string             `bson:"set"`                     // Replica set name
+	MajorityVoteCount       float64            `bson:"majorityVoteCount"`       // Number of votes needed to elect a primary. 4.4+
+	VotingMembersCount      float64            `bson:"votingMembersCount"`      // Number of members configured with votes. 4.4+
+	InitialSyncStatus       *InitialSyncStatus `bson:"initialSyncStatus"`       // Only reported by a member during its initial sync.
+}
+
+// InitialSyncStatus is the progress of the initial sync of a member, see replSetGetStatus.
+type InitialSyncStatus struct {
+	FailedInitialSyncAttempts     float64             `bson:"failedInitialSyncAttempts"`     // Failed attempts of the current initial sync.
+	TotalInitialSyncElapsedMillis float64             `bson:"totalInitialSyncElapsedMillis"` // Time since the initial sync started.
+	ApproxTotalDataSize           float64             `bson:"approxTotalDataSize"`           // Size of the data to clone, in bytes. 4.4+
+	ApproxTotalBytesCopied        float64             `bson:"approxTotalBytesCopied"`        // Data cloned so far, in bytes. 4.4+
+	InitialSyncOplogStart         primitive.Timestamp `bson:"initialSyncOplogStart"`         // Start of the oplog entries to apply after cloning.
+	InitialSyncOplogEnd           primitive.Timestamp `bson:"initialSyncOplogEnd"`           // End of the oplog entries to apply, set once cloning is done.
 }
 
 type Member struct {
@@ -82,6 +97,7 @@ type RSConfig struct {
 	ConfigServer                       bool       `bson:"configsvr"`
 	WriteConcernMajorityJournalDefault bool       `bson:"writeConcernMajorityJournalDefault"`
 	Version                            int32      `bson:"version"`
+	Term                               *int64     `bson:"term"` // Term of the primary which set the config. 4.4+
 	ProtocolVersion                    int64      `bson:"protocolVersion"`
 	Settings                           RSSettings `bson:"settings"`
 	Members                            []Member   `bson:"members"`
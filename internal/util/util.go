@@ -17,6 +17,7 @@ package util
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -31,6 +32,20 @@ const (
 	ErrNotPrimaryOrSecondary = int32(13436)
 )
 
+// DefaultCommandTimeout bounds the commands of the helpers run by the collectors, so a command
+// hung during an election doesn't stall the whole scrape.
+const DefaultCommandTimeout = 5 * time.Second
+
+// WithTimeout returns a context canceled after d, or at the deadline of ctx if it is earlier.
+// A d of zero or less keeps the deadline of ctx.
+func WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, d)
+}
+
 // MyState returns the replica set and the instance's state if available.
 func MyState(ctx context.Context, client *mongo.Client) (string, int, error) {
 	var status proto.ReplicaSetStatus
@@ -68,9 +83,19 @@ func IsReplicationNotEnabledError(err mongo.CommandError) bool {
 		err.Code == ErrNotPrimaryOrSecondary
 }
 
-func ClusterID(ctx context.Context, client *mongo.Client) (string, error) {
+// ConfigVersion returns the sharding metadata version document from config.version.
+// It is only available through mongos and on config servers.
+func ConfigVersion(ctx context.Context, client *mongo.Client) (*proto.ConfigVersion, error) {
 	var cv proto.ConfigVersion
-	if err := client.Database("config").Collection("version").FindOne(ctx, bson.M{}).Decode(&cv); err == nil {
+	if err := client.Database("config").Collection("version").FindOne(ctx, bson.M{}).Decode(&cv); err != nil {
+		return nil, err
+	}
+
+	return &cv, nil
+}
+
+func ClusterID(ctx context.Context, client *mongo.Client) (string, error) {
+	if cv, err := ConfigVersion(ctx, client); err == nil {
 		return cv.ClusterID.Hex(), nil
 	}
 
@@ -0,0 +1,85 @@
+// mongodb_exporter
+// Copyright (C) 2017 Percona LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// An earlier deadline of the parent is kept.
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	ctx, cancel = WithTimeout(parent, time.Minute)
+	defer cancel()
+	parentDeadline, _ := parent.Deadline()
+	deadline, _ = ctx.Deadline()
+	assert.Equal(t, parentDeadline, deadline)
+
+	ctx, cancel = WithTimeout(context.Background(), 0)
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+	assert.Error(t, ctx.Err())
+}
+
+func TestHelpersCanceledContext(t *testing.T) {
+	t.Parallel()
+
+	// Nothing listens on the port, the server selection would wait for 30s.
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=30000"))
+	require.NoError(t, err)
+	defer client.Disconnect(context.Background()) //nolint:errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+
+	_, _, err = MyState(ctx, client)
+	assert.Error(t, err)
+
+	_, err = ReplicasetConfig(ctx, client)
+	assert.Error(t, err)
+
+	// Server selection errors are reported as an unknown cluster ID.
+	id, _ := ClusterID(ctx, client)
+	assert.Empty(t, id)
+
+	// A hung command is bounded by WithTimeout.
+	ctx, cancel = WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = ReplicasetConfig(ctx, client)
+	assert.Error(t, err)
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+}